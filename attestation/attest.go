@@ -5,8 +5,12 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"os"
+	"time"
 
+	"github.com/stateless-solutions/compatibility-layer/jcs"
+	"github.com/stateless-solutions/compatibility-layer/metrics"
 	"github.com/stateless-solutions/compatibility-layer/models"
 	"golang.org/x/crypto/ssh"
 )
@@ -18,12 +22,28 @@ func newErrorResponse(err *models.RPCErr, id json.RawMessage) models.RPCResJSONA
 	}
 }
 
-func attestableError(jsonErr *models.RPCErr) ([]byte, error) {
-	return json.Marshal(jsonErr)
+// attestables are serialized with JCS so verifiers in any language can recompute the hashes
+func attestableError(jsonErr *models.RPCErr) ([]byte, string, error) {
+	data, err := json.Marshal(jsonErr)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return attestableJSON(data)
 }
 
-func attestableJSON(result interface{}) ([]byte, error) {
-	return json.Marshal(result)
+// results are carried as raw upstream JSON so they only need to be canonicalized, the ones with integers
+// that are not exact doubles are attested as they are since their canonical form would be shared with other integers
+func attestableJSON(result json.RawMessage) ([]byte, string, error) {
+	attestable, err := jcs.Transform(result)
+	if errors.Is(err, jcs.ErrInvalidNumber) {
+		return result, RawScheme, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return attestable, jcs.Scheme, nil
 }
 
 func GetSigningKeyFromKeyFile(keyfile string) (ssh.Signer, error) {
@@ -51,21 +71,28 @@ func GetSigningKeyFromKeyFileWithPassphrase(keyfile string, password string) (ss
 
 }
 
-func attest(data []byte, identity string, signer ssh.Signer, full bool) (models.Attestation, error) {
-	msgFixed := sha256.Sum256(data)
-	msg := msgFixed[:]
+func attest(data []byte, canonicalization string, identity string, signer ssh.Signer, full bool) (models.Attestation, error) {
+	msg := sha256.Sum256(data)
+	return signDigest(msg[:], canonicalization, identity, signer, full)
+}
+
+// signDigest signs the sha256 digest of the attested bytes, full attestations state the canonicalization of the bytes
+func signDigest(msg []byte, canonicalization string, identity string, signer ssh.Signer, full bool) (models.Attestation, error) {
+	start := time.Now()
 	sig, err := signer.Sign(rand.Reader, msg)
+	metrics.ObserveAttestation(time.Since(start))
 	if err != nil {
 		return models.Attestation{}, err
 	}
 	var attestation models.Attestation
 	if full {
 		attestation = models.Attestation{
-			SignatureFormat: sig.Format,
-			MsgHash:         hex.EncodeToString(msg),
-			HashAlgo:        "sha256",
-			Identiy:         identity,
-			Signature:       hex.EncodeToString(sig.Blob),
+			SignatureFormat:  sig.Format,
+			MsgHash:          hex.EncodeToString(msg),
+			HashAlgo:         "sha256",
+			Canonicalization: canonicalization,
+			Identiy:          identity,
+			Signature:        hex.EncodeToString(sig.Blob),
 		}
 	} else {
 		attestation = models.Attestation{
@@ -77,21 +104,21 @@ func attest(data []byte, identity string, signer ssh.Signer, full bool) (models.
 	return attestation, nil
 }
 
+// attestableRes returns the bytes of the result of the response, or of its error if it has no result, and their canonicalization
+func attestableRes(input *models.RPCResJSON) ([]byte, string, error) {
+	if !input.HasResult() {
+		return attestableError(input.Error)
+	}
+	return attestableJSON(input.Result)
+}
+
 func attestor(input *models.RPCResJSON, identity string, signer ssh.Signer, full bool) (*models.RPCResJSONAttested, error) {
-	var attestable []byte
-	var err error
-	if input.Result == nil {
-		attestable, err = attestableError(input.Error)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		attestable, err = attestableJSON(input.Result)
-		if err != nil {
-			return nil, err
-		}
+	attestable, canonicalization, err := attestableRes(input)
+	if err != nil {
+		return nil, err
 	}
-	attestation, err := attest(attestable, identity, signer, full)
+	// the canonicalization is stated on the attestations that are not canonicalized with the one of the batch
+	attestation, err := attest(attestable, canonicalization, identity, signer, full || canonicalization != jcs.Scheme)
 	if err != nil {
 		return nil, err
 	}
@@ -117,3 +144,20 @@ func AttestRess(ress []*models.RPCResJSON, identity string, signer ssh.Signer) (
 
 	return attestedRess, nil
 }
+
+func AttestNotification(notification *models.RPCNotification, identity string, signer ssh.Signer) (*models.RPCNotificationAttested, error) {
+	attestable, canonicalization, err := attestableJSON(notification.Params.Result)
+	if err != nil {
+		return nil, err
+	}
+	attestation, err := attest(attestable, canonicalization, identity, signer, true)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RPCNotificationAttested{
+		JSONRPC:     notification.JSONRPC,
+		Method:      notification.Method,
+		Params:      notification.Params,
+		Attestation: &attestation,
+	}, nil
+}
@@ -0,0 +1,182 @@
+package attestation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/jcs"
+	"github.com/stateless-solutions/compatibility-layer/models"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// Version1 attestations sign the hash of the result
+	Version1 = 1
+	// Version2 attestations sign the hash of a payload that binds the result to its request, chain, block and time
+	Version2 = 2
+)
+
+// Binding holds what a version 2 attestation binds the result of a response to
+type Binding struct {
+	Chain  string
+	Method string
+	Params json.RawMessage
+	Block  string // block number or slot the result was resolved at, empty if it is unknown
+	Nonce  string // optional nonce sent by the client
+}
+
+// payloadV2 is the signed payload of version 2 attestations, it is serialized with JCS
+type payloadV2 struct {
+	Block      string          `json:"block"`
+	Chain      string          `json:"chain"`
+	Method     string          `json:"method"`
+	Nonce      string          `json:"nonce"`
+	Params     json.RawMessage `json:"params"`
+	ResultHash string          `json:"resultHash"`
+	Timestamp  int64           `json:"timestamp"`
+	Version    int             `json:"version"`
+}
+
+// payloadV2Of returns the signed payload of the sha256 digest of the attestable bytes of a result with its binding
+func payloadV2Of(resultDigest []byte, binding Binding, timestamp int64) ([]byte, string, error) {
+	params := json.RawMessage("null")
+	if len(binding.Params) > 0 {
+		var err error
+		params, err = jcs.Transform(binding.Params)
+		if errors.Is(err, jcs.ErrInvalidNumber) {
+			params, err = json.Marshal(string(binding.Params)) // params that can't be canonicalized are bound as a string of their bytes
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	payload := payloadV2{
+		Block:      binding.Block,
+		Chain:      binding.Chain,
+		Method:     binding.Method,
+		Nonce:      binding.Nonce,
+		Params:     params,
+		ResultHash: hex.EncodeToString(resultDigest),
+		Timestamp:  timestamp,
+		Version:    Version2,
+	}
+
+	data, err := jcs.Marshal(payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, payload.ResultHash, nil
+}
+
+// attestV2 attests the digest of the result bytes, the canonicalization is the one of the result since the payload is always serialized with JCS
+func attestV2(resultDigest []byte, canonicalization string, binding Binding, timestamp int64, identity string, signer ssh.Signer, full bool) (models.Attestation, error) {
+	payload, resultHash, err := payloadV2Of(resultDigest, binding, timestamp)
+	if err != nil {
+		return models.Attestation{}, err
+	}
+
+	msg := sha256.Sum256(payload)
+	attestation, err := signDigest(msg[:], canonicalization, identity, signer, full)
+	if err != nil {
+		return models.Attestation{}, err
+	}
+
+	// verifiers need these fields to rebuild the payload with their request
+	attestation.Version = Version2
+	attestation.Chain = binding.Chain
+	attestation.Block = binding.Block
+	attestation.Timestamp = timestamp
+	attestation.Nonce = binding.Nonce
+	attestation.ResultHash = resultHash
+
+	return attestation, nil
+}
+
+// AttestRessV2 attests the responses with version 2 attestations, the bindings are in the same order as the responses
+func AttestRessV2(ress []*models.RPCResJSON, bindings []Binding, identity string, signer ssh.Signer) ([]*models.RPCResJSONAttested, error) {
+	timestamp := time.Now().Unix()
+
+	var attestedRess []*models.RPCResJSONAttested
+	for i, res := range ress {
+		attestable, canonicalization, err := attestableRes(res)
+		if err != nil {
+			return nil, err
+		}
+
+		digest := sha256.Sum256(attestable)
+		attestation, err := attestV2(digest[:], canonicalization, bindings[i], timestamp, identity, signer, i == 0 || canonicalization != jcs.Scheme)
+		if err != nil {
+			return nil, err
+		}
+
+		attestedRess = append(attestedRess, &models.RPCResJSONAttested{
+			Result:      res.Result,
+			Error:       res.Error,
+			JSONRPC:     res.JSONRPC,
+			ID:          res.ID,
+			Attestation: &attestation,
+		})
+	}
+
+	return attestedRess, nil
+}
+
+// AttestNotificationV2 attests the notification with a version 2 attestation
+func AttestNotificationV2(notification *models.RPCNotification, binding Binding, identity string, signer ssh.Signer) (*models.RPCNotificationAttested, error) {
+	attestable, canonicalization, err := attestableJSON(notification.Params.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(attestable)
+	attestation, err := attestV2(digest[:], canonicalization, binding, time.Now().Unix(), identity, signer, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RPCNotificationAttested{
+		JSONRPC:     notification.JSONRPC,
+		Method:      notification.Method,
+		Params:      notification.Params,
+		Attestation: &attestation,
+	}, nil
+}
+
+// VerifyV2 checks the version 2 attestation of the JSON of a result (or of its error) against the binding of the request of the verifier
+// the chain, method, params and nonce of the binding are the ones of the request, the block is taken from the attestation
+// results of streamed responses are verified with their bytes as they were sent
+func VerifyV2(result []byte, attestation *models.Attestation, binding Binding, publicKey ssh.PublicKey) error {
+	attestable := result
+	if attestation.Canonicalization != RawScheme {
+		var err error
+		attestable, err = jcs.Transform(result)
+		if err != nil {
+			return err
+		}
+	}
+
+	binding.Block = attestation.Block
+	digest := sha256.Sum256(attestable)
+	payload, _, err := payloadV2Of(digest[:], binding, attestation.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	msg := sha256.Sum256(payload)
+	blob, err := hex.DecodeString(attestation.Signature)
+	if err != nil {
+		return err
+	}
+
+	format := attestation.SignatureFormat
+	if format == "" {
+		format = publicKey.Type()
+	}
+
+	return publicKey.Verify(msg[:], &ssh.Signature{Format: format, Blob: blob})
+}
@@ -0,0 +1,22 @@
+package attestation
+
+import (
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// RawScheme is the canonicalization of the results of streamed responses and of the results with integers that are not exact doubles,
+// streamed results can't be canonicalized without buffering them, so their bytes are hashed as they are sent
+const RawScheme = "none"
+
+// AttestDigest attests the sha256 digest of the bytes of a streamed result with a version 1 attestation
+func AttestDigest(digest []byte, identity string, signer ssh.Signer) (models.Attestation, error) {
+	return signDigest(digest, RawScheme, identity, signer, true)
+}
+
+// AttestDigestV2 attests the sha256 digest of the bytes of a streamed result with a version 2 attestation
+func AttestDigestV2(digest []byte, binding Binding, identity string, signer ssh.Signer) (models.Attestation, error) {
+	return attestV2(digest, RawScheme, binding, time.Now().Unix(), identity, signer, true)
+}
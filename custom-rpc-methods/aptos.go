@@ -0,0 +1,204 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// AptosLedgerVersion is the getter of the Aptos methods, the ledger_version param, empty is the latest version
+type AptosLedgerVersion string
+
+type ledgerVersionResult struct {
+	Data          json.RawMessage `json:"data"`
+	LedgerVersion string          `json:"ledgerVersion"`
+}
+
+// versions are strings as on the Aptos responses
+func (r ledgerVersionResult) envelope() ([]byte, []byte) {
+	return rawEnvelope("data", []string{"ledgerVersion"}, rawString(r.LedgerVersion))
+}
+
+func (r ledgerVersionResult) resolvedBlock() string {
+	return r.LedgerVersion
+}
+
+var ErrInternalLedgerInfoWithoutVersion = &models.RPCErr{
+	Code:          JSONRPCErrorInternal - 34,
+	Message:       "ledger info response does not have the ledger version",
+	HTTPErrorCode: 500,
+}
+
+type AptosImpl struct{}
+
+func (a AptosImpl) GetChainType() ChainType {
+	return ChainTypeAptos
+}
+
+func (a AptosImpl) SupportsRange() bool {
+	return false
+}
+
+func buildLedgerInfoReq(id string) *models.RPCReq {
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "get_ledger_info",
+		ID:      json.RawMessage(id),
+	}
+}
+
+func (a AptosImpl) GetHealthCheckReq() *models.RPCReq {
+	return buildLedgerInfoReq("1")
+}
+
+func (a AptosImpl) ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error) {
+	version, err := a.ExtractGetterReturnFromResponse(res)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(version, 10, 64)
+}
+
+func (a AptosImpl) GetBlockTime() time.Duration {
+	return 250 * time.Millisecond
+}
+
+// committed versions are final on Aptos, so the latest version is the finalized one
+func (a AptosImpl) GetFinalizedHeightReq() *models.RPCReq {
+	return buildLedgerInfoReq("1")
+}
+
+func (a AptosImpl) ExtractFinalizedHeight(res *models.RPCResJSON) (uint64, error) {
+	return a.ExtractHeightFromHealthCheck(res)
+}
+
+func (a AptosImpl) GetDefaultGetter() AptosLedgerVersion {
+	return ""
+}
+
+func (a AptosImpl) GetDefaultGetterRange() []AptosLedgerVersion {
+	return nil
+}
+
+func (a AptosImpl) GetCustomHandlerMap() map[string]func(*models.RPCReq) ([]AptosLedgerVersion, error) {
+	return nil
+}
+
+func (a AptosImpl) FromGetterTypeToHolder(gth GetterTypesHolder) AptosLedgerVersion {
+	return gth.Aptos
+}
+
+func (a AptosImpl) FromHolderToGetterType(gt AptosLedgerVersion) GetterTypesHolder {
+	return GetterTypesHolder{
+		Aptos: gt,
+	}
+}
+
+// versions are u64 so they are usually sent as strings, numbers are accepted too
+func (a AptosImpl) ExtractGetter(param interface{}) (AptosLedgerVersion, error) {
+	switch version := param.(type) {
+	case nil:
+		return a.GetDefaultGetter(), nil
+	case string:
+		if _, err := strconv.ParseUint(version, 10, 64); err != nil {
+			return "", ErrParseErr
+		}
+		return AptosLedgerVersion(version), nil
+	case float64:
+		if version < 0 || version != math.Trunc(version) || version > math.MaxInt64 {
+			return "", ErrParseErr
+		}
+		return AptosLedgerVersion(strconv.FormatUint(uint64(version), 10)), nil
+	}
+
+	return "", ErrParseErr
+}
+
+// only the latest version needs a getter req, it is the version of the ledger info
+func (a AptosImpl) BuildGetterReq(id string, gt AptosLedgerVersion) (*models.RPCReq, error) {
+	if gt != "" {
+		return nil, ErrParseErr
+	}
+
+	return buildLedgerInfoReq(id), nil
+}
+
+func (a AptosImpl) GetIndexOfIDHolder(gt AptosLedgerVersion) (string, error) {
+	if gt == "" {
+		return "latest", nil
+	}
+
+	return "", nil
+}
+
+func (a AptosImpl) ExtractGetterReturnFromResponse(res *models.RPCResJSON) (string, error) {
+	var info struct {
+		LedgerVersion string `json:"ledger_version"`
+	}
+	err := json.Unmarshal(res.Result, &info)
+	if err != nil {
+		return "", ErrInternalLedgerInfoWithoutVersion
+	}
+	if _, err := strconv.ParseUint(info.LedgerVersion, 10, 64); err != nil {
+		return "", ErrInternalLedgerInfoWithoutVersion
+	}
+
+	return info.LedgerVersion, nil
+}
+
+func (a AptosImpl) ExtractGetterReturnFromType(gt AptosLedgerVersion) (string, error) {
+	if gt == "" {
+		return "", ErrParseErr
+	}
+
+	return string(gt), nil
+}
+
+func (a AptosImpl) ExtractGetterStruct(res *models.RPCResJSON, gr string, contextRes *models.RPCResJSON) (ledgerVersionResult, error) {
+	return ledgerVersionResult{
+		Data:          res.Result,
+		LedgerVersion: gr,
+	}, nil
+}
+
+func (a AptosImpl) ExtractGetterRangeStruct(res *models.RPCResJSON, grTo, grFrom string) (noRangeSupported, error) {
+	return noRangeSupported{}, ErrParseErr
+}
+
+func (a AptosImpl) PinGetter(param interface{}, gt AptosLedgerVersion, gr string) (interface{}, error) {
+	if gt != "" {
+		return param, nil // explicit versions already point to a single version
+	}
+
+	return gr, nil
+}
+
+// Aptos nodes have no websocket subscriptions
+func (a AptosImpl) ExtractGetterReturnFromNotification(notification *models.RPCNotification) (string, bool, error) {
+	return "", false, nil
+}
+
+func NewAptosMethodBuilder() CustomRpcMethodBuilder {
+	return NewGenericConv(AptosImpl{})
+}
+
+func (a AptosImpl) IsImmutableGetter(gt AptosLedgerVersion, finalizedHeight uint64) bool {
+	if gt == "" {
+		return false
+	}
+
+	version, _ := strconv.ParseUint(string(gt), 10, 64)
+	return version <= finalizedHeight
+}
+
+func (a AptosImpl) BuildContextReq(id string) *models.RPCReq {
+	return nil // the ledger version is the only data added to the results
+}
+
+func (a AptosImpl) GetIndexOfContextReq() string {
+	return ""
+}
@@ -0,0 +1,142 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// aptosEndpoint is the REST endpoint of an Aptos method, the methods are named after the operation ids of the API
+type aptosEndpoint struct {
+	httpMethod string
+	path       string   // the params between braces are replaced by the params of their keys
+	query      []string // keys of the params sent on the query of POST endpoints, the rest of the params are the body
+}
+
+var (
+	aptosEndpoints = map[string]aptosEndpoint{
+		"get_ledger_info":            {httpMethod: http.MethodGet, path: "/v1"},
+		"get_account":                {httpMethod: http.MethodGet, path: "/v1/accounts/{address}"},
+		"get_account_resources":      {httpMethod: http.MethodGet, path: "/v1/accounts/{address}/resources"},
+		"get_account_resource":       {httpMethod: http.MethodGet, path: "/v1/accounts/{address}/resource/{resource_type}"},
+		"get_account_modules":        {httpMethod: http.MethodGet, path: "/v1/accounts/{address}/modules"},
+		"get_account_module":         {httpMethod: http.MethodGet, path: "/v1/accounts/{address}/module/{module_name}"},
+		"get_block_by_height":        {httpMethod: http.MethodGet, path: "/v1/blocks/by_height/{block_height}"},
+		"get_block_by_version":       {httpMethod: http.MethodGet, path: "/v1/blocks/by_version/{version}"},
+		"get_transaction_by_hash":    {httpMethod: http.MethodGet, path: "/v1/transactions/by_hash/{txn_hash}"},
+		"get_transaction_by_version": {httpMethod: http.MethodGet, path: "/v1/transactions/by_version/{txn_version}"},
+		"view":                       {httpMethod: http.MethodPost, path: "/v1/view", query: []string{"ledger_version"}},
+		"get_table_item":             {httpMethod: http.MethodPost, path: "/v1/tables/{table_handle}/item", query: []string{"ledger_version"}},
+	}
+
+	aptosPathParamRegex = regexp.MustCompile(`\{([a-z_]+)\}`)
+)
+
+// BuildRESTReq returns the request of the endpoint of the method, the params must be named
+func (a AptosImpl) BuildRESTReq(req *models.RPCReq) (*RESTReq, error) {
+	endpoint, ok := aptosEndpoints[req.Method]
+	if !ok {
+		return nil, ErrMethodNotFound
+	}
+
+	params := map[string]json.RawMessage{}
+	if len(req.Params) > 0 && string(req.Params) != "null" {
+		if !isNamedParams(req.Params) {
+			return nil, ErrInvalidParams
+		}
+		err := json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return nil, ErrInvalidParams
+		}
+	}
+
+	var missing bool
+	path := aptosPathParamRegex.ReplaceAllStringFunc(endpoint.path, func(match string) string {
+		key := match[1 : len(match)-1]
+		value, ok := params[key]
+		if !ok {
+			missing = true
+			return match
+		}
+		delete(params, key)
+		return url.PathEscape(restValue(value))
+	})
+	if missing {
+		return nil, ErrInvalidParams
+	}
+
+	restReq := &RESTReq{
+		Method: endpoint.httpMethod,
+		Path:   path,
+		Query:  url.Values{},
+	}
+
+	if endpoint.httpMethod == http.MethodGet {
+		for key, value := range params {
+			if string(value) != "null" {
+				restReq.Query.Set(key, restValue(value))
+			}
+		}
+		return restReq, nil
+	}
+
+	for _, key := range endpoint.query {
+		value, ok := params[key]
+		if ok && string(value) != "null" {
+			restReq.Query.Set(key, restValue(value))
+		}
+		delete(params, key)
+	}
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, ErrInvalidParams
+	}
+	restReq.Body = body
+
+	return restReq, nil
+}
+
+// ExtractRESTError returns the error of the responses whose status is not successful
+func (a AptosImpl) ExtractRESTError(statusCode int, body []byte) *models.RPCErr {
+	if statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices {
+		return nil
+	}
+
+	return restError(statusCode, body)
+}
+
+// restError returns the error of a REST response with the message of the error body if it has one
+// the whole body is kept as the data of the error
+func restError(statusCode int, body []byte) *models.RPCErr {
+	rpcErr := &models.RPCErr{
+		Code:    JSONRPCErrorInternal,
+		Message: http.StatusText(statusCode),
+	}
+
+	var errBody struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &errBody) == nil && errBody.Message != "" {
+		rpcErr.Message = errBody.Message
+	}
+	if json.Valid(body) {
+		rpcErr.Data = body
+	}
+
+	return rpcErr
+}
+
+// restValue returns the value of a param as it is written on the path or query, strings without their quotes
+func restValue(value json.RawMessage) string {
+	s, ok := stringOf(value)
+	if ok {
+		return s
+	}
+
+	return strings.TrimSpace(string(value))
+}
@@ -0,0 +1,304 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+func TestAptos(t *testing.T) {
+	tests := []testCaseGenericConv{
+		{
+			name: "Account latest",
+			req: []*models.RPCReq{{
+				Method: "get_accountAndLedgerVersion",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"address":"0x1"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "get_account",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"sequence_number":"0","authentication_key":"0x01"}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"chain_id":1,"epoch":"10","ledger_version":"2150","block_height":"900"}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: ledgerVersionResult{
+					Data:          json.RawMessage(`{"sequence_number":"0","authentication_key":"0x01"}`),
+					LedgerVersion: "2150",
+				}.raw(),
+			},
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "View ledger version",
+			req: []*models.RPCReq{{
+				Method: "viewAndLedgerVersion",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"function":"0x1::coin::balance","type_arguments":["0x1::aptos_coin::AptosCoin"],"arguments":["0x1"],"ledger_version":"2000"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "view",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`["100"]`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: ledgerVersionResult{
+					Data:          json.RawMessage(`["100"]`),
+					LedgerVersion: "2000",
+				}.raw(),
+			},
+		},
+		{
+			name: "Resource numeric ledger version",
+			req: []*models.RPCReq{{
+				Method: "get_account_resourceAndLedgerVersion",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"address":"0x1","resource_type":"0x1::account::Account","ledger_version":2000}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "get_account_resource",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"type":"0x1::account::Account","data":{}}`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: ledgerVersionResult{
+					Data:          json.RawMessage(`{"type":"0x1::account::Account","data":{}}`),
+					LedgerVersion: "2000",
+				}.raw(),
+			},
+		},
+		{
+			name: "Ledger info without version",
+			req: []*models.RPCReq{{
+				Method: "get_account_modulesAndLedgerVersion",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"address":"0x1"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "get_account_modules",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`[]`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"chain_id":1,"epoch":"10"}`)}},
+			expectedErr:       ErrInternalLedgerInfoWithoutVersion,
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+	}
+
+	runTests(t, "../supported-chains/aptos.json", tests)
+}
+
+func TestAptosPinGetterParams(t *testing.T) {
+	tests := []testCasePinGetterParams{
+		{
+			name: "Missing ledger version",
+			req: []*models.RPCReq{{
+				Method: "get_account_resourcesAndLedgerVersion",
+				ID:     json.RawMessage("1"),
+				Params: json.RawMessage(`{"address":"0x1"}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"chain_id":1,"ledger_version":"2150"}`)},
+			},
+			expectedParams: []string{`{"address":"0x1","ledger_version":"2150"}`},
+		},
+		{
+			name: "Null ledger version",
+			req: []*models.RPCReq{{
+				Method: "get_table_itemAndLedgerVersion",
+				ID:     json.RawMessage("1"),
+				Params: json.RawMessage(`{"table_handle":"0x2","key_type":"address","value_type":"u64","key":"0x1","ledger_version":null}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"chain_id":1,"ledger_version":"2150"}`)},
+			},
+			expectedParams: []string{`{"table_handle":"0x2","key_type":"address","value_type":"u64","key":"0x1","ledger_version":"2150"}`},
+		},
+	}
+
+	runPinTests(t, "../supported-chains/aptos.json", tests)
+}
+
+func TestAptosBuildRESTReq(t *testing.T) {
+	tests := []struct {
+		name           string
+		req            *models.RPCReq
+		expectedMethod string
+		expectedPath   string
+		expectedQuery  string
+		expectedBody   string
+		expectedErr    error
+	}{
+		{
+			name:           "Ledger info",
+			req:            &models.RPCReq{Method: "get_ledger_info"},
+			expectedMethod: http.MethodGet,
+			expectedPath:   "/v1",
+		},
+		{
+			name:           "Resource with ledger version",
+			req:            &models.RPCReq{Method: "get_account_resource", Params: json.RawMessage(`{"address":"0x1","resource_type":"0x1::coin::CoinStore<0x1::aptos_coin::AptosCoin>","ledger_version":"2000"}`)},
+			expectedMethod: http.MethodGet,
+			expectedPath:   "/v1/accounts/0x1/resource/0x1::coin::CoinStore%3C0x1::aptos_coin::AptosCoin%3E",
+			expectedQuery:  "ledger_version=2000",
+		},
+		{
+			name:           "Block by height",
+			req:            &models.RPCReq{Method: "get_block_by_height", Params: json.RawMessage(`{"block_height":900,"with_transactions":true}`)},
+			expectedMethod: http.MethodGet,
+			expectedPath:   "/v1/blocks/by_height/900",
+			expectedQuery:  "with_transactions=true",
+		},
+		{
+			name:           "View",
+			req:            &models.RPCReq{Method: "view", Params: json.RawMessage(`{"function":"0x1::coin::balance","type_arguments":[],"arguments":["0x1"],"ledger_version":"2000"}`)},
+			expectedMethod: http.MethodPost,
+			expectedPath:   "/v1/view",
+			expectedQuery:  "ledger_version=2000",
+			expectedBody:   `{"arguments":["0x1"],"function":"0x1::coin::balance","type_arguments":[]}`,
+		},
+		{
+			name:        "Missing path param",
+			req:         &models.RPCReq{Method: "get_account", Params: json.RawMessage(`{}`)},
+			expectedErr: ErrInvalidParams,
+		},
+		{
+			name:        "Positional params",
+			req:         &models.RPCReq{Method: "get_account", Params: json.RawMessage(`["0x1"]`)},
+			expectedErr: ErrInvalidParams,
+		},
+		{
+			name:        "Unknown method",
+			req:         &models.RPCReq{Method: "get_events"},
+			expectedErr: ErrMethodNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restReq, err := AptosImpl{}.BuildRESTReq(tt.req)
+			if err != tt.expectedErr {
+				t.Fatalf("Test case %s: Expected error %v, got %v", tt.name, tt.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if restReq.Method != tt.expectedMethod {
+				t.Errorf("Test case %s: Expected method %s, got %s", tt.name, tt.expectedMethod, restReq.Method)
+			}
+			if restReq.Path != tt.expectedPath {
+				t.Errorf("Test case %s: Expected path %s, got %s", tt.name, tt.expectedPath, restReq.Path)
+			}
+			if restReq.Query.Encode() != tt.expectedQuery {
+				t.Errorf("Test case %s: Expected query %s, got %s", tt.name, tt.expectedQuery, restReq.Query.Encode())
+			}
+			if string(restReq.Body) != tt.expectedBody {
+				t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedBody, restReq.Body)
+			}
+		})
+	}
+}
+
+func TestAptosExtractRESTError(t *testing.T) {
+	body := []byte(`{"message":"Account not found by Address(0x2) and Ledger version(2150)","error_code":"account_not_found","vm_error_code":null}`)
+
+	rpcErr := AptosImpl{}.ExtractRESTError(http.StatusNotFound, body)
+	if rpcErr.Message != "Account not found by Address(0x2) and Ledger version(2150)" {
+		t.Errorf("Expected the message of the body, got %s", rpcErr.Message)
+	}
+	if string(rpcErr.Data) != string(body) {
+		t.Errorf("Expected the body as the data, got %s", rpcErr.Data)
+	}
+
+	rpcErr = AptosImpl{}.ExtractRESTError(http.StatusBadRequest, []byte("bad request"))
+	if rpcErr.Message != http.StatusText(http.StatusBadRequest) || rpcErr.Data != nil {
+		t.Errorf("Expected the status text without data, got %s %s", rpcErr.Message, rpcErr.Data)
+	}
+
+	rpcErr = AptosImpl{}.ExtractRESTError(http.StatusOK, []byte(`{"message":"not an error"}`))
+	if rpcErr != nil {
+		t.Errorf("Expected no error on successful responses, got %v", rpcErr)
+	}
+}
+
+func TestAptosFilterInvalidRPCReqs(t *testing.T) {
+	reqs := []*models.RPCReq{
+		{
+			Method: "get_accountAndLedgerVersion",
+			ID:     json.RawMessage("1"),
+			Params: json.RawMessage(`{"address":"0x1","ledger_version":"2000"}`),
+		},
+		{
+			Method: "get_accountAndLedgerVersion",
+			ID:     json.RawMessage("2"),
+			Params: json.RawMessage(`{"address":"0x1","ledger_version":"latest"}`),
+		},
+		{
+			Method: "get_accountAndLedgerVersion",
+			ID:     json.RawMessage("3"),
+			Params: json.RawMessage(`{"address":"0x1","ledger_version":-1}`),
+		},
+		{
+			Method: "viewAndLedgerVersion",
+			ID:     json.RawMessage("4"),
+			Params: json.RawMessage(`{"function":"0x1::coin::balance","type_arguments":[],"arguments":["0x1"]}`),
+		},
+		{
+			Method: "get_accountAndLedgerVersion",
+			ID:     json.RawMessage("5"),
+			Params: json.RawMessage(`["0x1"]`),
+		},
+	}
+
+	runFilterInvalidTests(t, "../supported-chains/aptos.json", reqs, []string{"1", "4"}, []string{"2", "3", "5"})
+}
+
+func TestAptosIsImmutableReq(t *testing.T) {
+	tests := []testCaseIsImmutableReq{
+		{
+			name:     "Version below finalized",
+			req:      &models.RPCReq{Method: "get_accountAndLedgerVersion", Params: json.RawMessage(`{"address":"0x1","ledger_version":"90"}`)},
+			expected: true,
+		},
+		{
+			name:     "Version above finalized",
+			req:      &models.RPCReq{Method: "get_accountAndLedgerVersion", Params: json.RawMessage(`{"address":"0x1","ledger_version":"110"}`)},
+			expected: false,
+		},
+		{
+			name:     "Latest version",
+			req:      &models.RPCReq{Method: "get_accountAndLedgerVersion", Params: json.RawMessage(`{"address":"0x1"}`)},
+			expected: false,
+		},
+	}
+
+	runIsImmutableTests(t, "../supported-chains/aptos.json", 100, tests)
+}
+
+// raw returns the JSON of the result as the buffered responses wrap its data
+func (r ledgerVersionResult) raw() json.RawMessage {
+	return splice(r, r.Data)
+}
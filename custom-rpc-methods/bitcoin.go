@@ -0,0 +1,243 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// BitcoinBlock is the getter of the Bitcoin methods, the hash of a block or empty for the best block
+type BitcoinBlock string
+
+// blockRef is the height and hash of the block a method was answered at
+type blockRef struct {
+	Height int
+	Hash   string
+}
+
+type blockHeightResult struct {
+	Data        json.RawMessage `json:"data"`
+	BlockHeight int             `json:"blockHeight"`
+	BlockHash   string          `json:"blockHash"`
+}
+
+// heights are numbers as on the bitcoind responses
+func (r blockHeightResult) envelope() ([]byte, []byte) {
+	return rawEnvelope("data", []string{"blockHeight", "blockHash"}, json.RawMessage(strconv.Itoa(r.BlockHeight)), rawString(r.BlockHash))
+}
+
+func (r blockHeightResult) resolvedBlock() string {
+	return strconv.Itoa(r.BlockHeight)
+}
+
+var (
+	ErrInternalBlockCountNotNumber = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 29,
+		Message:       "block count response is not a number",
+		HTTPErrorCode: 500,
+	}
+
+	ErrInternalBlockHeaderNotExpectedType = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 30,
+		Message:       "block header response does not have the height and hash",
+		HTTPErrorCode: 500,
+	}
+
+	ErrInternalBlockchainInfoNotExpectedType = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 38,
+		Message:       "blockchain info response does not have the blocks and best block hash",
+		HTTPErrorCode: 500,
+	}
+
+	bitcoinBlockHashRegex = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+)
+
+// bitcoinConfirmations is the depth at which Bitcoin blocks are considered final
+const bitcoinConfirmations = 6
+
+type BitcoinImpl struct{}
+
+func (b BitcoinImpl) GetChainType() ChainType {
+	return ChainTypeBitcoin
+}
+
+func (b BitcoinImpl) SupportsRange() bool {
+	return false
+}
+
+func buildGetBlockCountReq(id string) *models.RPCReq {
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "getblockcount",
+		ID:      json.RawMessage(id),
+	}
+}
+
+func (b BitcoinImpl) GetHealthCheckReq() *models.RPCReq {
+	return buildGetBlockCountReq("1")
+}
+
+func (b BitcoinImpl) ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error) {
+	height, err := strconv.ParseUint(string(res.Result), 10, 64)
+	if err != nil {
+		return 0, ErrInternalBlockCountNotNumber
+	}
+
+	return height, nil
+}
+
+func (b BitcoinImpl) GetBlockTime() time.Duration {
+	return 10 * time.Minute
+}
+
+func (b BitcoinImpl) GetFinalizedHeightReq() *models.RPCReq {
+	return buildGetBlockCountReq("1")
+}
+
+// bitcoin has no finality, blocks with enough confirmations are treated as final
+func (b BitcoinImpl) ExtractFinalizedHeight(res *models.RPCResJSON) (uint64, error) {
+	height, err := b.ExtractHeightFromHealthCheck(res)
+	if err != nil {
+		return 0, err
+	}
+	if height < bitcoinConfirmations {
+		return 0, nil
+	}
+
+	return height - bitcoinConfirmations, nil
+}
+
+func (b BitcoinImpl) GetDefaultGetter() BitcoinBlock {
+	return ""
+}
+
+func (b BitcoinImpl) GetDefaultGetterRange() []BitcoinBlock {
+	return nil
+}
+
+func (b BitcoinImpl) GetCustomHandlerMap() map[string]func(*models.RPCReq) ([]BitcoinBlock, error) {
+	return nil
+}
+
+func (b BitcoinImpl) FromGetterTypeToHolder(gth GetterTypesHolder) BitcoinBlock {
+	return gth.Bitcoin
+}
+
+func (b BitcoinImpl) FromHolderToGetterType(gt BitcoinBlock) GetterTypesHolder {
+	return GetterTypesHolder{
+		Bitcoin: gt,
+	}
+}
+
+// optional block hash params are null when they are skipped
+func (b BitcoinImpl) ExtractGetter(param interface{}) (BitcoinBlock, error) {
+	if param == nil {
+		return b.GetDefaultGetter(), nil
+	}
+
+	hash, ok := param.(string)
+	if !ok || !bitcoinBlockHashRegex.MatchString(hash) {
+		return "", ErrParseErr
+	}
+
+	return BitcoinBlock(hash), nil
+}
+
+// the best block is resolved with the blockchain info, so its height and hash are of the same block
+// and block hashes are resolved with their header that has both
+func (b BitcoinImpl) BuildGetterReq(id string, gt BitcoinBlock) (*models.RPCReq, error) {
+	if gt == "" {
+		return &models.RPCReq{
+			JSONRPC: "2.0",
+			Method:  "getblockchaininfo",
+			ID:      json.RawMessage(id),
+		}, nil
+	}
+
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "getblockheader",
+		ID:      json.RawMessage(id),
+		Params:  json.RawMessage(fmt.Sprintf(`["%s",true]`, gt)),
+	}, nil
+}
+
+func (b BitcoinImpl) GetIndexOfIDHolder(gt BitcoinBlock) (string, error) {
+	if gt == "" {
+		return "best", nil
+	}
+
+	return string(gt), nil
+}
+
+func (b BitcoinImpl) ExtractGetterReturnFromResponse(res *models.RPCResJSON) (blockRef, error) {
+	var block struct {
+		Hash          string `json:"hash"`
+		Height        *int   `json:"height"`
+		Blocks        *int   `json:"blocks"`
+		BestBlockHash string `json:"bestblockhash"`
+	}
+	err := json.Unmarshal(res.Result, &block)
+	if err != nil {
+		return blockRef{}, ErrInternalBlockHeaderNotExpectedType
+	}
+
+	// the blockchain info has the height and hash of the best block, the headers of their block
+	if block.Blocks != nil || block.BestBlockHash != "" {
+		if block.Blocks == nil || block.BestBlockHash == "" {
+			return blockRef{}, ErrInternalBlockchainInfoNotExpectedType
+		}
+		return blockRef{Height: *block.Blocks, Hash: block.BestBlockHash}, nil
+	}
+	if block.Hash == "" || block.Height == nil {
+		return blockRef{}, ErrInternalBlockHeaderNotExpectedType
+	}
+
+	return blockRef{Height: *block.Height, Hash: block.Hash}, nil
+}
+
+func (b BitcoinImpl) ExtractGetterReturnFromType(gt BitcoinBlock) (blockRef, error) {
+	return blockRef{}, ErrParseErr
+}
+
+func (b BitcoinImpl) ExtractGetterStruct(res *models.RPCResJSON, gr blockRef, contextRes *models.RPCResJSON) (blockHeightResult, error) {
+	return blockHeightResult{
+		Data:        res.Result,
+		BlockHeight: gr.Height,
+		BlockHash:   gr.Hash,
+	}, nil
+}
+
+func (b BitcoinImpl) ExtractGetterRangeStruct(res *models.RPCResJSON, grTo, grFrom blockRef) (noRangeSupported, error) {
+	return noRangeSupported{}, ErrParseErr
+}
+
+// the best block can't be requested by hash without resolving it first, and hashes are already pinned
+func (b BitcoinImpl) PinGetter(param interface{}, gt BitcoinBlock, gr blockRef) (interface{}, error) {
+	return param, nil
+}
+
+// bitcoind has no websocket subscriptions
+func (b BitcoinImpl) ExtractGetterReturnFromNotification(notification *models.RPCNotification) (blockRef, bool, error) {
+	return blockRef{}, false, nil
+}
+
+func NewBitcoinMethodBuilder() CustomRpcMethodBuilder {
+	return NewGenericConv(BitcoinImpl{})
+}
+
+func (b BitcoinImpl) IsImmutableGetter(gt BitcoinBlock, finalizedHeight uint64) bool {
+	return gt != ""
+}
+
+func (b BitcoinImpl) BuildContextReq(id string) *models.RPCReq {
+	return nil // the block height and hash are the only data added to the results
+}
+
+func (b BitcoinImpl) GetIndexOfContextReq() string {
+	return ""
+}
@@ -0,0 +1,254 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+const (
+	testBestBlockHash = "000000000000000000026d2c3c1f9e5f2c9f1f3b0a1c6a5b2d6e4a4b7e1f0c11"
+	testBlockHash     = "00000000000000000001c0a8a2b3f6e2d5e7c9b1a3f4d6e8c0b2a4f6d8e0c2a4"
+)
+
+func TestBitcoin(t *testing.T) {
+	tests := []testCaseGenericConv{
+		{
+			name: "Tx out best block",
+			req: []*models.RPCReq{{
+				Method: "gettxoutAndBlockHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["d5ada064c6417ca25c4308bd158c34b77e1c0eca2a73cda16c737e7424afba2f",0]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "gettxout",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"bestblock":"000000000000000000026d2c3c1f9e5f2c9f1f3b0a1c6a5b2d6e4a4b7e1f0c11","confirmations":1,"value":0.5}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"chain":"main","blocks":850000,"bestblockhash":"000000000000000000026d2c3c1f9e5f2c9f1f3b0a1c6a5b2d6e4a4b7e1f0c11"}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockHeightResult{
+					Data:        json.RawMessage(`{"bestblock":"000000000000000000026d2c3c1f9e5f2c9f1f3b0a1c6a5b2d6e4a4b7e1f0c11","confirmations":1,"value":0.5}`),
+					BlockHeight: 850000,
+					BlockHash:   testBestBlockHash,
+				}.raw(),
+			},
+			contentsToRewrite: []string{"best"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Block by hash",
+			req: []*models.RPCReq{{
+				Method: "getblockAndBlockHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["00000000000000000001c0a8a2b3f6e2d5e7c9b1a3f4d6e8c0b2a4f6d8e0c2a4",0]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getblock",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`"0100"`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"hash":"00000000000000000001c0a8a2b3f6e2d5e7c9b1a3f4d6e8c0b2a4f6d8e0c2a4","confirmations":3,"height":849998}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockHeightResult{
+					Data:        json.RawMessage(`"0100"`),
+					BlockHeight: 849998,
+					BlockHash:   testBlockHash,
+				}.raw(),
+			},
+			contentsToRewrite: []string{testBlockHash},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Raw transaction without block hash",
+			req: []*models.RPCReq{{
+				Method: "getrawtransactionAndBlockHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["d5ada064c6417ca25c4308bd158c34b77e1c0eca2a73cda16c737e7424afba2f"]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getrawtransaction",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`"0200"`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"chain":"main","blocks":850000,"bestblockhash":"000000000000000000026d2c3c1f9e5f2c9f1f3b0a1c6a5b2d6e4a4b7e1f0c11"}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockHeightResult{
+					Data:        json.RawMessage(`"0200"`),
+					BlockHeight: 850000,
+					BlockHash:   testBestBlockHash,
+				}.raw(),
+			},
+			contentsToRewrite: []string{"best"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Blockchain info without best block hash",
+			req: []*models.RPCReq{{
+				Method: "getblockchaininfoAndBlockHeight",
+				ID:     json.RawMessage("21"),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getblockchaininfo",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"chain":"main","blocks":850000}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"chain":"main","blocks":850000}`)}},
+			expectedErr:       ErrInternalBlockchainInfoNotExpectedType,
+			contentsToRewrite: []string{"best"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Blockchain info not an object",
+			req: []*models.RPCReq{{
+				Method: "getmempoolinfoAndBlockHeight",
+				ID:     json.RawMessage("21"),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getmempoolinfo",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"size":10}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`"850000"`)}},
+			expectedErr:       ErrInternalBlockHeaderNotExpectedType,
+			contentsToRewrite: []string{"best"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Block header without height",
+			req: []*models.RPCReq{{
+				Method: "getblockheaderAndBlockHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["00000000000000000001c0a8a2b3f6e2d5e7c9b1a3f4d6e8c0b2a4f6d8e0c2a4"]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getblockheader",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"hash":"00000000000000000001c0a8a2b3f6e2d5e7c9b1a3f4d6e8c0b2a4f6d8e0c2a4"}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"hash":"00000000000000000001c0a8a2b3f6e2d5e7c9b1a3f4d6e8c0b2a4f6d8e0c2a4"}`)}},
+			expectedErr:       ErrInternalBlockHeaderNotExpectedType,
+			contentsToRewrite: []string{testBlockHash},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "No block method",
+			req: []*models.RPCReq{{
+				Method: "getblockcount",
+				ID:     json.RawMessage("21"),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getblockcount",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage("850000"),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage("850000"),
+			},
+		},
+	}
+
+	runTests(t, "../supported-chains/bitcoin.json", tests)
+}
+
+func TestBitcoinFilterInvalidRPCReqs(t *testing.T) {
+	reqs := []*models.RPCReq{
+		{
+			Method: "getblockAndBlockHeight",
+			ID:     json.RawMessage("1"),
+			Params: json.RawMessage(`["00000000000000000001c0a8a2b3f6e2d5e7c9b1a3f4d6e8c0b2a4f6d8e0c2a4",1]`),
+		},
+		{
+			Method: "getblockAndBlockHeight",
+			ID:     json.RawMessage("2"),
+			Params: json.RawMessage(`[850000,1]`),
+		},
+		{
+			Method: "getrawtransactionAndBlockHeight",
+			ID:     json.RawMessage("3"),
+			Params: json.RawMessage(`["d5ada064c6417ca25c4308bd158c34b77e1c0eca2a73cda16c737e7424afba2f",true,"notahash"]`),
+		},
+		{
+			Method: "gettxoutAndBlockHeight",
+			ID:     json.RawMessage("4"),
+			Params: json.RawMessage(`["d5ada064c6417ca25c4308bd158c34b77e1c0eca2a73cda16c737e7424afba2f",0]`),
+		},
+	}
+
+	runFilterInvalidTests(t, "../supported-chains/bitcoin.json", reqs, []string{"1", "4"}, []string{"2", "3"})
+}
+
+func TestBitcoinExtractFinalizedHeight(t *testing.T) {
+	tests := []struct {
+		name        string
+		res         *models.RPCResJSON
+		expected    uint64
+		expectedErr error
+	}{
+		{
+			name:     "Confirmed depth",
+			res:      &models.RPCResJSON{Result: json.RawMessage("850000")},
+			expected: 849994,
+		},
+		{
+			name:     "Below confirmed depth",
+			res:      &models.RPCResJSON{Result: json.RawMessage("3")},
+			expected: 0,
+		},
+		{
+			name:        "Not a number",
+			res:         &models.RPCResJSON{Result: json.RawMessage(`"850000"`)},
+			expectedErr: ErrInternalBlockCountNotNumber,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			height, err := BitcoinImpl{}.ExtractFinalizedHeight(tt.res)
+			if err != tt.expectedErr {
+				t.Fatalf("Test case %s: Expected error %v, got %v", tt.name, tt.expectedErr, err)
+			}
+			if height != tt.expected {
+				t.Errorf("Test case %s: Expected height %d, got %d", tt.name, tt.expected, height)
+			}
+		})
+	}
+}
+
+// raw returns the JSON of the result as the buffered responses wrap its data
+func (r blockHeightResult) raw() json.RawMessage {
+	return splice(r, r.Data)
+}
@@ -0,0 +1,215 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// CosmosHeight is the getter of the Cosmos methods, 0 is the latest height as on CometBFT
+type CosmosHeight int64
+
+type heightResult struct {
+	Data   json.RawMessage `json:"data"`
+	Height string          `json:"height"`
+}
+
+// heights are strings as on the CometBFT responses
+func (r heightResult) envelope() ([]byte, []byte) {
+	return rawEnvelope("data", []string{"height"}, rawString(r.Height))
+}
+
+func (r heightResult) resolvedBlock() string {
+	return r.Height
+}
+
+var (
+	ErrInternalStatusNotExpectedType = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 28,
+		Message:       "status response does not have the latest block height",
+		HTTPErrorCode: 500,
+	}
+
+	cosmosMethodNameToCustomHandler = make(map[string]func(*models.RPCReq) ([]CosmosHeight, error))
+)
+
+func init() {
+	SaveCustomHandlersToMap(cosmosCustomHandlersHolder{}, cosmosMethodNameToCustomHandler)
+}
+
+type CosmosImpl struct{}
+
+func (c CosmosImpl) GetChainType() ChainType {
+	return ChainTypeCosmos
+}
+
+func (c CosmosImpl) SupportsRange() bool {
+	return false
+}
+
+func (c CosmosImpl) GetHealthCheckReq() *models.RPCReq {
+	return buildStatusReq("1")
+}
+
+func (c CosmosImpl) ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error) {
+	height, err := c.ExtractGetterReturnFromResponse(res)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(height, 10, 64)
+}
+
+func (c CosmosImpl) GetBlockTime() time.Duration {
+	return 6 * time.Second
+}
+
+// CometBFT has instant finality, the latest height is already final
+func (c CosmosImpl) GetFinalizedHeightReq() *models.RPCReq {
+	return buildStatusReq("1")
+}
+
+func (c CosmosImpl) ExtractFinalizedHeight(res *models.RPCResJSON) (uint64, error) {
+	return c.ExtractHeightFromHealthCheck(res)
+}
+
+func (c CosmosImpl) GetDefaultGetter() CosmosHeight {
+	return 0
+}
+
+func (c CosmosImpl) GetDefaultGetterRange() []CosmosHeight {
+	return nil
+}
+
+func (c CosmosImpl) GetCustomHandlerMap() map[string]func(*models.RPCReq) ([]CosmosHeight, error) {
+	return cosmosMethodNameToCustomHandler
+}
+
+func (c CosmosImpl) FromGetterTypeToHolder(gth GetterTypesHolder) CosmosHeight {
+	return gth.Cosmos
+}
+
+func (c CosmosImpl) FromHolderToGetterType(gt CosmosHeight) GetterTypesHolder {
+	return GetterTypesHolder{
+		Cosmos: gt,
+	}
+}
+
+// heights can be sent as strings or as numbers, a missing height is the latest one
+func (c CosmosImpl) ExtractGetter(param interface{}) (CosmosHeight, error) {
+	switch height := param.(type) {
+	case nil:
+		return c.GetDefaultGetter(), nil
+	case string:
+		if height == "" {
+			return c.GetDefaultGetter(), nil
+		}
+		h, err := strconv.ParseInt(height, 10, 64)
+		if err != nil || h < 0 {
+			return 0, ErrParseErr
+		}
+		return CosmosHeight(h), nil
+	case float64:
+		if height < 0 || height != math.Trunc(height) || height > math.MaxInt64 {
+			return 0, ErrParseErr
+		}
+		return CosmosHeight(height), nil
+	}
+
+	return 0, ErrParseErr
+}
+
+func buildStatusReq(id string) *models.RPCReq {
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "status",
+		ID:      json.RawMessage(id),
+	}
+}
+
+func (c CosmosImpl) BuildGetterReq(id string, gt CosmosHeight) (*models.RPCReq, error) {
+	if gt != 0 {
+		return nil, ErrParseErr // explicit heights don't need a getter req
+	}
+
+	return buildStatusReq(id), nil
+}
+
+func (c CosmosImpl) GetIndexOfIDHolder(gt CosmosHeight) (string, error) {
+	if gt != 0 {
+		return "", nil
+	}
+
+	return "latest", nil
+}
+
+func (c CosmosImpl) ExtractGetterReturnFromResponse(res *models.RPCResJSON) (string, error) {
+	var status struct {
+		SyncInfo map[string]json.RawMessage `json:"sync_info"`
+	}
+	err := json.Unmarshal(res.Result, &status)
+	if err != nil {
+		return "", ErrInternalStatusNotExpectedType
+	}
+
+	height, ok := stringOf(status.SyncInfo["latest_block_height"])
+	if !ok {
+		return "", ErrInternalStatusNotExpectedType
+	}
+	if _, err := strconv.ParseUint(height, 10, 64); err != nil {
+		return "", ErrInternalStatusNotExpectedType
+	}
+
+	return height, nil
+}
+
+func (c CosmosImpl) ExtractGetterReturnFromType(gt CosmosHeight) (string, error) {
+	if gt == 0 {
+		return "", ErrParseErr
+	}
+
+	return strconv.FormatInt(int64(gt), 10), nil
+}
+
+func (c CosmosImpl) ExtractGetterStruct(res *models.RPCResJSON, gr string, contextRes *models.RPCResJSON) (heightResult, error) {
+	return heightResult{
+		Data:   res.Result,
+		Height: gr,
+	}, nil
+}
+
+func (c CosmosImpl) ExtractGetterRangeStruct(res *models.RPCResJSON, grTo, grFrom string) (noRangeSupported, error) {
+	return noRangeSupported{}, ErrParseErr
+}
+
+func (c CosmosImpl) PinGetter(param interface{}, gt CosmosHeight, gr string) (interface{}, error) {
+	if gt != 0 {
+		return param, nil // explicit heights are already pinned
+	}
+
+	return gr, nil
+}
+
+// event notifications are not tied to a height that can be attached
+func (c CosmosImpl) ExtractGetterReturnFromNotification(notification *models.RPCNotification) (string, bool, error) {
+	return "", false, nil
+}
+
+func NewCosmosMethodBuilder() CustomRpcMethodBuilder {
+	return NewGenericConv(CosmosImpl{})
+}
+
+func (c CosmosImpl) IsImmutableGetter(gt CosmosHeight, finalizedHeight uint64) bool {
+	return gt > 0 && uint64(gt) <= finalizedHeight
+}
+
+func (c CosmosImpl) BuildContextReq(id string) *models.RPCReq {
+	return nil // the height is the only data added to the results
+}
+
+func (c CosmosImpl) GetIndexOfContextReq() string {
+	return ""
+}
@@ -0,0 +1,50 @@
+package customrpcmethods
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// cosmosHeightParam returns the height of the named height param
+// or the one of the position if the params are positional, CometBFT accepts both forms
+func cosmosHeightParam(req *models.RPCReq, pos int) ([]CosmosHeight, error) {
+	var height interface{}
+	if bytes.HasPrefix(bytes.TrimSpace(req.Params), []byte("{")) {
+		var p map[string]interface{}
+		err := json.Unmarshal(req.Params, &p)
+		if err != nil {
+			return nil, err
+		}
+		height = p["height"]
+	} else {
+		var p []interface{}
+		err := json.Unmarshal(req.Params, &p)
+		if err != nil {
+			return nil, err
+		}
+		if len(p) > pos {
+			height = p[pos]
+		}
+	}
+
+	gt, err := CosmosImpl{}.ExtractGetter(height)
+	if err != nil {
+		return nil, err
+	}
+
+	return []CosmosHeight{gt}, nil
+}
+
+type cosmosCustomHandlersHolder struct{}
+
+// HandleHeight extracts the height of the methods that have it as their first param, e.g. block or validators
+func (cosmosCustomHandlersHolder) HandleHeight(req *models.RPCReq) ([]CosmosHeight, error) {
+	return cosmosHeightParam(req, 0)
+}
+
+// HandleABCIQuery extracts the height of abci_query, its positional params are path, data, height and prove
+func (cosmosCustomHandlersHolder) HandleABCIQuery(req *models.RPCReq) ([]CosmosHeight, error) {
+	return cosmosHeightParam(req, 2)
+}
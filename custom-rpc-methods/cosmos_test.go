@@ -0,0 +1,398 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+func TestCosmos(t *testing.T) {
+	tests := []testCaseGenericConv{
+		{
+			name: "ABCI query named params latest",
+			req: []*models.RPCReq{{
+				Method: "abci_queryAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"path":"/store/bank/key","data":"01"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "abci_query",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"response":{"code":0}}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"node_info":{"network":"cosmoshub-4"},"sync_info":{"latest_block_hash":"AB","latest_block_height":"21"}}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: heightResult{
+					Data:   json.RawMessage(`{"response":{"code":0}}`),
+					Height: "21",
+				}.raw(),
+			},
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "ABCI query named height",
+			req: []*models.RPCReq{{
+				Method: "abci_queryAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"path":"/store/bank/key","data":"01","height":"15"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "abci_query",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"response":{"code":0}}`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: heightResult{
+					Data:   json.RawMessage(`{"response":{"code":0}}`),
+					Height: "15",
+				}.raw(),
+			},
+		},
+		{
+			name: "ABCI query positional height",
+			req: []*models.RPCReq{{
+				Method: "abci_queryAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["/store/bank/key","01",15,false]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "abci_query",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"response":{"code":0}}`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: heightResult{
+					Data:   json.RawMessage(`{"response":{"code":0}}`),
+					Height: "15",
+				}.raw(),
+			},
+		},
+		{
+			name: "ABCI query positional height zero",
+			req: []*models.RPCReq{{
+				Method: "abci_queryAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["/store/bank/key","01","0",false]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "abci_query",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"response":{"code":0}}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"node_info":{"network":"cosmoshub-4"},"sync_info":{"latest_block_hash":"AB","latest_block_height":"21"}}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: heightResult{
+					Data:   json.RawMessage(`{"response":{"code":0}}`),
+					Height: "21",
+				}.raw(),
+			},
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Block no params",
+			req: []*models.RPCReq{{
+				Method: "blockAndHeight",
+				ID:     json.RawMessage("21"),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "block",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"block":{}}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"node_info":{"network":"cosmoshub-4"},"sync_info":{"latest_block_hash":"AB","latest_block_height":"21"}}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: heightResult{
+					Data:   json.RawMessage(`{"block":{}}`),
+					Height: "21",
+				}.raw(),
+			},
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Block positional height",
+			req: []*models.RPCReq{{
+				Method: "blockAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["10"]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "block",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"block":{}}`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: heightResult{
+					Data:   json.RawMessage(`{"block":{}}`),
+					Height: "10",
+				}.raw(),
+			},
+		},
+		{
+			name: "Validators named height",
+			req: []*models.RPCReq{{
+				Method: "validatorsAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"height":"10","page":"1"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "validators",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"validators":[]}`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: heightResult{
+					Data:   json.RawMessage(`{"validators":[]}`),
+					Height: "10",
+				}.raw(),
+			},
+		},
+		{
+			name: "Tx search without height",
+			req: []*models.RPCReq{{
+				Method: "tx_searchAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"query":"tx.height>1"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "tx_search",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"txs":[],"total_count":"0"}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"node_info":{"network":"cosmoshub-4"},"sync_info":{"latest_block_hash":"AB","latest_block_height":"21"}}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: heightResult{
+					Data:   json.RawMessage(`{"txs":[],"total_count":"0"}`),
+					Height: "21",
+				}.raw(),
+			},
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Status response without height",
+			req: []*models.RPCReq{{
+				Method: "blockAndHeight",
+				ID:     json.RawMessage("21"),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "block",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"block":{}}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"sync_info":{}}`)}},
+			expectedErr:       ErrInternalStatusNotExpectedType,
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "No height method",
+			req: []*models.RPCReq{{
+				Method: "status",
+				ID:     json.RawMessage("21"),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "status",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"sync_info":{}}`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"sync_info":{}}`),
+			},
+		},
+	}
+
+	runTests(t, "../supported-chains/cosmos.json", tests)
+}
+
+func TestCosmosPinGetterParams(t *testing.T) {
+	tests := []testCasePinGetterParams{
+		{
+			name: "Block named params latest",
+			req: []*models.RPCReq{{
+				Method: "blockAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"node_info":{"network":"cosmoshub-4"},"sync_info":{"latest_block_hash":"AB","latest_block_height":"21"}}`)},
+			},
+			expectedParams: []string{`{"height":"21"}`},
+		},
+		{
+			name: "Block no params",
+			req: []*models.RPCReq{{
+				Method: "blockAndHeight",
+				ID:     json.RawMessage("21"),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"node_info":{"network":"cosmoshub-4"},"sync_info":{"latest_block_hash":"AB","latest_block_height":"21"}}`)},
+			},
+			expectedParams: []string{`["21"]`},
+		},
+		{
+			name: "Validators positional height zero",
+			req: []*models.RPCReq{{
+				Method: "validatorsAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["0"]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"node_info":{"network":"cosmoshub-4"},"sync_info":{"latest_block_hash":"AB","latest_block_height":"21"}}`)},
+			},
+			expectedParams: []string{`["21"]`},
+		},
+		{
+			name: "ABCI query positional latest",
+			req: []*models.RPCReq{{
+				Method: "abci_queryAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["/store/bank/key","01"]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"node_info":{"network":"cosmoshub-4"},"sync_info":{"latest_block_hash":"AB","latest_block_height":"21"}}`)},
+			},
+			expectedParams: []string{`["/store/bank/key","01","21"]`},
+		},
+		{
+			name: "ABCI query named latest",
+			req: []*models.RPCReq{{
+				Method: "abci_queryAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"path":"/store/bank/key","data":"01","prove":false}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"node_info":{"network":"cosmoshub-4"},"sync_info":{"latest_block_hash":"AB","latest_block_height":"21"}}`)},
+			},
+			expectedParams: []string{`{"path":"/store/bank/key","data":"01","prove":false,"height":"21"}`},
+		},
+		{
+			name: "ABCI query named height",
+			req: []*models.RPCReq{{
+				Method: "abci_queryAndHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"path":"/store/bank/key","data":"01","height":"15"}`),
+			}},
+			getterRes:      map[string]*models.RPCResJSON{},
+			expectedParams: []string{`{"path":"/store/bank/key","data":"01","height":"15"}`},
+		},
+	}
+
+	runPinTests(t, "../supported-chains/cosmos.json", tests)
+}
+
+func TestCosmosFilterInvalidRPCReqs(t *testing.T) {
+	reqs := []*models.RPCReq{
+		{
+			Method: "blockAndHeight",
+			ID:     json.RawMessage("1"),
+			Params: json.RawMessage(`{"height":"10"}`),
+		},
+		{
+			Method: "blockAndHeight",
+			ID:     json.RawMessage("2"),
+			Params: json.RawMessage(`{"height":"ten"}`),
+		},
+		{
+			Method: "abci_queryAndHeight",
+			ID:     json.RawMessage("3"),
+			Params: json.RawMessage(`["/store/bank/key","01",-1,false]`),
+		},
+		{
+			Method: "tx_searchAndHeight",
+			ID:     json.RawMessage("4"),
+			Params: json.RawMessage(`{"query":"tx.height>1"}`),
+		},
+	}
+
+	runFilterInvalidTests(t, "../supported-chains/cosmos.json", reqs, []string{"1", "4"}, []string{"2", "3"})
+}
+
+func TestCosmosIsImmutableReq(t *testing.T) {
+	tests := []testCaseIsImmutableReq{
+		{
+			name:     "Height below finalized",
+			req:      &models.RPCReq{Method: "abci_queryAndHeight", Params: json.RawMessage(`{"path":"/store/bank/key","height":"90"}`)},
+			expected: true,
+		},
+		{
+			name:     "Original method height below finalized",
+			req:      &models.RPCReq{Method: "block", Params: json.RawMessage(`["90"]`)},
+			expected: true,
+		},
+		{
+			name:     "Height above finalized",
+			req:      &models.RPCReq{Method: "blockAndHeight", Params: json.RawMessage(`{"height":"110"}`)},
+			expected: false,
+		},
+		{
+			name:     "Latest height",
+			req:      &models.RPCReq{Method: "blockAndHeight", Params: json.RawMessage(`{}`)},
+			expected: false,
+		},
+		{
+			name:     "Not cacheable",
+			req:      &models.RPCReq{Method: "tx_searchAndHeight", Params: json.RawMessage(`{"query":"tx.height=90"}`)},
+			expected: false,
+		},
+	}
+
+	runIsImmutableTests(t, "../supported-chains/cosmos.json", 100, tests)
+}
+
+// raw returns the JSON of the result as the buffered responses wrap its data
+func (r heightResult) raw() json.RawMessage {
+	return splice(r, r.Data)
+}
@@ -2,50 +2,156 @@ package customrpcmethods
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	gethRPC "github.com/ethereum/go-ethereum/rpc"
-	solanaRPC "github.com/gagliardetto/solana-go/rpc"
 	"github.com/stateless-solutions/compatibility-layer/models"
 )
 
 type ChainType string
 
 const (
-	ChainTypeEVM    ChainType = "evm"
-	ChainTypeSolana ChainType = "solana"
+	ChainTypeEVM      ChainType = "evm"
+	ChainTypeSolana   ChainType = "solana"
+	ChainTypeCosmos   ChainType = "cosmos"
+	ChainTypeBitcoin  ChainType = "bitcoin"
+	ChainTypeStarknet ChainType = "starknet"
+	ChainTypeNear     ChainType = "near"
+	ChainTypeAptos    ChainType = "aptos"
+	ChainTypeSui      ChainType = "sui"
+	ChainTypeTron     ChainType = "tron"
 )
 
 var (
-	chainTypeToMethodBuilder = map[ChainType]CustomRpcMethodBuilder{
-		ChainTypeEVM:    NewEVMMethodBuilder(),
-		ChainTypeSolana: NewSolanaMethodBuilder(),
+	// builders are created for each holder so holders of different routes don't share configs
+	chainTypeToMethodBuilder = map[ChainType]func() CustomRpcMethodBuilder{
+		ChainTypeEVM:      NewEVMMethodBuilder,
+		ChainTypeSolana:   NewSolanaMethodBuilder,
+		ChainTypeCosmos:   NewCosmosMethodBuilder,
+		ChainTypeBitcoin:  NewBitcoinMethodBuilder,
+		ChainTypeStarknet: NewStarknetMethodBuilder,
+		ChainTypeNear:     NewNearMethodBuilder,
+		ChainTypeAptos:    NewAptosMethodBuilder,
+		ChainTypeSui:      NewSuiMethodBuilder,
+		ChainTypeTron:     NewTronMethodBuilder,
 	}
-
-	errDifferentChainTypes = errors.New("different chain types in the same batch")
 )
 
 type Method struct {
-	OriginalMethod       string `json:"originalMethod"`
-	CustomMethod         string `json:"customMethod"`
-	PositionsGetterParam []int  `json:"positionsGetterParam,omitempty"`
-	CustomHandler        string `json:"customHandler,omitempty"`
-	IsRange              bool   `json:"isRange"`
+	OriginalMethod       string        `json:"originalMethod"`
+	CustomMethod         string        `json:"customMethod"`
+	PositionsGetterParam []int         `json:"positionsGetterParam,omitempty"`
+	KeysGetterParam      []string      `json:"keysGetterParam,omitempty"`
+	GetterPaths          []GetterPaths `json:"getterPaths,omitempty"`
+	CustomHandler        string        `json:"customHandler,omitempty"`
+	Plugin               *Plugin       `json:"plugin,omitempty"`
+	IsRange              bool          `json:"isRange"`
+	IsSubscription       bool          `json:"isSubscription,omitempty"`
+	Cacheable            bool          `json:"cacheable,omitempty"`
+}
+
+// GetterPaths are the JSON paths of the getter params of a method, e.g. $[0].fromBlock and $[0].toBlock
+// the getter paths of a method are alternatives, the first one with any of its paths on the params is used
+// and the last one is used if none of them is
+type GetterPaths struct {
+	Paths []string `json:"paths"`
+	// Defaults are the getter params of the paths that are not on the params by their position, null has no default
+	Defaults []interface{} `json:"defaults,omitempty"`
 }
 
 type MethodsConfig struct {
-	ChainNames []string  `json:"chainNames"`
-	ChainType  ChainType `json:"chainType"`
-	Methods    []Method  `json:"methods"`
+	ChainNames     []string  `json:"chainNames"`
+	ChainType      ChainType `json:"chainType"`
+	Upstreams      []string  `json:"upstreams,omitempty"`
+	UpstreamPolicy string    `json:"upstreamPolicy,omitempty"`
+	WSUpstream     string    `json:"wsUpstream,omitempty"`
+	BlockTimeMs    int64     `json:"blockTimeMs,omitempty"`
+	Precedence     int       `json:"precedence,omitempty"` // methods also defined by configs of other chain types are resolved to the highest precedence
+	Methods        []Method  `json:"methods"`
 }
 
 // this structure is needed bc you can't return directly a generic in a non generic func
 type GetterTypesHolder struct {
-	EVM    *gethRPC.BlockNumberOrHash
-	Solana solanaRPC.CommitmentType
+	EVM      *gethRPC.BlockNumberOrHash
+	Solana   SolanaGetter
+	Cosmos   CosmosHeight
+	Bitcoin  BitcoinBlock
+	Starknet StarknetBlockID
+	Near     NearBlockReference
+	Aptos    AptosLedgerVersion
+	Sui      SuiCheckpoint
+	Tron     TronBlock
+	// ChainType is the chain type of the method of the getter, set by the custom method holder
+	ChainType ChainType
+}
+
+// customResult is implemented by the results of custom methods
+// they splice the raw upstream result with the getter return they were resolved at, so the result is never decoded
+type customResult interface {
+	// envelope returns the JSON written before and after the upstream result
+	envelope() ([]byte, []byte)
+	resolvedBlock() string
+}
+
+// splice returns the upstream result wrapped with the envelope of the custom result
+func splice(r customResult, result json.RawMessage) json.RawMessage {
+	prefix, suffix := r.envelope()
+	result = rawOrNull(result)
+
+	buf := make([]byte, 0, len(prefix)+len(result)+len(suffix))
+	buf = append(buf, prefix...)
+	buf = append(buf, result...)
+	buf = append(buf, suffix...)
+
+	return buf
+}
+
+// rawOrNull returns the raw result or null if the upstream didn't return one
+func rawOrNull(result json.RawMessage) json.RawMessage {
+	if len(result) == 0 {
+		return json.RawMessage("null")
+	}
+
+	return result
+}
+
+// rawString returns the JSON of the string
+func rawString(s string) json.RawMessage {
+	data, _ := json.Marshal(s)
+	return data
+}
+
+// stringOf returns the string of a raw JSON string, null and other types are not strings
+func stringOf(raw json.RawMessage) (string, bool) {
+	var s string
+	if len(raw) == 0 || raw[0] != '"' || json.Unmarshal(raw, &s) != nil {
+		return "", false
+	}
+
+	return s, true
+}
+
+// rawEnvelope returns the envelope of an object whose first key holds the upstream result
+// and the rest of the keys hold the raw values in the same order, the keys are not escaped
+func rawEnvelope(resultKey string, keys []string, values ...json.RawMessage) ([]byte, []byte) {
+	prefix := []byte(`{"` + resultKey + `":`)
+
+	var suffix []byte
+	for i, key := range keys {
+		suffix = append(suffix, ',', '"')
+		suffix = append(suffix, key...)
+		suffix = append(suffix, '"', ':')
+		suffix = append(suffix, values[i]...)
+	}
+	suffix = append(suffix, '}')
+
+	return prefix, suffix
 }
 
 // functions in this interface are in the order they are called
@@ -54,42 +160,143 @@ type CustomRpcMethodBuilder interface {
 	PopulateConfig(gatewayMode bool, configs []MethodsConfig)
 	// HandleGatewayMode changes the rpc methods from regular to custom in case gateway mode is on
 	HandleGatewayMode(rpcReqs []*models.RPCReq) ([]*models.RPCReq, error)
+	// FilterInvalidRPCReqs returns the rpc reqs whose getters can be extracted and an error response for each of the invalid ones
+	FilterInvalidRPCReqs(rpcReqs []*models.RPCReq) ([]*models.RPCReq, []*models.RPCResJSON)
 	// GetCustomMethodsMap returns map of custom rpc methods to GetterType slice, slice needed in case of range
 	GetCustomMethodsMap(rpcReqs []*models.RPCReq) (map[string][]GetterTypesHolder, error)
 	// ChangeCustomMethods changes custom methods in rpc reqs slice to their original counterparts and returns map of the ID to original method
 	ChangeCustomMethods(rpcReqs []*models.RPCReq) (map[string]string, error)
 	// AddGetterMethodsIfNeeded returns rpc reqs with the getter rpc method for the getter struct and a map of tags to ID of the getter method
 	AddGetterMethodsIfNeeded(rpcReqs []*models.RPCReq, customMethodsMap map[string][]GetterTypesHolder) ([]*models.RPCReq, map[string]string, error)
+	// PinGetterParams rewrites the getter params of the rpc reqs to the concrete values resolved by the getter responses, only used on pinned mode
+	PinGetterParams(rpcReqs []*models.RPCReq, getterResponses []*models.RPCResJSON, changedMethods, idsHolder map[string]string, customMethodsMap map[string][]GetterTypesHolder) error
 	// ChangeCustomMethodsResponses returns responses originally input as rpc reqs based on the responses of previous funcs
 	ChangeCustomMethodsResponses(responses []*models.RPCResJSON, changedMethods, idsHolder map[string]string, customMethodsMap map[string][]GetterTypesHolder) ([]*models.RPCResJSON, error)
+	// ChangeSubscriptionNotification adds the getter struct to the notifications of subscriptions made with custom methods, only used on websockets
+	ChangeSubscriptionNotification(notification *models.RPCNotification) error
+	// BuildDefaultGetterReq returns the getter req of the default getter and the index of its id holder, only used by the head poller
+	BuildDefaultGetterReq(id string) (*models.RPCReq, string, error)
+	// IsImmutableReq returns if the rpc req is of a cacheable method and all its getters point to immutable blocks, only used by the response cache
+	IsImmutableReq(rpcReq *models.RPCReq, finalizedHeight uint64) bool
+	// ResultEnvelope returns the JSON to write before and after the result of the response of the id and the block it was resolved at
+	// both are empty if the id is not of a custom method, the error of a failed getter is returned to answer with it instead of the result
+	// only used when streaming responses
+	ResultEnvelope(id json.RawMessage, getterResponses []*models.RPCResJSON, changedMethods, idsHolder map[string]string, customMethodsMap map[string][]GetterTypesHolder) ([]byte, []byte, string, *models.RPCErr, error)
+	// WrapsResult returns if the results of the custom method are wrapped by its plugin, they can't be streamed since the plugin needs the whole result
+	WrapsResult(customMethod string) bool
 }
 
 // ImplementationPublicData is the interface of functions of public data to be used from repos that import the compatibility layer
 type ImplementationPublicData interface {
 	GetChainType() ChainType
 	SupportsRange() bool
+	// GetHealthCheckReq returns the rpc req used to check the health of the upstreams of the chain type
+	GetHealthCheckReq() *models.RPCReq
+	// ExtractHeightFromHealthCheck returns the height of the chain from the response of the health check req
+	ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error)
+	// GetBlockTime returns the usual time between blocks of the chain type, it can be overwritten on the config files
+	GetBlockTime() time.Duration
+	// GetFinalizedHeightReq returns the rpc req used to get the finalized height of the chain type
+	GetFinalizedHeightReq() *models.RPCReq
+	// ExtractFinalizedHeight returns the finalized height of the chain from the response of the finalized height req
+	ExtractFinalizedHeight(res *models.RPCResJSON) (uint64, error)
+	// BuildContextReq returns the req of the node data added to the custom structs, nil if the chain type has none
+	// the upstream pools fetch it with their health checks
+	BuildContextReq(id string) *models.RPCReq
 }
 
 // ChainTypeToPublicData is a map to be able to fetch various data from chain type when compatibility layer is expoted
 var ChainTypeToPublicData map[ChainType]ImplementationPublicData = map[ChainType]ImplementationPublicData{
-	ChainTypeEVM:    EVMImpl{},
-	ChainTypeSolana: SolanaImpl{},
+	ChainTypeEVM:      EVMImpl{},
+	ChainTypeSolana:   SolanaImpl{},
+	ChainTypeCosmos:   CosmosImpl{},
+	ChainTypeBitcoin:  BitcoinImpl{},
+	ChainTypeStarknet: StarknetImpl{},
+	ChainTypeNear:     NearImpl{},
+	ChainTypeAptos:    AptosImpl{},
+	ChainTypeSui:      SuiImpl{},
+	ChainTypeTron:     TronImpl{},
+}
+
+// RESTReq is the request of a REST API an rpc req is translated to
+type RESTReq struct {
+	Method  string
+	Path    string // escaped path relative to the URL of the upstream
+	Query   url.Values
+	Body    []byte
+	JSONRPC bool // the request is a JSON-RPC request and its response is a JSON-RPC response instead of the result
+}
+
+// RESTTranslator is implemented by the chain types whose upstreams serve a REST API instead of JSON-RPC
+// each rpc req is sent as a REST request and the body of its response is the result
+type RESTTranslator interface {
+	// BuildRESTReq returns the REST request of the rpc req, the error is returned as the error response of the rpc req
+	BuildRESTReq(req *models.RPCReq) (*RESTReq, error)
+	// ExtractRESTError returns the error of the rpc req from the REST response, nil if the response is successful
+	ExtractRESTError(statusCode int, body []byte) *models.RPCErr
+}
+
+// ChainTypeToRESTTranslator is a map of the chain types whose upstreams serve a REST API to their translators
+var ChainTypeToRESTTranslator map[ChainType]RESTTranslator = map[ChainType]RESTTranslator{
+	ChainTypeAptos: AptosImpl{},
+	ChainTypeTron:  TronImpl{},
 }
 
 type CustomMethodHolder struct {
 	ChainTypeToMethodBuilder  map[ChainType]CustomRpcMethodBuilder
 	CustomMethodToChainType   map[string]ChainType
 	OriginalMethodToChainType map[string]ChainType
+	SubscriptionMethods       map[string]bool
+	gatewayMode               bool
+	// methods defined by configs of different chain types with the same precedence, with their chain types
+	ambiguousCustomMethods   map[string][]ChainType
+	ambiguousOriginalMethods map[string][]ChainType
 }
 
-func NewCustomMethodHolder(gatewayMode bool, configFiles string) *CustomMethodHolder {
-	ch := &CustomMethodHolder{
-		ChainTypeToMethodBuilder:  make(map[ChainType]CustomRpcMethodBuilder, len(chainTypeToMethodBuilder)),
-		CustomMethodToChainType:   map[string]ChainType{},
-		OriginalMethodToChainType: map[string]ChainType{},
+// methodResolver resolves the chain type of the method names of the configs
+// names defined by configs of different chain types are resolved to the config with the highest precedence,
+// they are ambiguous if more than one chain type has the highest precedence
+type methodResolver struct {
+	chainTypes  map[string]ChainType
+	precedences map[string]int
+	ambiguous   map[string][]ChainType
+}
+
+func newMethodResolver() *methodResolver {
+	return &methodResolver{
+		chainTypes:  map[string]ChainType{},
+		precedences: map[string]int{},
+		ambiguous:   map[string][]ChainType{},
 	}
+}
 
-	configsMap := make(map[ChainType][]MethodsConfig, len(chainTypeToMethodBuilder))
+func (r *methodResolver) add(method string, chainType ChainType, precedence int) {
+	current, ok := r.chainTypes[method]
+	if !ok || precedence > r.precedences[method] {
+		r.chainTypes[method] = chainType
+		r.precedences[method] = precedence
+		delete(r.ambiguous, method)
+		return
+	}
+	if precedence < r.precedences[method] || chainType == current {
+		return
+	}
+
+	chainTypes, ok := r.ambiguous[method]
+	if !ok {
+		chainTypes = []ChainType{current}
+	}
+	for _, ct := range chainTypes {
+		if ct == chainType {
+			return
+		}
+	}
+	r.ambiguous[method] = append(chainTypes, chainType)
+}
+
+// ReadConfigFiles reads the config files separated by a comma
+func ReadConfigFiles(configFiles string) []MethodsConfig {
+	var configs []MethodsConfig
 	files := strings.Split(configFiles, ",")
 	for _, file := range files {
 		byteValue, err := os.ReadFile(file)
@@ -108,16 +315,56 @@ func NewCustomMethodHolder(gatewayMode bool, configFiles string) *CustomMethodHo
 			panic(fmt.Sprintf("invalid chain type: %s", config.ChainType))
 		}
 
+		// plugins are shipped next to the config files, so their paths don't depend on the working directory
+		for _, method := range config.Methods {
+			if method.Plugin != nil && !filepath.IsAbs(method.Plugin.Path) {
+				method.Plugin.Path = filepath.Join(filepath.Dir(file), method.Plugin.Path)
+			}
+		}
+
+		configs = append(configs, config)
+	}
+
+	return configs
+}
+
+func NewCustomMethodHolder(gatewayMode bool, configFiles string) *CustomMethodHolder {
+	return NewCustomMethodHolderFromConfigs(gatewayMode, ReadConfigFiles(configFiles))
+}
+
+func NewCustomMethodHolderFromConfigs(gatewayMode bool, configs []MethodsConfig) *CustomMethodHolder {
+	ch := &CustomMethodHolder{
+		ChainTypeToMethodBuilder: make(map[ChainType]CustomRpcMethodBuilder, len(chainTypeToMethodBuilder)),
+		SubscriptionMethods:      map[string]bool{},
+		gatewayMode:              gatewayMode,
+	}
+
+	customResolver := newMethodResolver()
+	originalResolver := newMethodResolver()
+	configsMap := make(map[ChainType][]MethodsConfig, len(chainTypeToMethodBuilder))
+	for _, config := range configs {
+		_, ok := chainTypeToMethodBuilder[config.ChainType]
+		if !ok {
+			panic(fmt.Sprintf("invalid chain type: %s", config.ChainType))
+		}
+
 		configsMap[config.ChainType] = append(configsMap[config.ChainType], config)
 
 		for _, method := range config.Methods {
-			ch.CustomMethodToChainType[method.CustomMethod] = config.ChainType
-			ch.OriginalMethodToChainType[method.OriginalMethod] = config.ChainType
+			customResolver.add(method.CustomMethod, config.ChainType, config.Precedence)
+			originalResolver.add(method.OriginalMethod, config.ChainType, config.Precedence)
+			if method.IsSubscription {
+				ch.SubscriptionMethods[method.CustomMethod] = true
+			}
 		}
 	}
+	ch.CustomMethodToChainType = customResolver.chainTypes
+	ch.OriginalMethodToChainType = originalResolver.chainTypes
+	ch.ambiguousCustomMethods = customResolver.ambiguous
+	ch.ambiguousOriginalMethods = originalResolver.ambiguous
 
 	for chainType, configs := range configsMap {
-		methodBuilder := chainTypeToMethodBuilder[chainType]
+		methodBuilder := chainTypeToMethodBuilder[chainType]()
 		methodBuilder.PopulateConfig(gatewayMode, configs)
 		ch.ChainTypeToMethodBuilder[chainType] = methodBuilder
 	}
@@ -125,125 +372,398 @@ func NewCustomMethodHolder(gatewayMode bool, configFiles string) *CustomMethodHo
 	return ch
 }
 
-func (ch *CustomMethodHolder) getChainTypeFromRPCReqCustomMethods(rpcReqs []*models.RPCReq) (ChainType, error) {
-	var customMethods []string
-	for _, rpcReq := range rpcReqs {
-		customMethods = append(customMethods, rpcReq.Method)
+// GetChainTypes returns the chain types of the loaded config files sorted by name
+func (ch *CustomMethodHolder) GetChainTypes() []ChainType {
+	var chainTypes []ChainType
+	for chainType := range ch.ChainTypeToMethodBuilder {
+		chainTypes = append(chainTypes, chainType)
 	}
+	sort.Slice(chainTypes, func(i, j int) bool {
+		return chainTypes[i] < chainTypes[j]
+	})
 
-	return ch.getChainTypeFromCustomMethods(customMethods)
+	return chainTypes
 }
 
-func (ch *CustomMethodHolder) getChainTypeFromCustomMethodsMaps(customMethodsMap map[string]string) (ChainType, error) {
-	var customMethods []string
-	for _, customMethod := range customMethodsMap {
-		customMethods = append(customMethods, customMethod)
+// ambiguousMethodErr returns the error of the rpc req if its method is ambiguous, nil if it isn't
+// original methods are only translated on gateway mode, otherwise they are forwarded as they are
+func (ch *CustomMethodHolder) ambiguousMethodErr(rpcReq *models.RPCReq) *models.RPCErr {
+	chainTypes, ok := ch.ambiguousCustomMethods[rpcReq.Method]
+	if !ok && ch.gatewayMode {
+		chainTypes, ok = ch.ambiguousOriginalMethods[rpcReq.Method]
+	}
+	if !ok {
+		return nil
+	}
+
+	names := make([]string, 0, len(chainTypes))
+	for _, chainType := range chainTypes {
+		names = append(names, string(chainType))
 	}
 
-	return ch.getChainTypeFromCustomMethods(customMethods)
+	return &models.RPCErr{
+		Code:          ErrAmbiguousMethod.Code,
+		Message:       fmt.Sprintf("method %s is defined by the chain types %s, use the route of its chain or set the precedence of its configs", rpcReq.Method, strings.Join(names, ", ")),
+		HTTPErrorCode: ErrAmbiguousMethod.HTTPErrorCode,
+	}
 }
 
-func (ch *CustomMethodHolder) getChainTypeFromCustomMethods(customMethods []string) (ChainType, error) {
-	var chainType ChainType
-	for _, customMethod := range customMethods {
-		reqChaintype, ok := ch.CustomMethodToChainType[customMethod]
-		if ok {
-			if chainType == "" {
-				chainType = reqChaintype
-			} else {
-				if chainType != reqChaintype {
-					return "", errDifferentChainTypes
-				}
+// chainTypeReqs are the rpc reqs of a batch whose methods are of the same chain type and their positions on the batch
+type chainTypeReqs struct {
+	chainType ChainType
+	reqs      []*models.RPCReq
+	positions []int
+}
+
+// splitRPCReqs returns the rpc reqs of each chain type of the batch in the order their chain types first appear
+// reqs whose methods are not on the configs are left out
+func splitRPCReqs(rpcReqs []*models.RPCReq, methodToChainType map[string]ChainType) []*chainTypeReqs {
+	var groups []*chainTypeReqs
+	byChainType := map[ChainType]*chainTypeReqs{}
+	for i, rpcReq := range rpcReqs {
+		chainType, ok := methodToChainType[rpcReq.Method]
+		if !ok {
+			continue
+		}
+
+		group, ok := byChainType[chainType]
+		if !ok {
+			group = &chainTypeReqs{chainType: chainType}
+			byChainType[chainType] = group
+			groups = append(groups, group)
+		}
+		group.reqs = append(group.reqs, rpcReq)
+		group.positions = append(group.positions, i)
+	}
+
+	return groups
+}
+
+// chainTypeBatch is the state of the custom methods of a batch that belongs to a chain type
+type chainTypeBatch struct {
+	chainType        ChainType
+	customMethodsMap map[string][]GetterTypesHolder
+	changedMethods   map[string]string
+	idsHolder        map[string]string
+}
+
+// splitBatch returns the state of the custom methods of each chain type of the batch sorted by chain type
+// the indexes of the id holders of batches of more than one chain type are prefixed with their chain type,
+// so the same tag of different chain types (e.g. latest) has its own getter req
+func splitBatch(customMethodsMap map[string][]GetterTypesHolder, changedMethods, idsHolder map[string]string) []*chainTypeBatch {
+	byChainType := map[ChainType]*chainTypeBatch{}
+	for id, gts := range customMethodsMap {
+		if len(gts) == 0 {
+			continue
+		}
+
+		chainType := gts[0].ChainType
+		batch, ok := byChainType[chainType]
+		if !ok {
+			batch = &chainTypeBatch{
+				chainType:        chainType,
+				customMethodsMap: map[string][]GetterTypesHolder{},
+				changedMethods:   map[string]string{},
+				idsHolder:        map[string]string{},
 			}
+			byChainType[chainType] = batch
+		}
+		batch.customMethodsMap[id] = gts
+		if customMethod, ok := changedMethods[id]; ok {
+			batch.changedMethods[id] = customMethod
 		}
 	}
 
-	return chainType, nil
+	batches := make([]*chainTypeBatch, 0, len(byChainType))
+	for _, batch := range byChainType {
+		batches = append(batches, batch)
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].chainType < batches[j].chainType
+	})
+
+	for _, batch := range batches {
+		prefix := idsHolderPrefix(batch.chainType, len(batches))
+		for index, id := range idsHolder {
+			if strings.HasPrefix(index, prefix) {
+				batch.idsHolder[strings.TrimPrefix(index, prefix)] = id
+			}
+		}
+	}
+
+	return batches
 }
 
-func (ch *CustomMethodHolder) getChainTypeFromRPCReqOriginalMethods(rpcReqs []*models.RPCReq) (ChainType, error) {
-	var originalMethods []string
-	for _, rpcReq := range rpcReqs {
-		originalMethods = append(originalMethods, rpcReq.Method)
+// idsHolderPrefix returns the prefix of the indexes of the id holders of the chain type
+func idsHolderPrefix(chainType ChainType, chainTypes int) string {
+	if chainTypes <= 1 {
+		return ""
 	}
 
-	return ch.getChainTypeFromOriginalMethods(originalMethods)
+	return string(chainType) + ":"
 }
 
-func (ch *CustomMethodHolder) getChainTypeFromOriginalMethods(originalMethods []string) (ChainType, error) {
-	var chainType ChainType
-	for _, originalMethod := range originalMethods {
-		reqChaintype, ok := ch.OriginalMethodToChainType[originalMethod]
-		if ok {
-			if chainType == "" {
-				chainType = reqChaintype
-			} else {
-				if chainType != reqChaintype {
-					return "", errDifferentChainTypes
-				}
+func (ch *CustomMethodHolder) HandleGatewayMode(rpcReqs []*models.RPCReq) ([]*models.RPCReq, error) {
+	methodToChainType := ch.OriginalMethodToChainType
+	if len(ch.ambiguousOriginalMethods) > 0 {
+		methodToChainType = make(map[string]ChainType, len(ch.OriginalMethodToChainType))
+		for method, chainType := range ch.OriginalMethodToChainType {
+			if _, ok := ch.ambiguousOriginalMethods[method]; !ok {
+				methodToChainType[method] = chainType
 			}
 		}
 	}
 
-	return chainType, nil
+	groups := splitRPCReqs(rpcReqs, methodToChainType)
+	if len(groups) == 0 {
+		return rpcReqs, nil
+	}
+
+	merged := make([]*models.RPCReq, len(rpcReqs))
+	copy(merged, rpcReqs)
+	for _, group := range groups {
+		reqs, err := ch.ChainTypeToMethodBuilder[group.chainType].HandleGatewayMode(group.reqs)
+		if err != nil {
+			return nil, err
+		}
+		for i, pos := range group.positions {
+			merged[pos] = reqs[i]
+		}
+	}
+
+	return merged, nil
 }
 
-func (ch *CustomMethodHolder) HandleGatewayMode(rpcReqs []*models.RPCReq) ([]*models.RPCReq, error) {
-	chainType, err := ch.getChainTypeFromRPCReqOriginalMethods(rpcReqs)
-	if err != nil {
-		return nil, err
+// FilterInvalidRPCReqs returns the valid rpc reqs in their order and an error response for each of the invalid ones
+// reqs with an ambiguous method are invalid
+func (ch *CustomMethodHolder) FilterInvalidRPCReqs(rpcReqs []*models.RPCReq) ([]*models.RPCReq, []*models.RPCResJSON, error) {
+	errByReq := map[*models.RPCReq]*models.RPCResJSON{}
+	var unambiguousReqs []*models.RPCReq
+	for _, rpcReq := range rpcReqs {
+		rpcErr := ch.ambiguousMethodErr(rpcReq)
+		if rpcErr != nil {
+			errByReq[rpcReq] = &models.RPCResJSON{
+				JSONRPC: "2.0",
+				Error:   rpcErr,
+				ID:      rpcReq.ID,
+			}
+			continue
+		}
+		unambiguousReqs = append(unambiguousReqs, rpcReq)
 	}
-	if chainType == "" {
-		return rpcReqs, nil
+
+	for _, group := range splitRPCReqs(unambiguousReqs, ch.CustomMethodToChainType) {
+		validReqs, errRess := ch.ChainTypeToMethodBuilder[group.chainType].FilterInvalidRPCReqs(group.reqs)
+
+		valid := make(map[*models.RPCReq]bool, len(validReqs))
+		for _, req := range validReqs {
+			valid[req] = true
+		}
+		// the error responses are in the order of the invalid reqs
+		var i int
+		for _, req := range group.reqs {
+			if !valid[req] && i < len(errRess) {
+				errByReq[req] = errRess[i]
+				i++
+			}
+		}
+	}
+	if len(errByReq) == 0 {
+		return rpcReqs, nil, nil
+	}
+
+	var validReqs []*models.RPCReq
+	var errRess []*models.RPCResJSON
+	for _, rpcReq := range rpcReqs {
+		errRes, ok := errByReq[rpcReq]
+		if ok {
+			errRess = append(errRess, errRes)
+			continue
+		}
+		validReqs = append(validReqs, rpcReq)
 	}
 
-	return ch.ChainTypeToMethodBuilder[chainType].HandleGatewayMode(rpcReqs)
+	return validReqs, errRess, nil
 }
 
+// GetCustomMethodsMap returns the getters of the custom methods of all the chain types of the batch
+// the getters are tagged with the chain type of their method
 func (ch *CustomMethodHolder) GetCustomMethodsMap(rpcReqs []*models.RPCReq) (map[string][]GetterTypesHolder, error) {
-	chainType, err := ch.getChainTypeFromRPCReqCustomMethods(rpcReqs)
-	if err != nil {
-		return nil, err
-	}
-	if chainType == "" {
+	groups := splitRPCReqs(rpcReqs, ch.CustomMethodToChainType)
+	if len(groups) == 0 {
 		return nil, nil
 	}
 
-	return ch.ChainTypeToMethodBuilder[chainType].GetCustomMethodsMap(rpcReqs)
+	customMethodsMap := map[string][]GetterTypesHolder{}
+	for _, group := range groups {
+		groupMap, err := ch.ChainTypeToMethodBuilder[group.chainType].GetCustomMethodsMap(group.reqs)
+		if err != nil {
+			return nil, err
+		}
+		for id, gts := range groupMap {
+			for i := range gts {
+				gts[i].ChainType = group.chainType
+			}
+			customMethodsMap[id] = gts
+		}
+	}
+
+	return customMethodsMap, nil
 }
 
 func (ch *CustomMethodHolder) ChangeCustomMethods(rpcReqs []*models.RPCReq) (map[string]string, error) {
-	chainType, err := ch.getChainTypeFromRPCReqCustomMethods(rpcReqs)
-	if err != nil {
-		return nil, err
-	}
-	if chainType == "" {
+	groups := splitRPCReqs(rpcReqs, ch.CustomMethodToChainType)
+	if len(groups) == 0 {
 		return nil, nil
 	}
 
-	return ch.ChainTypeToMethodBuilder[chainType].ChangeCustomMethods(rpcReqs)
+	changedMethods := make(map[string]string, len(rpcReqs))
+	for _, group := range groups {
+		groupChanged, err := ch.ChainTypeToMethodBuilder[group.chainType].ChangeCustomMethods(group.reqs)
+		if err != nil {
+			return nil, err
+		}
+		for id, customMethod := range groupChanged {
+			changedMethods[id] = customMethod
+		}
+	}
+
+	return changedMethods, nil
 }
 
+// AddGetterMethodsIfNeeded adds the getter reqs of each chain type of the batch after the rpc reqs
 func (ch *CustomMethodHolder) AddGetterMethodsIfNeeded(rpcReqs []*models.RPCReq, customMethodsMap map[string][]GetterTypesHolder) ([]*models.RPCReq, map[string]string, error) {
-	chainType, err := ch.getChainTypeFromRPCReqOriginalMethods(rpcReqs)
-	if err != nil {
-		return nil, nil, err
-	}
-	if chainType == "" || customMethodsMap == nil {
+	if customMethodsMap == nil {
 		return rpcReqs, nil, nil
 	}
 
-	return ch.ChainTypeToMethodBuilder[chainType].AddGetterMethodsIfNeeded(rpcReqs, customMethodsMap)
+	batches := splitBatch(customMethodsMap, nil, nil)
+	idsHolder := map[string]string{}
+	for _, batch := range batches {
+		var batchIDsHolder map[string]string
+		var err error
+		// all the rpc reqs are passed so the ids of the getter reqs are not repeated on the batch
+		rpcReqs, batchIDsHolder, err = ch.ChainTypeToMethodBuilder[batch.chainType].AddGetterMethodsIfNeeded(rpcReqs, batch.customMethodsMap)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		prefix := idsHolderPrefix(batch.chainType, len(batches))
+		for index, id := range batchIDsHolder {
+			idsHolder[prefix+index] = id
+		}
+	}
+
+	return rpcReqs, idsHolder, nil
 }
 
-func (ch *CustomMethodHolder) ChangeCustomMethodsResponses(responses []*models.RPCResJSON, changedMethods, idsHolder map[string]string, customMethodsMap map[string][]GetterTypesHolder) ([]*models.RPCResJSON, error) {
-	chainType, err := ch.getChainTypeFromCustomMethodsMaps(changedMethods)
-	if err != nil {
-		return nil, err
+func (ch *CustomMethodHolder) PinGetterParams(rpcReqs []*models.RPCReq, getterResponses []*models.RPCResJSON, changedMethods, idsHolder map[string]string, customMethodsMap map[string][]GetterTypesHolder) error {
+	if customMethodsMap == nil {
+		return nil
+	}
+
+	for _, batch := range splitBatch(customMethodsMap, changedMethods, idsHolder) {
+		err := ch.ChainTypeToMethodBuilder[batch.chainType].PinGetterParams(rpcReqs, getterResponses, batch.changedMethods, batch.idsHolder, batch.customMethodsMap)
+		if err != nil {
+			return err
+		}
 	}
-	if chainType == "" || customMethodsMap == nil {
+
+	return nil
+}
+
+// ChangeCustomMethodsResponses changes the responses of the custom methods of each chain type of the batch
+// each chain type removes the responses of its getter reqs, the responses are kept in their order
+func (ch *CustomMethodHolder) ChangeCustomMethodsResponses(responses []*models.RPCResJSON, changedMethods, idsHolder map[string]string, customMethodsMap map[string][]GetterTypesHolder) ([]*models.RPCResJSON, error) {
+	if customMethodsMap == nil {
 		return responses, nil
 	}
 
-	return ch.ChainTypeToMethodBuilder[chainType].ChangeCustomMethodsResponses(responses, changedMethods, idsHolder, customMethodsMap)
+	for _, batch := range splitBatch(customMethodsMap, changedMethods, idsHolder) {
+		var err error
+		responses, err = ch.ChainTypeToMethodBuilder[batch.chainType].ChangeCustomMethodsResponses(responses, batch.changedMethods, batch.idsHolder, batch.customMethodsMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return responses, nil
+}
+
+func (ch *CustomMethodHolder) ChangeSubscriptionNotification(chainType ChainType, notification *models.RPCNotification) error {
+	methodBuilder, ok := ch.ChainTypeToMethodBuilder[chainType]
+	if !ok {
+		return nil
+	}
+
+	return methodBuilder.ChangeSubscriptionNotification(notification)
+}
+
+func (ch *CustomMethodHolder) BuildDefaultGetterReq(chainType ChainType, id string) (*models.RPCReq, string, error) {
+	methodBuilder, ok := ch.ChainTypeToMethodBuilder[chainType]
+	if !ok {
+		return nil, "", fmt.Errorf("chain type %s is not on the config files", chainType)
+	}
+
+	return methodBuilder.BuildDefaultGetterReq(id)
+}
+
+// IsImmutableReq returns if the response of the rpc req can be cached, the finalized height is 0 if it is unknown
+// reqs of ambiguous methods are never cached
+func (ch *CustomMethodHolder) IsImmutableReq(rpcReq *models.RPCReq, finalizedHeight uint64) bool {
+	chainType, ok := ch.CustomMethodToChainType[rpcReq.Method]
+	if ok {
+		if _, ambiguous := ch.ambiguousCustomMethods[rpcReq.Method]; ambiguous {
+			return false
+		}
+	} else {
+		chainType, ok = ch.OriginalMethodToChainType[rpcReq.Method]
+		if !ok {
+			return false
+		}
+		if _, ambiguous := ch.ambiguousOriginalMethods[rpcReq.Method]; ambiguous {
+			return false
+		}
+	}
+
+	return ch.ChainTypeToMethodBuilder[chainType].IsImmutableReq(rpcReq, finalizedHeight)
+}
+
+// ResultEnvelope returns the JSON to write before and after the streamed result of the response of the id and the block it was resolved at
+// the error of a failed getter is returned to answer with it instead of the result
+func (ch *CustomMethodHolder) ResultEnvelope(id json.RawMessage, getterResponses []*models.RPCResJSON, changedMethods, idsHolder map[string]string, customMethodsMap map[string][]GetterTypesHolder) ([]byte, []byte, string, *models.RPCErr, error) {
+	if customMethodsMap == nil {
+		return nil, nil, "", nil, nil
+	}
+
+	for _, batch := range splitBatch(customMethodsMap, changedMethods, idsHolder) {
+		if _, ok := batch.changedMethods[string(id)]; ok {
+			return ch.ChainTypeToMethodBuilder[batch.chainType].ResultEnvelope(id, getterResponses, batch.changedMethods, batch.idsHolder, batch.customMethodsMap)
+		}
+	}
+
+	return nil, nil, "", nil, nil
+}
+
+// WrapsResult returns if the result of the response of the id is wrapped by the plugin of its custom method
+func (ch *CustomMethodHolder) WrapsResult(id json.RawMessage, changedMethods map[string]string) bool {
+	customMethod, ok := changedMethods[string(id)]
+	if !ok {
+		return false
+	}
+	chainType, ok := ch.CustomMethodToChainType[customMethod]
+	if !ok {
+		return false
+	}
+
+	return ch.ChainTypeToMethodBuilder[chainType].WrapsResult(customMethod)
+}
+
+// GetBlockTime returns the block time of the config if it is set or the one of its chain type
+func (config MethodsConfig) GetBlockTime() time.Duration {
+	if config.BlockTimeMs > 0 {
+		return time.Duration(config.BlockTimeMs) * time.Millisecond
+	}
+
+	return ChainTypeToPublicData[config.ChainType].GetBlockTime()
 }
@@ -0,0 +1,132 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+func TestMixedChainTypesBatch(t *testing.T) {
+	ch := NewCustomMethodHolder(false, "../supported-chains/ethereum.json,../supported-chains/starknet.json")
+
+	reqs := []*models.RPCReq{
+		{JSONRPC: "2.0", Method: "eth_getBalanceAndBlockNumber", ID: json.RawMessage("1"), Params: json.RawMessage(`["0x1","latest"]`)},
+		{JSONRPC: "2.0", Method: "starknet_getNonceAndBlockNumber", ID: json.RawMessage("2"), Params: json.RawMessage(`["latest","0x1"]`)},
+		{JSONRPC: "2.0", Method: "eth_chainId", ID: json.RawMessage("3")},
+	}
+
+	reqs, errRess, err := ch.FilterInvalidRPCReqs(reqs)
+	if err != nil || len(errRess) != 0 || len(reqs) != 3 {
+		t.Fatalf("Expected all the reqs to be valid, got %d valid, %v errors and error %v", len(reqs), errRess, err)
+	}
+	customMethodsMap, err := ch.GetCustomMethodsMap(reqs)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	changedMethods, err := ch.ChangeCustomMethods(reqs)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	if reqs[0].Method != "eth_getBalance" || reqs[1].Method != "starknet_getNonce" {
+		t.Errorf("Expected the methods of both chain types to be changed, got %s and %s", reqs[0].Method, reqs[1].Method)
+	}
+
+	reqs, idsHolder, err := ch.AddGetterMethodsIfNeeded(reqs, customMethodsMap)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	if len(reqs) != 5 {
+		t.Fatalf("Expected a getter req for the latest tag of each chain type, got %d reqs", len(reqs))
+	}
+	evmID, ok := idsHolder["evm:latest"]
+	if !ok {
+		t.Fatalf("Expected the evm index to be prefixed, got %v", idsHolder)
+	}
+	starknetID, ok := idsHolder["starknet:latest"]
+	if !ok {
+		t.Fatalf("Expected the starknet index to be prefixed, got %v", idsHolder)
+	}
+
+	ress := []*models.RPCResJSON{
+		{JSONRPC: "2.0", ID: json.RawMessage("1"), Result: json.RawMessage(`"0x64"`)},
+		{JSONRPC: "2.0", ID: json.RawMessage("2"), Result: json.RawMessage(`"0x5"`)},
+		{JSONRPC: "2.0", ID: json.RawMessage("3"), Result: json.RawMessage(`"0x1"`)},
+		{JSONRPC: "2.0", ID: json.RawMessage(starknetID), Result: json.RawMessage(`{"block_hash":"0x9","block_number":20}`)},
+		{JSONRPC: "2.0", ID: json.RawMessage(evmID), Result: json.RawMessage(`{"number":"0x10"}`)},
+	}
+	ress, err = ch.ChangeCustomMethodsResponses(ress, changedMethods, idsHolder, customMethodsMap)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+
+	expected := []json.RawMessage{
+		blockNumberResult{Data: json.RawMessage(`"0x64"`), BlockNumber: "0x10"}.raw(),
+		starknetBlockResult{Data: json.RawMessage(`"0x5"`), BlockNumber: 20}.raw(),
+		json.RawMessage(`"0x1"`),
+	}
+	if len(ress) != len(expected) {
+		t.Fatalf("Expected %d responses without the getters, got %d", len(expected), len(ress))
+	}
+	for i, res := range ress {
+		if string(res.ID) != string(reqs[i].ID) {
+			t.Errorf("Expected response %d to have id %s, got %s", i, reqs[i].ID, res.ID)
+		}
+		if string(res.Result) != string(expected[i]) {
+			t.Errorf("Expected response %d to have result %s, got %s", i, expected[i], res.Result)
+		}
+	}
+}
+
+func TestAmbiguousMethods(t *testing.T) {
+	methods := []Method{{
+		CustomMethod:         "eth_callAndBlockNumber",
+		OriginalMethod:       "eth_call",
+		PositionsGetterParam: []int{1},
+	}}
+	evm := MethodsConfig{ChainType: ChainTypeEVM, Methods: methods}
+	tron := MethodsConfig{ChainType: ChainTypeTron, Methods: methods}
+	custom := &models.RPCReq{Method: "eth_callAndBlockNumber", ID: json.RawMessage("1"), Params: json.RawMessage(`[{},"latest"]`)}
+
+	ch := NewCustomMethodHolderFromConfigs(false, []MethodsConfig{evm, tron})
+	validReqs, errRess, err := ch.FilterInvalidRPCReqs([]*models.RPCReq{custom})
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	if len(validReqs) != 0 || len(errRess) != 1 {
+		t.Fatalf("Expected the ambiguous req to be invalid, got %d valid and %d errors", len(validReqs), len(errRess))
+	}
+	if errRess[0].Error.Code != ErrAmbiguousMethod.Code || !strings.Contains(errRess[0].Error.Message, "evm, tron") {
+		t.Errorf("Expected the ambiguous method error with its chain types, got %v", errRess[0].Error)
+	}
+
+	// original methods are forwarded as they are out of gateway mode
+	original := &models.RPCReq{Method: "eth_call", ID: json.RawMessage("2"), Params: json.RawMessage(`[{},"latest"]`)}
+	validReqs, errRess, _ = ch.FilterInvalidRPCReqs([]*models.RPCReq{original})
+	if len(validReqs) != 1 || len(errRess) != 0 {
+		t.Errorf("Expected the original method to be valid out of gateway mode, got %d valid and %d errors", len(validReqs), len(errRess))
+	}
+	if ch.IsImmutableReq(original, 100) {
+		t.Errorf("Expected the ambiguous original method to not be cacheable")
+	}
+
+	ch = NewCustomMethodHolderFromConfigs(true, []MethodsConfig{evm, tron})
+	reqs, _ := ch.HandleGatewayMode([]*models.RPCReq{original})
+	validReqs, errRess, _ = ch.FilterInvalidRPCReqs(reqs)
+	if len(validReqs) != 0 || len(errRess) != 1 || reqs[0].Method != "eth_call" {
+		t.Errorf("Expected the original method to be ambiguous on gateway mode, got %d valid and %d errors", len(validReqs), len(errRess))
+	}
+
+	tron.Precedence = 1
+	for _, configs := range [][]MethodsConfig{{evm, tron}, {tron, evm}} {
+		ch = NewCustomMethodHolderFromConfigs(false, configs)
+		if ch.CustomMethodToChainType["eth_callAndBlockNumber"] != ChainTypeTron {
+			t.Errorf("Expected the method of the config with the highest precedence, got %s", ch.CustomMethodToChainType["eth_callAndBlockNumber"])
+		}
+		validReqs, errRess, _ = ch.FilterInvalidRPCReqs([]*models.RPCReq{custom})
+		if len(validReqs) != 1 || len(errRess) != 0 {
+			t.Errorf("Expected the method with precedence to be valid, got %d valid and %d errors", len(validReqs), len(errRess))
+		}
+	}
+}
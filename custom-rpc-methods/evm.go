@@ -3,20 +3,39 @@ package customrpcmethods
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	gethRPC "github.com/ethereum/go-ethereum/rpc"
 	"github.com/stateless-solutions/compatibility-layer/models"
 )
 
 type blockNumberResult struct {
-	Data        interface{} `json:"data"`
-	BlockNumber string      `json:"blockNumber"`
+	Data        json.RawMessage `json:"data"`
+	BlockNumber string          `json:"blockNumber"`
 }
 
 type blockRangeResult struct {
-	Data          interface{} `json:"data"`
-	StartingBlock string      `json:"startingBlock"`
-	EndingBlock   string      `json:"endingBlock"`
+	Data          json.RawMessage `json:"data"`
+	StartingBlock string          `json:"startingBlock"`
+	EndingBlock   string          `json:"endingBlock"`
+}
+
+func (r blockNumberResult) envelope() ([]byte, []byte) {
+	return rawEnvelope("data", []string{"blockNumber"}, rawString(r.BlockNumber))
+}
+
+func (r blockNumberResult) resolvedBlock() string {
+	return r.BlockNumber
+}
+
+func (r blockRangeResult) envelope() ([]byte, []byte) {
+	return rawEnvelope("data", []string{"startingBlock", "endingBlock"}, rawString(r.StartingBlock), rawString(r.EndingBlock))
+}
+
+// the ending block is the one the whole range is consistent with
+func (r blockRangeResult) resolvedBlock() string {
+	return r.EndingBlock
 }
 
 var (
@@ -32,6 +51,12 @@ var (
 		HTTPErrorCode: 500,
 	}
 
+	ErrInternalBlockNumberNotHex = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 26,
+		Message:       "block number response is not a hex number",
+		HTTPErrorCode: 500,
+	}
+
 	evmMethodNameToCustomHandlder = make(map[string]func(*models.RPCReq) ([]*gethRPC.BlockNumberOrHash, error))
 )
 
@@ -64,6 +89,50 @@ func (e EVMImpl) SupportsRange() bool {
 	return true
 }
 
+func (e EVMImpl) GetHealthCheckReq() *models.RPCReq {
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "eth_blockNumber",
+		ID:      json.RawMessage("1"),
+	}
+}
+
+func (e EVMImpl) ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error) {
+	block, ok := stringOf(res.Result)
+	if !ok {
+		return 0, ErrInternalBlockNumberNotHex
+	}
+
+	height, err := hexutil.DecodeUint64(block)
+	if err != nil {
+		return 0, ErrInternalBlockNumberNotHex
+	}
+
+	return height, nil
+}
+
+func (e EVMImpl) GetBlockTime() time.Duration {
+	return 12 * time.Second
+}
+
+func (e EVMImpl) GetFinalizedHeightReq() *models.RPCReq {
+	return buildGetBlockByNumberReq("finalized", "1")
+}
+
+func (e EVMImpl) ExtractFinalizedHeight(res *models.RPCResJSON) (uint64, error) {
+	block, err := e.ExtractGetterReturnFromResponse(res)
+	if err != nil {
+		return 0, err
+	}
+
+	height, err := hexutil.DecodeUint64(block)
+	if err != nil {
+		return 0, ErrInternalBlockNumberNotHex
+	}
+
+	return height, nil
+}
+
 func (e EVMImpl) GetDefaultGetter() *gethRPC.BlockNumberOrHash {
 	bnl, _ := remarshalBlockNumberOrHash("latest")
 	return bnl
@@ -177,12 +246,13 @@ func (e EVMImpl) GetIndexOfIDHolder(gt *gethRPC.BlockNumberOrHash) (string, erro
 }
 
 func (e EVMImpl) ExtractGetterReturnFromResponse(res *models.RPCResJSON) (string, error) {
-	resMap, ok := res.Result.(map[string]interface{})
-	if !ok {
+	var resMap map[string]json.RawMessage
+	err := json.Unmarshal(res.Result, &resMap)
+	if err != nil || resMap == nil {
 		return "", ErrInternalBlockNumberMethodNotMap
 	}
 
-	block, ok := resMap["number"].(string)
+	block, ok := stringOf(resMap["number"])
 	if !ok {
 		return "", ErrInternalBlockNumberMethodNotNumberEntry
 	}
@@ -211,7 +281,7 @@ func (e EVMImpl) ExtractGetterReturnFromType(gt *gethRPC.BlockNumberOrHash) (str
 	return gt.BlockNumber.String(), nil
 }
 
-func (e EVMImpl) ExtractGetterStruct(res *models.RPCResJSON, gr string) (blockNumberResult, error) {
+func (e EVMImpl) ExtractGetterStruct(res *models.RPCResJSON, gr string, contextRes *models.RPCResJSON) (blockNumberResult, error) {
 	return blockNumberResult{
 		Data:        res.Result,
 		BlockNumber: gr,
@@ -226,6 +296,53 @@ func (e EVMImpl) ExtractGetterRangeStruct(res *models.RPCResJSON, grTo, grFrom s
 	}, nil
 }
 
+func (e EVMImpl) PinGetter(param interface{}, gt *gethRPC.BlockNumberOrHash, gr string) (interface{}, error) {
+	if gt.BlockHash != nil {
+		return param, nil // block hashes already point to a single block
+	}
+	if *gt.BlockNumber == gethRPC.PendingBlockNumber {
+		return param, nil // pending block can't be requested by number
+	}
+
+	return gr, nil
+}
+
+// new heads notifications have the block number on the number entry
+// and logs notifications have it on the block number entry
+func (e EVMImpl) ExtractGetterReturnFromNotification(notification *models.RPCNotification) (string, bool, error) {
+	var resMap map[string]json.RawMessage
+	err := json.Unmarshal(notification.Params.Result, &resMap)
+	if err != nil {
+		return "", false, nil // pending transactions notifications are not tied to a block
+	}
+
+	for _, key := range []string{"number", "blockNumber"} {
+		block, ok := stringOf(resMap[key])
+		if ok {
+			return block, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
 func NewEVMMethodBuilder() CustomRpcMethodBuilder {
 	return NewGenericConv(EVMImpl{})
 }
+
+func (e EVMImpl) IsImmutableGetter(gt *gethRPC.BlockNumberOrHash, finalizedHeight uint64) bool {
+	if gt.BlockHash != nil {
+		return true
+	}
+
+	// tags are negative except earliest that is the genesis block
+	return *gt.BlockNumber >= 0 && uint64(*gt.BlockNumber) <= finalizedHeight
+}
+
+func (e EVMImpl) BuildContextReq(id string) *models.RPCReq {
+	return nil // the block number is the only data added to the results
+}
+
+func (e EVMImpl) GetIndexOfContextReq() string {
+	return ""
+}
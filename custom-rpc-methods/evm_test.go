@@ -23,15 +23,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "aaa",
+					Data:        json.RawMessage(`"aaa"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"latest"},
 			idsToRewrite:      []string{"22"},
@@ -50,14 +50,14 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 1,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "1",
+				Result: json.RawMessage(`"1"`),
 			}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "1",
+					Data:        json.RawMessage(`"1"`),
 					BlockNumber: "0x23",
-				},
+				}.raw(),
 			},
 		},
 		{
@@ -74,14 +74,14 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa"}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`"aaa"`)}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "aaa",
+					Data:        json.RawMessage(`"aaa"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"0x3f07a9c83155594c000642e7d60e8a8a00038d03e9849171a05ed0e2d47acbb3"},
 			idsToRewrite:      []string{"22"},
@@ -100,15 +100,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "1",
+				Result: json.RawMessage(`"1"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "1",
+					Data:        json.RawMessage(`"1"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"pending"},
 			idsToRewrite:      []string{"22"},
@@ -127,15 +127,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "1",
+				Result: json.RawMessage(`"1"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "1",
+					Data:        json.RawMessage(`"1"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"earliest"},
 			idsToRewrite:      []string{"22"},
@@ -154,15 +154,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "1",
+				Result: json.RawMessage(`"1"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "1",
+					Data:        json.RawMessage(`"1"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"finalized"},
 			idsToRewrite:      []string{"22"},
@@ -181,15 +181,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "1",
+				Result: json.RawMessage(`"1"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "1",
+					Data:        json.RawMessage(`"1"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"safe"},
 			idsToRewrite:      []string{"22"},
@@ -208,11 +208,11 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 1,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "1",
+				Result: json.RawMessage(`"1"`),
 			}},
 			expectedRes: &models.RPCResJSON{
 				ID:     json.RawMessage("21"),
-				Result: "1",
+				Result: json.RawMessage(`"1"`),
 			},
 		},
 		{
@@ -229,15 +229,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: "a"}},
+				Result: json.RawMessage(`"a"`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "aaa",
+					Data:        json.RawMessage(`"aaa"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"latest"},
 			idsToRewrite:      []string{"22"},
@@ -257,15 +257,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"block": "0x21"}}},
+				Result: json.RawMessage(`{"block":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "aaa",
+					Data:        json.RawMessage(`"aaa"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"latest"},
 			idsToRewrite:      []string{"22"},
@@ -285,15 +285,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "aaa",
+					Data:        json.RawMessage(`"aaa"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"latest"},
 			idsToRewrite:      []string{"22"},
@@ -312,15 +312,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "aaa",
+					Data:        json.RawMessage(`"aaa"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"latest"},
 			idsToRewrite:      []string{"22"},
@@ -339,15 +339,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "aaa",
+					Data:        json.RawMessage(`"aaa"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"latest"},
 			idsToRewrite:      []string{"22"},
@@ -366,18 +366,18 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}},
+				Result: json.RawMessage(`{"number":"0x21"}`)},
 				{ID: json.RawMessage("23"),
-					Result: map[string]interface{}{"number": "0x22"}}},
+					Result: json.RawMessage(`{"number":"0x22"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockRangeResult{
-					Data:          "aaa",
+					Data:          json.RawMessage(`"aaa"`),
 					StartingBlock: "0x21",
 					EndingBlock:   "0x22",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"earliest", "latest"},
 			idsToRewrite:      []string{"22", "23"},
@@ -396,15 +396,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 1,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockRangeResult{
-					Data:          "aaa",
+					Data:          json.RawMessage(`"aaa"`),
 					StartingBlock: "0x21",
 					EndingBlock:   "0x22",
-				},
+				}.raw(),
 			},
 		},
 		{
@@ -421,16 +421,16 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockRangeResult{
-					Data:          "aaa",
+					Data:          json.RawMessage(`"aaa"`),
 					StartingBlock: "0x21",
 					EndingBlock:   "0x21",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"0x3f07a9c83155594c000642e7d60e8a8a00038d03e9849171a05ed0e2d47acbb3"},
 			idsToRewrite:      []string{"22"},
@@ -449,18 +449,18 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}},
+				Result: json.RawMessage(`{"number":"0x21"}`)},
 				{ID: json.RawMessage("23"),
-					Result: map[string]interface{}{"number": "0x22"}}},
+					Result: json.RawMessage(`{"number":"0x22"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockRangeResult{
-					Data:          "aaa",
+					Data:          json.RawMessage(`"aaa"`),
 					StartingBlock: "0x21",
 					EndingBlock:   "0x22",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"safe", "pending"},
 			idsToRewrite:      []string{"22", "23"},
@@ -484,7 +484,7 @@ func TestEVM(t *testing.T) {
 					Message: "call not good",
 				},
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Error: &models.RPCErr{
@@ -509,7 +509,7 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
 				Error: &models.RPCErr{
 					Code:    21,
@@ -539,7 +539,7 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa"}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`"aaa"`)}, {ID: json.RawMessage("22"),
 				Error: &models.RPCErr{
 					Code:    21,
 					Message: "block number not good",
@@ -568,9 +568,9 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}},
+				Result: json.RawMessage(`{"number":"0x21"}`)},
 				{ID: json.RawMessage("23"),
 					Error: &models.RPCErr{
 						Code:    21,
@@ -600,18 +600,18 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}},
+				Result: json.RawMessage(`{"number":"0x21"}`)},
 				{ID: json.RawMessage("23"),
-					Result: map[string]interface{}{"number": "0x22"}}},
+					Result: json.RawMessage(`{"number":"0x22"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockRangeResult{
-					Data:          "aaa",
+					Data:          json.RawMessage(`"aaa"`),
 					StartingBlock: "0x21",
 					EndingBlock:   "0x22",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"earliest", "latest"},
 			idsToRewrite:      []string{"22", "23"},
@@ -630,18 +630,18 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}},
+				Result: json.RawMessage(`{"number":"0x21"}`)},
 				{ID: json.RawMessage("23"),
-					Result: map[string]interface{}{"number": "0x22"}}},
+					Result: json.RawMessage(`{"number":"0x22"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockRangeResult{
-					Data:          "aaa",
+					Data:          json.RawMessage(`"aaa"`),
 					StartingBlock: "0x21",
 					EndingBlock:   "0x22",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"safe", "latest"},
 			idsToRewrite:      []string{"22", "23"},
@@ -660,18 +660,18 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}},
+				Result: json.RawMessage(`{"number":"0x21"}`)},
 				{ID: json.RawMessage("23"),
-					Result: map[string]interface{}{"number": "0x22"}}},
+					Result: json.RawMessage(`{"number":"0x22"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockRangeResult{
-					Data:          "aaa",
+					Data:          json.RawMessage(`"aaa"`),
 					StartingBlock: "0x21",
 					EndingBlock:   "0x22",
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"earliest", "pending"},
 			idsToRewrite:      []string{"22", "23"},
@@ -690,15 +690,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "aaa",
+					Data:        json.RawMessage(`"aaa"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			gatewayMode:       true,
 			contentsToRewrite: []string{"latest"},
@@ -718,15 +718,15 @@ func TestEVM(t *testing.T) {
 			expectedReqLength: 2,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: map[string]interface{}{"number": "0x21"}}},
+				Result: json.RawMessage(`{"number":"0x21"}`)}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: blockNumberResult{
-					Data:        "aaa",
+					Data:        json.RawMessage(`"aaa"`),
 					BlockNumber: "0x21",
-				},
+				}.raw(),
 			},
 			gatewayMode:       true,
 			contentsToRewrite: []string{"latest"},
@@ -736,3 +736,315 @@ func TestEVM(t *testing.T) {
 
 	runTests(t, "../supported-chains/ethereum.json", tests)
 }
+
+func TestEVMPinGetterParams(t *testing.T) {
+	tests := []testCasePinGetterParams{
+		{
+			name: "Balance latest tag",
+			req: []*models.RPCReq{{
+				Method: "eth_getBalanceAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"number":"0x21"}`)},
+			},
+			expectedParams: []string{`["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","0x21"]`},
+		},
+		{
+			name: "Call without block param",
+			req: []*models.RPCReq{{
+				Method: "eth_callAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"to":"0x6b175474e89094c44da98b954eedeac495271d0f"}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"number":"0x21"}`)},
+			},
+			expectedParams: []string{`[{"to":"0x6b175474e89094c44da98b954eedeac495271d0f"},"0x21"]`},
+		},
+		{
+			name: "Call with big integer",
+			req: []*models.RPCReq{{
+				Method: "eth_callAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"to":"0x6b175474e89094c44da98b954eedeac495271d0f","gas":12345678901234567890},"latest"]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"number":"0x21"}`)},
+			},
+			expectedParams: []string{`[{"to":"0x6b175474e89094c44da98b954eedeac495271d0f","gas":12345678901234567890},"0x21"]`},
+		},
+		{
+			name: "Storage at block hash",
+			req: []*models.RPCReq{{
+				Method: "eth_getStorageAtAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["","",{"blockHash":"0x3f07a9c83155594c000642e7d60e8a8a00038d03e9849171a05ed0e2d47acbb3"}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"0x3f07a9c83155594c000642e7d60e8a8a00038d03e9849171a05ed0e2d47acbb3": {Result: json.RawMessage(`{"number":"0x21"}`)},
+			},
+			expectedParams: []string{`["","",{"blockHash":"0x3f07a9c83155594c000642e7d60e8a8a00038d03e9849171a05ed0e2d47acbb3"}]`},
+		},
+		{
+			name: "Transaction count pending tag",
+			req: []*models.RPCReq{{
+				Method: "eth_getTransactionCountAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["","pending"]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"pending": {Result: json.RawMessage(`{"number":"0x21"}`)},
+			},
+			expectedParams: []string{`["","pending"]`},
+		},
+		{
+			name: "Logs latest to block",
+			req: []*models.RPCReq{{
+				Method: "eth_getLogsAndBlockRange",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"fromBlock":"0x1","toBlock":"latest"}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"number":"0x21"}`)},
+			},
+			expectedParams: []string{`[{"fromBlock":"0x1","toBlock":"0x21"}]`},
+		},
+		{
+			name: "Logs without range",
+			req: []*models.RPCReq{{
+				Method: "eth_getLogsAndBlockRange",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"earliest": {Result: json.RawMessage(`{"number":"0x0"}`)},
+				"latest":   {Result: json.RawMessage(`{"number":"0x21"}`)},
+			},
+			expectedParams: []string{`[{"fromBlock":"0x0","toBlock":"0x21"}]`},
+		},
+		{
+			name: "Error on getter request",
+			req: []*models.RPCReq{{
+				Method: "eth_getBalanceAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["","safe"]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"safe": {Error: &models.RPCErr{Code: -32000, Message: "safe block not found"}},
+			},
+			expectedParams: []string{`["","safe"]`},
+		},
+		{
+			name: "Batch with different tags",
+			req: []*models.RPCReq{{
+				Method: "eth_getBalanceAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["","finalized"]`),
+			}, {
+				Method: "eth_getCodeAndBlockNumber",
+				ID:     json.RawMessage("22"),
+				Params: json.RawMessage(`["","latest"]`),
+			}, {
+				Method: "eth_getCode",
+				ID:     json.RawMessage("23"),
+				Params: json.RawMessage(`["","latest"]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"finalized": {Result: json.RawMessage(`{"number":"0x1f"}`)},
+				"latest":    {Result: json.RawMessage(`{"number":"0x21"}`)},
+			},
+			expectedParams: []string{`["","0x1f"]`, `["","0x21"]`, `["","latest"]`},
+		},
+	}
+
+	runPinTests(t, "../supported-chains/ethereum.json", tests)
+}
+
+func TestEVMFilterInvalidRPCReqs(t *testing.T) {
+	reqs := []*models.RPCReq{
+		{
+			Method: "eth_getBalanceAndBlockNumber",
+			ID:     json.RawMessage("1"),
+			Params: json.RawMessage(`["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]`),
+		},
+		{
+			Method: "eth_getBalanceAndBlockNumber",
+			ID:     json.RawMessage("2"),
+			Params: json.RawMessage(`["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latestt"]`),
+		},
+		{
+			Method: "eth_getBalance",
+			ID:     json.RawMessage("3"),
+			Params: json.RawMessage(`["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latestt"]`),
+		},
+		{
+			Method: "eth_callAndBlockNumber",
+			ID:     json.RawMessage("4"),
+			Params: json.RawMessage(`{"to":"0x6b175474e89094c44da98b954eedeac495271d0f"}`),
+		},
+	}
+
+	// regular methods are not validated, the chain answers them
+	runFilterInvalidTests(t, "../supported-chains/ethereum.json", reqs, []string{"1", "3"}, []string{"2", "4"})
+}
+
+func TestEVMIsImmutableReq(t *testing.T) {
+	tests := []testCaseIsImmutableReq{
+		{
+			name: "Block Hash",
+			req: &models.RPCReq{
+				Method: "eth_getBalance",
+				Params: json.RawMessage(`["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","0x2f1c5b3b1e2a3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5"]`),
+			},
+			expected: true,
+		},
+		{
+			name: "Block Number Below Finalized",
+			req: &models.RPCReq{
+				Method: "eth_getBalanceAndBlockNumber",
+				Params: json.RawMessage(`["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","0x64"]`),
+			},
+			expected: true,
+		},
+		{
+			name: "Block Number Above Finalized",
+			req: &models.RPCReq{
+				Method: "eth_getBalance",
+				Params: json.RawMessage(`["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","0x3e9"]`),
+			},
+			expected: false,
+		},
+		{
+			name: "Tag",
+			req: &models.RPCReq{
+				Method: "eth_getBalance",
+				Params: json.RawMessage(`["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","finalized"]`),
+			},
+			expected: false,
+		},
+		{
+			name: "Logs Range Below Finalized",
+			req: &models.RPCReq{
+				Method: "eth_getLogs",
+				Params: json.RawMessage(`[{"fromBlock":"0x1","toBlock":"0x2"}]`),
+			},
+			expected: true,
+		},
+		{
+			name: "Logs Range To Latest",
+			req: &models.RPCReq{
+				Method: "eth_getLogs",
+				Params: json.RawMessage(`[{"fromBlock":"0x1"}]`),
+			},
+			expected: false,
+		},
+		{
+			name: "Method Not Cacheable",
+			req: &models.RPCReq{
+				Method: "eth_subscribe",
+				Params: json.RawMessage(`["newHeads"]`),
+			},
+			expected: false,
+		},
+		{
+			name: "Method Not On Config",
+			req: &models.RPCReq{
+				Method: "eth_getBlockByHash",
+				Params: json.RawMessage(`["0x2f1c5b3b1e2a3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5",false]`),
+			},
+			expected: false,
+		},
+	}
+
+	runIsImmutableTests(t, "../supported-chains/ethereum.json", 1000, tests)
+}
+
+var evmLogsGetterPathsConfig = MethodsConfig{
+	ChainType: ChainTypeEVM,
+	Methods: []Method{{
+		CustomMethod:   "eth_getLogsAndBlockRange",
+		OriginalMethod: "eth_getLogs",
+		GetterPaths: []GetterPaths{
+			{Paths: []string{"$[0].blockHash"}},
+			{Paths: []string{"$[0].fromBlock", "$[0].toBlock"}, Defaults: []interface{}{"earliest", "latest"}},
+		},
+		IsRange: true,
+	}},
+}
+
+func TestEVMGetterPaths(t *testing.T) {
+	g := NewGenericConv(EVMImpl{})
+	g.PopulateConfig(false, []MethodsConfig{evmLogsGetterPathsConfig})
+
+	for _, params := range []string{
+		`[{}]`,
+		`[{"fromBlock":"0x1"}]`,
+		`[{"toBlock":"finalized"}]`,
+		`[{"fromBlock":"0x1","toBlock":"0x2","address":"0x6b175474e89094c44da98b954eedeac495271d0f"}]`,
+		`[{"fromBlock":"0x1","toBlock":null}]`,
+		`[{"blockHash":"0x3f07a9c83155594c000642e7d60e8a8a00038d03e9849171a05ed0e2d47acbb3"}]`,
+		`[{"blockHash":"0x3f07a9c83155594c000642e7d60e8a8a00038d03e9849171a05ed0e2d47acbb3","fromBlock":"0x1"}]`,
+	} {
+		req := &models.RPCReq{Method: "eth_getLogsAndBlockRange", ID: json.RawMessage("21"), Params: json.RawMessage(params)}
+		expected, err := evmCustomHandlersHolder{}.HandleGetLogsAndBlockRange(req)
+		if err != nil {
+			t.Fatalf("Params %s: Error not expected, got %v", params, err)
+		}
+
+		gts, err := g.getGetters(req)
+		if err != nil {
+			t.Fatalf("Params %s: Error not expected, got %v", params, err)
+		}
+		if len(gts) != len(expected) {
+			t.Fatalf("Params %s: Expected %d getters, got %d", params, len(expected), len(gts))
+		}
+		for i, gt := range gts {
+			if gt.String() != expected[i].String() {
+				t.Errorf("Params %s: Expected getter %d to be %s, got %s", params, i, expected[i], gt)
+			}
+		}
+	}
+}
+
+func TestEVMGetterPathsPinGetterParams(t *testing.T) {
+	tests := []testCasePinGetterParams{
+		{
+			name: "Logs with default to block",
+			req: []*models.RPCReq{{
+				Method: "eth_getLogsAndBlockRange",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"fromBlock":"0x1"}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"number":"0x21"}`)},
+			},
+			expectedParams: []string{`[{"fromBlock":"0x1","toBlock":"0x21"}]`},
+		},
+		{
+			name: "Logs at block hash",
+			req: []*models.RPCReq{{
+				Method: "eth_getLogsAndBlockRange",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"blockHash":"0x3f07a9c83155594c000642e7d60e8a8a00038d03e9849171a05ed0e2d47acbb3"}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"0x3f07a9c83155594c000642e7d60e8a8a00038d03e9849171a05ed0e2d47acbb3": {Result: json.RawMessage(`{"number":"0x21"}`)},
+			},
+			expectedParams: []string{`[{"blockHash":"0x3f07a9c83155594c000642e7d60e8a8a00038d03e9849171a05ed0e2d47acbb3"}]`},
+		},
+	}
+
+	runPinTestsFromConfigs(t, []MethodsConfig{evmLogsGetterPathsConfig}, tests)
+}
+
+// raw returns the JSON of the result as the buffered responses wrap its data
+func (r blockNumberResult) raw() json.RawMessage {
+	return splice(r, r.Data)
+}
+
+// raw returns the JSON of the result as the buffered responses wrap its data
+func (r blockRangeResult) raw() json.RawMessage {
+	return splice(r, r.Data)
+}
@@ -1,37 +1,48 @@
 package customrpcmethods
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	gethRPC "github.com/ethereum/go-ethereum/rpc"
-	solanaRPC "github.com/gagliardetto/solana-go/rpc"
 	"github.com/stateless-solutions/compatibility-layer/models"
 )
 
 // GetterTypes is a generic for the type of the data that needs to be gotten from a chain type
 // this one is most likely related to blocks and can be input as tags
 type GetterTypes interface {
-	*gethRPC.BlockNumberOrHash | solanaRPC.CommitmentType
+	*gethRPC.BlockNumberOrHash | SolanaGetter | CosmosHeight | BitcoinBlock | StarknetBlockID | NearBlockReference | AptosLedgerVersion | SuiCheckpoint | TronBlock
 }
 
 // GetterReturns is a generic of all the possible types of the data gotten for each chain type
 type GetterReturns interface {
-	string | int
+	string | int | blockRef
 }
 
 // GetterStructs is a generic of all custom structs to return in the non range custom methods
 type GetterStructs interface {
-	blockNumberResult | contextResult
+	blockNumberResult | contextResult | heightResult | blockHeightResult | starknetBlockResult | ledgerVersionResult | checkpointResult
+	customResult
 }
 
 // GetterRangeStructs is a generic of all custom structs to return in the range custom methods
 type GetterRangeStructs interface {
-	blockRangeResult | noRangeSupported
+	blockRangeResult | starknetBlockRangeResult | noRangeSupported
+	customResult
 }
 
 type noRangeSupported struct{} // placeholder struct for chains that don't support ranges
 
+func (r noRangeSupported) envelope() ([]byte, []byte) {
+	return nil, nil
+}
+
+func (r noRangeSupported) resolvedBlock() string {
+	return ""
+}
+
 // GenericConvImpl is an interface for each chain type to be used in the generic converter
 type GenericConvImpl[T GetterTypes, R GetterReturns, S GetterStructs, SR GetterRangeStructs] interface {
 	GetChainType() ChainType
@@ -46,8 +57,31 @@ type GenericConvImpl[T GetterTypes, R GetterReturns, S GetterStructs, SR GetterR
 	GetIndexOfIDHolder(gt T) (string, error)
 	ExtractGetterReturnFromResponse(res *models.RPCResJSON) (R, error)
 	ExtractGetterReturnFromType(gt T) (R, error)
-	ExtractGetterStruct(res *models.RPCResJSON, gr R) (S, error)
+	// ExtractGetterStruct returns the custom struct of the response, the context response is nil if it is unknown
+	ExtractGetterStruct(res *models.RPCResJSON, gr R, contextRes *models.RPCResJSON) (S, error)
 	ExtractGetterRangeStruct(res *models.RPCResJSON, grTo, grFrom R) (SR, error)
+	PinGetter(param interface{}, gt T, gr R) (interface{}, error)
+	ExtractGetterReturnFromNotification(notification *models.RPCNotification) (R, bool, error)
+	IsImmutableGetter(gt T, finalizedHeight uint64) bool
+	// BuildContextReq returns the req of the node data added to the custom structs besides the getter return, nil if the chain type has none
+	BuildContextReq(id string) *models.RPCReq
+	// GetIndexOfContextReq returns the index of the id holder of the context req, empty if the chain type has none
+	GetIndexOfContextReq() string
+}
+
+// getterPositioner is implemented by the chain types with custom handlers that find the getter params
+// on positions that depend on the params, the positions are used to pin the params
+type getterPositioner interface {
+	// GetterPositions returns the positions of the getter params found by the custom handler, none if the getters are not on the params,
+	// false if they are the configured ones
+	GetterPositions(customHandler string, req *models.RPCReq) ([]int, bool)
+}
+
+// replacedKeysPinner is implemented by the chain types whose getters can be sent on other keys than the pinned ones,
+// the keys are removed from named params when their getter is pinned
+type replacedKeysPinner interface {
+	// ReplacedKeys returns the keys that can't be sent with the pinned getter
+	ReplacedKeys() []string
 }
 
 // GenericConv is the generic struct for the converter of all chain types
@@ -57,8 +91,13 @@ type GenericConv[T GetterTypes, R GetterReturns, S GetterStructs, SR GetterRange
 	regularToCustom             map[string]string
 	customToRegular             map[string]string
 	customMethodToPos           map[string][]int
+	customMethodToKeys          map[string][]string
+	customMethodToPaths         map[string][]getterPaths
 	customMethodToIsRange       map[string]bool
+	customMethodIsCacheable     map[string]bool
 	customMethodToCustomHandler map[string]func(*models.RPCReq) ([]T, error)
+	customMethodToHandlerName   map[string]string
+	customMethodToWrapPlugin    map[string]*wasmPlugin
 }
 
 func NewGenericConv[T GetterTypes, R GetterReturns, S GetterStructs, SR GetterRangeStructs](impl GenericConvImpl[T, R, S, SR]) *GenericConv[T, R, S, SR] {
@@ -74,8 +113,13 @@ func NewGenericConv[T GetterTypes, R GetterReturns, S GetterStructs, SR GetterRa
 		regularToCustom:             map[string]string{},
 		customToRegular:             map[string]string{},
 		customMethodToPos:           map[string][]int{},
+		customMethodToKeys:          map[string][]string{},
+		customMethodToPaths:         map[string][]getterPaths{},
 		customMethodToIsRange:       map[string]bool{},
+		customMethodIsCacheable:     map[string]bool{},
 		customMethodToCustomHandler: map[string]func(*models.RPCReq) ([]T, error){},
+		customMethodToHandlerName:   map[string]string{},
+		customMethodToWrapPlugin:    map[string]*wasmPlugin{},
 	}
 }
 
@@ -89,10 +133,16 @@ func (g *GenericConv[T, R, S, SR]) PopulateConfig(gatewayMode bool, configs []Me
 				panic(fmt.Sprintf("positions getter param length for method %s is %d and the max allowed is 2", method.CustomMethod, len(method.PositionsGetterParam)))
 			}
 			g.customMethodToPos[method.CustomMethod] = method.PositionsGetterParam
+			if len(method.KeysGetterParam) > 2 {
+				panic(fmt.Sprintf("keys getter param length for method %s is %d and the max allowed is 2", method.CustomMethod, len(method.KeysGetterParam)))
+			}
+			g.customMethodToKeys[method.CustomMethod] = method.KeysGetterParam
+			g.customMethodToPaths[method.CustomMethod] = parseGetterPaths(method)
 			if method.IsRange && !g.impl.SupportsRange() {
 				panic(fmt.Sprintf("is range is true for method %s of chain type %s that doesn't support it", method.CustomMethod, g.impl.GetChainType()))
 			}
 			g.customMethodToIsRange[method.CustomMethod] = method.IsRange
+			g.customMethodIsCacheable[method.CustomMethod] = method.Cacheable
 			if method.CustomHandler != "" {
 				if g.impl.GetCustomHandlerMap() == nil {
 					panic(fmt.Sprintf("method type %s has a custom handler and chain type %s doesn't support it", method.CustomMethod, g.impl.GetChainType()))
@@ -102,6 +152,20 @@ func (g *GenericConv[T, R, S, SR]) PopulateConfig(gatewayMode bool, configs []Me
 					panic(fmt.Sprintf("custom handler %s for method %s is not implemented", method.CustomHandler, method.CustomMethod))
 				}
 				g.customMethodToCustomHandler[method.CustomMethod] = handlerFunc
+				g.customMethodToHandlerName[method.CustomMethod] = method.CustomHandler
+			}
+			if method.Plugin != nil {
+				if method.CustomHandler != "" {
+					panic(fmt.Sprintf("method %s has a custom handler and a plugin", method.CustomMethod))
+				}
+				plugin, err := loadPlugin(*method.Plugin)
+				if err != nil {
+					panic(fmt.Sprintf("plugin %s for method %s can't be loaded: %v", method.Plugin.Path, method.CustomMethod, err))
+				}
+				g.customMethodToCustomHandler[method.CustomMethod] = g.pluginHandler(plugin)
+				if plugin.wraps {
+					g.customMethodToWrapPlugin[method.CustomMethod] = plugin
+				}
 			}
 		}
 	}
@@ -142,22 +206,33 @@ func (g *GenericConv[T, R, S, SR]) getGetters(req *models.RPCReq) ([]T, error) {
 			return customHandler(req)
 		}
 
-		var p []interface{}
-		err := json.Unmarshal(req.Params, &p)
+		var values []interface{}
+		var present []bool
+		var err error
+		if len(g.customMethodToPaths[req.Method]) > 0 {
+			values, present, err = g.pathGetterParams(req)
+		} else {
+			// in case no param position or key default getters are used
+			// the params are not parsed since they can be of any form
+			if len(g.customMethodToPos[req.Method]) == 0 && len(g.customMethodToKeys[req.Method]) == 0 {
+				return g.returnDefaultGetters(req), nil
+			}
+
+			values, present, err = g.getterParams(req)
+		}
 		if err != nil {
 			return nil, err
 		}
 
-		// in case no param position or params are empty
-		// default getters are used
-		if len(g.customMethodToPos[req.Method]) == 0 || len(p) == 0 {
+		// in case params are empty default getters are used
+		if values == nil {
 			return g.returnDefaultGetters(req), nil
 		}
 
 		var gts []T
 		var defaultFromUsed bool
-		for i, pos := range g.customMethodToPos[req.Method] {
-			if len(p) <= pos {
+		for i := range values {
+			if !present[i] {
 				// in case params of the position are not present
 				// default getters are used
 				if i == 0 {
@@ -180,7 +255,7 @@ func (g *GenericConv[T, R, S, SR]) getGetters(req *models.RPCReq) ([]T, error) {
 				return nil, ErrParseErr
 			}
 
-			gt, err := g.impl.ExtractGetter(p[pos])
+			gt, err := g.impl.ExtractGetter(values[i])
 			if err != nil {
 				return nil, err
 			}
@@ -194,6 +269,168 @@ func (g *GenericConv[T, R, S, SR]) getGetters(req *models.RPCReq) ([]T, error) {
 	return nil, nil
 }
 
+// getterPaths are the parsed JSON paths of an alternative of the getter paths of a method
+type getterPaths struct {
+	paths    []jsonPath
+	defaults []interface{}
+}
+
+// parseGetterPaths returns the parsed getter paths of the method, it panics if they are not valid
+func parseGetterPaths(method Method) []getterPaths {
+	var parsed []getterPaths
+	for _, gp := range method.GetterPaths {
+		if len(gp.Paths) == 0 || len(gp.Paths) > 2 {
+			panic(fmt.Sprintf("getter paths length for method %s is %d and it must be 1 or 2", method.CustomMethod, len(gp.Paths)))
+		}
+		if len(gp.Defaults) > len(gp.Paths) {
+			panic(fmt.Sprintf("getter paths defaults for method %s are more than its paths", method.CustomMethod))
+		}
+
+		alternative := getterPaths{defaults: gp.Defaults}
+		for _, path := range gp.Paths {
+			jp, err := parseJSONPath(path)
+			if err != nil {
+				panic(fmt.Sprintf("getter paths for method %s: %v", method.CustomMethod, err))
+			}
+			alternative.paths = append(alternative.paths, jp)
+		}
+		parsed = append(parsed, alternative)
+	}
+
+	return parsed
+}
+
+// matchGetterPaths returns the first getter paths of the method with any of its paths on the params,
+// or the last one of the form of the params so the missing paths can be added, or the last one
+func (g *GenericConv[T, R, S, SR]) matchGetterPaths(customMethod string, params interface{}) getterPaths {
+	alternatives := g.customMethodToPaths[customMethod]
+	for _, alternative := range alternatives {
+		for _, path := range alternative.paths {
+			if _, ok := path.get(params); ok {
+				return alternative
+			}
+		}
+	}
+
+	for i := len(alternatives) - 1; i >= 0; i-- {
+		if alternatives[i].paths[0].isFormOf(params) {
+			return alternatives[i]
+		}
+	}
+
+	return alternatives[len(alternatives)-1]
+}
+
+// pathGetterParams returns the params at the getter paths of the method and if each of them is present
+// the defaults of the paths are used for the params that are not present
+func (g *GenericConv[T, R, S, SR]) pathGetterParams(req *models.RPCReq) ([]interface{}, []bool, error) {
+	var p interface{}
+	err := json.Unmarshal(req.Params, &p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	alternative := g.matchGetterPaths(req.Method, p)
+	values := make([]interface{}, len(alternative.paths))
+	present := make([]bool, len(alternative.paths))
+	for i, path := range alternative.paths {
+		values[i], present[i] = path.get(p)
+		if !present[i] && i < len(alternative.defaults) && alternative.defaults[i] != nil {
+			values[i], present[i] = alternative.defaults[i], true
+		}
+	}
+
+	return values, present, nil
+}
+
+// isNamedParams returns if the params are a named object instead of a positional array
+func isNamedParams(params json.RawMessage) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(params), []byte("{"))
+}
+
+// getterParams returns the params at the positions of the method, or at its keys if the params are named,
+// and if each of them is present. nil is returned if the params are empty
+// methods without getter params of the form of the params can't be parsed
+func (g *GenericConv[T, R, S, SR]) getterParams(req *models.RPCReq) ([]interface{}, []bool, error) {
+	if isNamedParams(req.Params) {
+		var p map[string]interface{}
+		err := json.Unmarshal(req.Params, &p)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keys := g.customMethodToKeys[req.Method]
+		if len(p) == 0 {
+			return nil, nil, nil
+		}
+		if len(keys) == 0 {
+			return nil, nil, ErrParseErr
+		}
+
+		values := make([]interface{}, len(keys))
+		present := make([]bool, len(keys))
+		for i, key := range keys {
+			values[i], present[i] = p[key]
+		}
+
+		return values, present, nil
+	}
+
+	var p []interface{}
+	err := json.Unmarshal(req.Params, &p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	positions := g.customMethodToPos[req.Method]
+	if len(p) == 0 {
+		return nil, nil, nil
+	}
+	if len(positions) == 0 {
+		return nil, nil, ErrParseErr
+	}
+
+	values := make([]interface{}, len(positions))
+	present := make([]bool, len(positions))
+	for i, pos := range positions {
+		if len(p) > pos {
+			values[i], present[i] = p[pos], true
+		}
+	}
+
+	return values, present, nil
+}
+
+func (g *GenericConv[T, R, S, SR]) FilterInvalidRPCReqs(rpcReqs []*models.RPCReq) ([]*models.RPCReq, []*models.RPCResJSON) {
+	var validReqs []*models.RPCReq
+	var errRess []*models.RPCResJSON
+
+	for _, req := range rpcReqs {
+		_, err := g.getGetters(req)
+		if err != nil {
+			var rpcErr *models.RPCErr
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &models.RPCErr{
+					Code:          ErrParseErr.Code,
+					Message:       err.Error(),
+					HTTPErrorCode: ErrParseErr.HTTPErrorCode,
+				}
+			}
+
+			errRess = append(errRess, &models.RPCResJSON{
+				JSONRPC: "2.0",
+				Error:   rpcErr,
+				ID:      req.ID,
+			})
+			continue
+		}
+
+		validReqs = append(validReqs, req)
+	}
+
+	return validReqs, errRess
+}
+
 func (g *GenericConv[T, R, S, SR]) GetCustomMethodsMap(rpcReqs []*models.RPCReq) (map[string][]GetterTypesHolder, error) {
 	customMethodsGetter := make(map[string][]GetterTypesHolder, len(rpcReqs))
 
@@ -246,6 +483,18 @@ func (g *GenericConv[T, R, S, SR]) AddGetterMethodsIfNeeded(rpcReqs []*models.RP
 		}
 	}
 
+	// the context req is added once for all the custom methods
+	index := g.impl.GetIndexOfContextReq()
+	if len(cMethodsGetter) > 0 && index != "" {
+		id, err := generateRandomNumberStringWithRetries(rpcReqs)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		idsHolder[index] = id
+		rpcReqs = append(rpcReqs, g.impl.BuildContextReq(id))
+	}
+
 	return rpcReqs, idsHolder, nil
 }
 
@@ -265,6 +514,185 @@ func (b *GenericConv[T, R, S, SR]) ChangeCustomMethods(rpcReqs []*models.RPCReq)
 	return changedMethods, nil
 }
 
+// SplitGetterReqs separates the getter rpc reqs added by AddGetterMethodsIfNeeded from the rest of the rpc reqs
+func SplitGetterReqs(rpcReqs []*models.RPCReq, idsHolder map[string]string) ([]*models.RPCReq, []*models.RPCReq) {
+	getterIDs := make(map[string]bool, len(idsHolder))
+	for _, id := range idsHolder {
+		getterIDs[id] = true
+	}
+
+	var reqs, getterReqs []*models.RPCReq
+	for _, rpcReq := range rpcReqs {
+		if getterIDs[string(rpcReq.ID)] {
+			getterReqs = append(getterReqs, rpcReq)
+		} else {
+			reqs = append(reqs, rpcReq)
+		}
+	}
+
+	return reqs, getterReqs
+}
+
+func (g *GenericConv[T, R, S, SR]) PinGetterParams(rpcReqs []*models.RPCReq, getterResponses []*models.RPCResJSON, changedMethods, idsHolder map[string]string, cMethodsGetter map[string][]GetterTypesHolder) error {
+	gHolder, _, err := getGetterHolder(getterResponses, idsHolder)
+	if err != nil {
+		return err
+	}
+
+	for _, req := range rpcReqs {
+		customMethod, ok := changedMethods[string(req.ID)]
+		if !ok {
+			continue
+		}
+
+		// without paths, positions or keys of the form of the params the getters of custom handlers can't be rewritten,
+		// the default getters and the ones of handlers that report no positions are not present in the params
+		named := isNamedParams(req.Params)
+		hasPaths := len(g.customMethodToPaths[customMethod]) > 0
+		positions, reported := g.getterPositions(req, customMethod)
+		if !hasPaths && ((named && len(g.customMethodToKeys[customMethod]) == 0) || (!named && len(positions) == 0)) {
+			_, hasHandler := g.customMethodToCustomHandler[customMethod]
+			if hasHandler && !reported {
+				return ErrMethodNotPinnable
+			}
+			continue
+		}
+
+		getterReturns, gtError, err := g.getGetterReturn(string(req.ID), gHolder, cMethodsGetter)
+		if err != nil {
+			return err
+		}
+		if gtError != nil {
+			continue // the getter error will be returned on the response
+		}
+
+		switch {
+		case hasPaths:
+			err = g.pinPathParams(req, customMethod, cMethodsGetter[string(req.ID)], getterReturns)
+		case named:
+			err = g.pinNamedParams(req, customMethod, cMethodsGetter[string(req.ID)], getterReturns)
+		default:
+			err = g.pinPositionalParams(req, positions, cMethodsGetter[string(req.ID)], getterReturns)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getterPositions returns the positions of the getter params of the req, the ones found by the custom handler
+// and true if the chain type reports them
+func (g *GenericConv[T, R, S, SR]) getterPositions(req *models.RPCReq, customMethod string) ([]int, bool) {
+	handlerName, ok := g.customMethodToHandlerName[customMethod]
+	if ok {
+		positioner, ok := g.impl.(getterPositioner)
+		if ok {
+			positions, ok := positioner.GetterPositions(handlerName, req)
+			if ok {
+				return positions, true
+			}
+		}
+	}
+
+	return g.customMethodToPos[customMethod], false
+}
+
+func (g *GenericConv[T, R, S, SR]) pinPositionalParams(req *models.RPCReq, positions []int, gts []GetterTypesHolder, getterReturns []R) error {
+	var p []interface{}
+	if req.Params != nil {
+		err := decodeParams(req.Params, &p)
+		if err != nil {
+			return err
+		}
+	}
+
+	paths := make([]jsonPath, len(positions))
+	for i, pos := range positions {
+		paths[i] = jsonPath{{index: pos, isIndex: true}}
+	}
+
+	return g.pinParams(req, p, paths, gts, getterReturns)
+}
+
+// pinPathParams rewrites the params at the getter paths that were used to get the getters
+// missing paths are only added if the getter was pinned
+func (g *GenericConv[T, R, S, SR]) pinPathParams(req *models.RPCReq, customMethod string, gts []GetterTypesHolder, getterReturns []R) error {
+	var p interface{}
+	if req.Params != nil {
+		err := decodeParams(req.Params, &p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return g.pinParams(req, p, g.matchGetterPaths(customMethod, p).paths, gts, getterReturns)
+}
+
+func (g *GenericConv[T, R, S, SR]) pinNamedParams(req *models.RPCReq, customMethod string, gts []GetterTypesHolder, getterReturns []R) error {
+	var p map[string]interface{}
+	err := decodeParams(req.Params, &p)
+	if err != nil {
+		return err
+	}
+
+	keys := g.customMethodToKeys[customMethod]
+	paths := make([]jsonPath, len(keys))
+	for i, key := range keys {
+		paths[i] = jsonPath{{key: key}}
+	}
+
+	return g.pinParams(req, p, paths, gts, getterReturns)
+}
+
+// pinParams splices the pinned getters into the params at their paths, the rest of the params are forwarded as they were sent
+// so numbers that don't fit a float64 and the order of the keys are kept. Getters that were not pinned are not added
+func (g *GenericConv[T, R, S, SR]) pinParams(req *models.RPCReq, p interface{}, paths []jsonPath, gts []GetterTypesHolder, getterReturns []R) error {
+	params := req.Params
+	for i, gt := range gts {
+		if i >= len(paths) || i >= len(getterReturns) {
+			break
+		}
+
+		current, _ := paths[i].get(p)
+		sent, err := json.Marshal(current) // before pinning since the getter can be pinned on the current param
+		if err != nil {
+			return err
+		}
+		pinned, err := g.impl.PinGetter(current, g.impl.FromGetterTypeToHolder(gt), getterReturns[i])
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(pinned)
+		if err != nil {
+			return err
+		}
+		if pinned == nil || bytes.Equal(value, sent) {
+			continue
+		}
+
+		params, _ = paths[i].splice(params, value) // params of another form on the path are kept as they are
+		replacer, ok := g.impl.(replacedKeysPinner)
+		if ok && len(paths[i]) == 1 && !paths[i][0].isIndex {
+			for _, key := range replacer.ReplacedKeys() {
+				params, _ = jsonPath{{key: key}}.remove(params)
+			}
+		}
+	}
+	req.Params = params
+
+	return nil
+}
+
+// decodeParams decodes the params keeping their numbers as they were sent
+func decodeParams(params json.RawMessage, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.UseNumber()
+
+	return dec.Decode(v)
+}
+
 func getGetterHolder(responses []*models.RPCResJSON, idsHolder map[string]string) (map[string]*models.RPCResJSON, []*models.RPCResJSON, error) {
 	gHolder := make(map[string]*models.RPCResJSON, len(idsHolder))
 	var responsesWithoutG []*models.RPCResJSON
@@ -306,8 +734,8 @@ func (b *GenericConv[T, R, S, SR]) ChangeCustomMethodsResponses(responses []*mod
 	return cleanRes, nil
 }
 
-func (g *GenericConv[T, R, S, SR]) getGetterReturn(res *models.RPCResJSON, gtHolder map[string]*models.RPCResJSON, cMethodsGetter map[string][]GetterTypesHolder) ([]R, *models.RPCErr, error) {
-	gts := cMethodsGetter[string(res.ID)]
+func (g *GenericConv[T, R, S, SR]) getGetterReturn(id string, gtHolder map[string]*models.RPCResJSON, cMethodsGetter map[string][]GetterTypesHolder) ([]R, *models.RPCErr, error) {
+	gts := cMethodsGetter[id]
 
 	var getterReturns []R
 	var gtError *models.RPCErr
@@ -320,7 +748,10 @@ func (g *GenericConv[T, R, S, SR]) getGetterReturn(res *models.RPCResJSON, gtHol
 		}
 
 		if index != "" {
-			gth := gtHolder[index]
+			gth, ok := gtHolder[index]
+			if !ok {
+				return nil, nil, ErrInternal // getter response is missing from the upstream responses
+			}
 			if gth.Error != nil {
 				gtError = gth.Error
 				break // if there was an error the rest of the response is invalid
@@ -348,7 +779,7 @@ func (b *GenericConv[T, R, S, SR]) changeResultToGetterStruct(res *models.RPCRes
 	if res.Error != nil {
 		return nil // if there is an error the rest of the response is invalid
 	}
-	getterReturn, gtError, err := b.getGetterReturn(res, gtHolder, cMethodsGetter)
+	newRes, gtError, err := b.getterStruct(res, gtHolder, cMethodsGetter, originalMethod)
 	if err != nil {
 		return err
 	}
@@ -358,6 +789,36 @@ func (b *GenericConv[T, R, S, SR]) changeResultToGetterStruct(res *models.RPCRes
 		return nil
 	}
 
+	wrapped := splice(newRes, res.Result)
+	plugin, ok := b.customMethodToWrapPlugin[originalMethod]
+	if ok {
+		var rpcErr *models.RPCErr
+		wrapped, rpcErr = plugin.wrap(pluginWrapInput{
+			Method:  originalMethod,
+			Result:  res.Result,
+			Wrapped: wrapped,
+			Block:   newRes.resolvedBlock(),
+		})
+		if rpcErr != nil {
+			res.Result = nil
+			res.Error = rpcErr
+			return nil
+		}
+	}
+
+	res.Result = wrapped
+	res.Block = newRes.resolvedBlock()
+
+	return nil
+}
+
+// getterStruct returns the custom struct of the response with the getter returns of its getters
+func (b *GenericConv[T, R, S, SR]) getterStruct(res *models.RPCResJSON, gtHolder map[string]*models.RPCResJSON, cMethodsGetter map[string][]GetterTypesHolder, originalMethod string) (customResult, *models.RPCErr, error) {
+	getterReturn, gtError, err := b.getGetterReturn(string(res.ID), gtHolder, cMethodsGetter)
+	if err != nil || gtError != nil {
+		return nil, gtError, err
+	}
+
 	if b.customMethodToIsRange[originalMethod] {
 		from := getterReturn[0]
 		to := getterReturn[0]
@@ -366,16 +827,104 @@ func (b *GenericConv[T, R, S, SR]) changeResultToGetterStruct(res *models.RPCRes
 		}
 		newRes, err := b.impl.ExtractGetterRangeStruct(res, from, to)
 		if err != nil {
-			return err
-		}
-		res.Result = newRes
-	} else {
-		newRes, err := b.impl.ExtractGetterStruct(res, getterReturn[0])
-		if err != nil {
-			return err
+			return nil, nil, err
 		}
-		res.Result = newRes
+		return newRes, nil, nil
+	}
+
+	newRes, err := b.impl.ExtractGetterStruct(res, getterReturn[0], gtHolder[b.impl.GetIndexOfContextReq()])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newRes, nil, nil
+}
+
+func (b *GenericConv[T, R, S, SR]) ResultEnvelope(id json.RawMessage, getterResponses []*models.RPCResJSON, changedMethods, idsHolder map[string]string, cMethodsGetter map[string][]GetterTypesHolder) ([]byte, []byte, string, *models.RPCErr, error) {
+	originalMethod, ok := changedMethods[string(id)]
+	if !ok {
+		return nil, nil, "", nil, nil
+	}
+
+	gHolder, _, err := getGetterHolder(getterResponses, idsHolder)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+
+	newRes, gtError, err := b.getterStruct(&models.RPCResJSON{ID: id}, gHolder, cMethodsGetter, originalMethod)
+	if err != nil || gtError != nil {
+		return nil, nil, "", gtError, err
+	}
+
+	prefix, suffix := newRes.envelope()
+
+	return prefix, suffix, newRes.resolvedBlock(), nil, nil
+}
+
+func (b *GenericConv[T, R, S, SR]) WrapsResult(customMethod string) bool {
+	_, ok := b.customMethodToWrapPlugin[customMethod]
+	return ok
+}
+
+func (g *GenericConv[T, R, S, SR]) ChangeSubscriptionNotification(notification *models.RPCNotification) error {
+	gr, ok, err := g.impl.ExtractGetterReturnFromNotification(notification)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil // notification doesn't have the getter return or already has it on its own structure
+	}
+
+	newRes, err := g.impl.ExtractGetterStruct(&models.RPCResJSON{Result: notification.Params.Result}, gr, nil)
+	if err != nil {
+		return err
 	}
+	notification.Params.Result = splice(newRes, notification.Params.Result)
+	notification.Block = newRes.resolvedBlock()
 
 	return nil
 }
+
+func (g *GenericConv[T, R, S, SR]) BuildDefaultGetterReq(id string) (*models.RPCReq, string, error) {
+	gt := g.impl.GetDefaultGetter()
+
+	index, err := g.impl.GetIndexOfIDHolder(gt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := g.impl.BuildGetterReq(id, gt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return req, index, nil
+}
+
+func (g *GenericConv[T, R, S, SR]) IsImmutableReq(rpcReq *models.RPCReq, finalizedHeight uint64) bool {
+	// original methods have the getter params on the same positions as their custom counterparts
+	customMethod, ok := g.regularToCustom[rpcReq.Method]
+	if !ok {
+		customMethod = rpcReq.Method
+	}
+	if !g.customMethodIsCacheable[customMethod] {
+		return false
+	}
+
+	gts, err := g.getGetters(&models.RPCReq{
+		Method: customMethod,
+		Params: rpcReq.Params,
+		ID:     rpcReq.ID,
+	})
+	if err != nil || len(gts) == 0 {
+		return false
+	}
+
+	for _, gt := range gts {
+		if !g.impl.IsImmutableGetter(gt, finalizedHeight) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -9,7 +9,7 @@ import (
 
 // CustomHandlerHolder is a generic of structs that hold the methods for custom handlers
 type CustomHandlerHolder interface {
-	evmCustomHandlersHolder
+	evmCustomHandlersHolder | solanaCustomHandlersHolder | cosmosCustomHandlersHolder | starknetCustomHandlersHolder | nearCustomHandlersHolder | tronCustomHandlersHolder
 }
 
 // this validates if all custom handlers have the correct structure and saves unto a map
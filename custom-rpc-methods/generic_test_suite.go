@@ -1,6 +1,7 @@
 package customrpcmethods
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stateless-solutions/compatibility-layer/models"
@@ -65,10 +66,114 @@ func runTests(t *testing.T, configFile string, tests []testCaseGenericConv) {
 					}
 				}
 
-				if ress[0].Result != tt.expectedRes.Result {
+				if string(ress[0].Result) != string(tt.expectedRes.Result) {
 					t.Errorf("Test case %s: Expected response %s, got %s", tt.name, tt.expectedRes.Result, tt.res[0].Result)
 				}
 			}
 		})
 	}
 }
+
+type testCasePinGetterParams struct {
+	name           string
+	req            []*models.RPCReq
+	getterRes      map[string]*models.RPCResJSON // responses of the getters by their content
+	expectedParams []string
+	expectedErr    error
+}
+
+func runPinTests(t *testing.T, configFile string, tests []testCasePinGetterParams) {
+	runPinTestsFromConfigs(t, ReadConfigFiles(configFile), tests)
+}
+
+func runPinTestsFromConfigs(t *testing.T, configs []MethodsConfig, tests []testCasePinGetterParams) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := NewCustomMethodHolderFromConfigs(false, configs)
+
+			context, err := ch.GetCustomMethodsMap(tt.req)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+			changedMethods, _ := ch.ChangeCustomMethods(tt.req)
+
+			reqs, idsHolder, err := ch.AddGetterMethodsIfNeeded(tt.req, context)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+
+			reqs, getterReqs := SplitGetterReqs(reqs, idsHolder)
+			if len(getterReqs) != len(tt.getterRes) {
+				t.Fatalf("Test case %s: Expected %d getter reqs, got %d", tt.name, len(tt.getterRes), len(getterReqs))
+			}
+
+			var getterRess []*models.RPCResJSON
+			for content, res := range tt.getterRes {
+				res.ID = json.RawMessage(idsHolder[content])
+				getterRess = append(getterRess, res)
+			}
+
+			err = ch.PinGetterParams(reqs, getterRess, changedMethods, idsHolder, context)
+			if err != tt.expectedErr {
+				t.Fatalf("Test case %s: Expected error %v, got %v", tt.name, tt.expectedErr, err)
+			}
+
+			for i, expectedParams := range tt.expectedParams {
+				if string(reqs[i].Params) != expectedParams {
+					t.Errorf("Test case %s: Expected params %s, got %s", tt.name, expectedParams, reqs[i].Params)
+				}
+			}
+		})
+	}
+}
+
+// runFilterInvalidTests checks that the reqs with invalid getter params are answered with a parse error and the rest are kept
+func runFilterInvalidTests(t *testing.T, configFile string, reqs []*models.RPCReq, expectedValidIDs, expectedErrIDs []string) {
+	ch := NewCustomMethodHolder(false, configFile)
+
+	validReqs, errRess, err := ch.FilterInvalidRPCReqs(reqs)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+
+	if len(validReqs) != len(expectedValidIDs) {
+		t.Fatalf("Expected %d valid reqs, got %d", len(expectedValidIDs), len(validReqs))
+	}
+	for i, id := range expectedValidIDs {
+		if string(validReqs[i].ID) != id {
+			t.Errorf("Expected valid req with id %s, got %s", id, validReqs[i].ID)
+		}
+	}
+
+	if len(errRess) != len(expectedErrIDs) {
+		t.Fatalf("Expected %d error responses, got %d", len(expectedErrIDs), len(errRess))
+	}
+	for i, id := range expectedErrIDs {
+		if string(errRess[i].ID) != id {
+			t.Errorf("Expected error response with id %s, got %s", id, errRess[i].ID)
+		}
+		if errRess[i].Error == nil || errRess[i].Error.Code != ErrParseErr.Code {
+			t.Errorf("Expected error code %d for id %s, got %v", ErrParseErr.Code, id, errRess[i].Error)
+		}
+	}
+}
+
+type testCaseIsImmutableReq struct {
+	name     string
+	req      *models.RPCReq
+	expected bool
+}
+
+// runIsImmutableTests checks if the reqs are immutable at the finalized height
+func runIsImmutableTests(t *testing.T, configFile string, finalizedHeight uint64, tests []testCaseIsImmutableReq) {
+	ch := NewCustomMethodHolder(false, configFile)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			immutable := ch.IsImmutableReq(tt.req, finalizedHeight)
+			if immutable != tt.expected {
+				t.Errorf("Test case %s: Expected immutable %v, got %v", tt.name, tt.expected, immutable)
+			}
+		})
+	}
+}
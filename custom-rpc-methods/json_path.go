@@ -0,0 +1,261 @@
+package customrpcmethods
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is a key of an object or an index of an array of a JSON path
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// jsonPath is a path to a value of the params, e.g. $[0].fromBlock
+// only the root, keys (.key or ["key"]) and indexes ([0]) are supported
+type jsonPath []pathSegment
+
+func parseJSONPath(path string) (jsonPath, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("json path %s doesn't start with $", path)
+	}
+
+	var segments jsonPath
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("json path %s has an empty key", path)
+			}
+			segments = append(segments, pathSegment{key: key})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("json path %s has an unclosed bracket", path)
+			}
+			inner := rest[1:end]
+			if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, pathSegment{key: inner[1 : len(inner)-1]})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("json path %s has an invalid index %s", path, inner)
+				}
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("json path %s has an unexpected character %q", path, rest[0])
+		}
+	}
+
+	return segments, nil
+}
+
+// get returns the value of the path on the decoded params, null values are not present
+func (p jsonPath) get(v interface{}) (interface{}, bool) {
+	for _, segment := range p {
+		if segment.isIndex {
+			arr, ok := v.([]interface{})
+			if !ok || segment.index >= len(arr) {
+				return nil, false
+			}
+			v = arr[segment.index]
+		} else {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			v = obj[segment.key]
+		}
+	}
+
+	return v, v != nil
+}
+
+// isFormOf returns if the path is of the form of the decoded params, an index of positional params or a key of named ones
+func (p jsonPath) isFormOf(v interface{}) bool {
+	if len(p) == 0 {
+		return true
+	}
+
+	switch v.(type) {
+	case []interface{}:
+		return p[0].isIndex
+	case map[string]interface{}:
+		return !p[0].isIndex
+	}
+
+	return false
+}
+
+// splice returns the raw params with the raw value on the path, the rest of the params are kept as they were sent
+// missing objects and arrays of the path are added and arrays are filled with nulls up to the index.
+// False is returned if the params have another type on the path
+func (p jsonPath) splice(raw, value json.RawMessage) (json.RawMessage, bool) {
+	if len(p) == 0 {
+		return value, true
+	}
+
+	segment := p[0]
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = []byte("{}")
+		if segment.isIndex {
+			raw = []byte("[]")
+		}
+	}
+
+	start, end, count, ok := segment.locate(raw)
+	if !ok {
+		return raw, false
+	}
+	if start >= 0 {
+		child, ok := p[1:].splice(raw[start:end], value)
+		return concatJSON(raw[:start], child, raw[end:]), ok
+	}
+
+	child, ok := p[1:].splice(nil, value)
+	var member []byte
+	if count > 0 {
+		member = []byte(",")
+	}
+	if segment.isIndex {
+		for ; count < segment.index; count++ {
+			member = append(member, "null,"...)
+		}
+		member = append(member, child...)
+	} else {
+		key, _ := json.Marshal(segment.key)
+		member = concatJSON(member, key, []byte(":"), child)
+	}
+	closing := len(raw) - 1
+
+	return concatJSON(raw[:closing], member, raw[closing:]), ok
+}
+
+// locate returns the offsets of the value of the segment on the raw object or array, -1 if it is not present,
+// and the number of members of the object or array. False is returned if the raw value is of another type
+func (s pathSegment) locate(raw json.RawMessage) (int, int, int, bool) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	tok, err := dec.Token()
+	if err != nil {
+		return -1, -1, 0, false
+	}
+	delim, _ := tok.(json.Delim)
+	if (s.isIndex && delim != '[') || (!s.isIndex && delim != '{') {
+		return -1, -1, 0, false
+	}
+
+	count := 0
+	for ; dec.More(); count++ {
+		var key string
+		if !s.isIndex {
+			tok, err := dec.Token()
+			if err != nil {
+				return -1, -1, 0, false
+			}
+			key, _ = tok.(string)
+		}
+
+		var value json.RawMessage
+		err := dec.Decode(&value)
+		if err != nil {
+			return -1, -1, 0, false
+		}
+		if (s.isIndex && count == s.index) || (!s.isIndex && key == s.key) {
+			end := int(dec.InputOffset())
+			return end - len(value), end, count, true
+		}
+	}
+
+	return -1, -1, count, true
+}
+
+// remove returns the raw params without the value on the path, the rest of the params are kept as they were sent.
+// False is returned if the path is not on the params
+func (p jsonPath) remove(raw json.RawMessage) (json.RawMessage, bool) {
+	if len(p) == 0 {
+		return raw, false
+	}
+
+	raw = bytes.TrimSpace(raw)
+	if len(p) > 1 {
+		start, end, _, ok := p[0].locate(raw)
+		if !ok || start < 0 {
+			return raw, false
+		}
+		child, ok := p[1:].remove(raw[start:end])
+		return concatJSON(raw[:start], child, raw[end:]), ok
+	}
+
+	start, end, ok := p[0].locateMember(raw)
+	if !ok {
+		return raw, false
+	}
+
+	return concatJSON(raw[:start], raw[end:]), true
+}
+
+// locateMember returns the offsets of the member of the segment on the raw object or array with one of its commas
+func (s pathSegment) locateMember(raw json.RawMessage) (int, int, bool) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	tok, err := dec.Token()
+	if err != nil {
+		return -1, -1, false
+	}
+	delim, _ := tok.(json.Delim)
+	if (s.isIndex && delim != '[') || (!s.isIndex && delim != '{') {
+		return -1, -1, false
+	}
+
+	// the member starts after the previous value, so it includes the comma before it
+	start := int(dec.InputOffset())
+	for count := 0; dec.More(); count++ {
+		var key string
+		if !s.isIndex {
+			tok, err := dec.Token()
+			if err != nil {
+				return -1, -1, false
+			}
+			key, _ = tok.(string)
+		}
+
+		var value json.RawMessage
+		err := dec.Decode(&value)
+		if err != nil {
+			return -1, -1, false
+		}
+		end := int(dec.InputOffset())
+		if (s.isIndex && count == s.index) || (!s.isIndex && key == s.key) {
+			if count == 0 && dec.More() {
+				end += bytes.IndexByte(raw[end:], ',') + 1 // the first member has no comma before it
+				end = len(raw) - len(bytes.TrimLeft(raw[end:], " \t\r\n"))
+			}
+			return start, end, true
+		}
+		start = end
+	}
+
+	return -1, -1, false
+}
+
+func concatJSON(parts ...[]byte) json.RawMessage {
+	var b []byte
+	for _, part := range parts {
+		b = append(b, part...)
+	}
+
+	return b
+}
@@ -0,0 +1,179 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		params        string
+		expectedValue interface{}
+		expectedOk    bool
+		value         interface{}
+		expectedSet   string
+	}{
+		{
+			name:          "Key of an element",
+			path:          "$[0].fromBlock",
+			params:        `[{"fromBlock":"0x1"}]`,
+			expectedValue: "0x1",
+			expectedOk:    true,
+			value:         "0x2",
+			expectedSet:   `[{"fromBlock":"0x2"}]`,
+		},
+		{
+			name:        "Missing key",
+			path:        "$[0].toBlock",
+			params:      `[{"fromBlock":"0x1"}]`,
+			value:       "0x2",
+			expectedSet: `[{"fromBlock":"0x1","toBlock":"0x2"}]`,
+		},
+		{
+			name:        "Null value",
+			path:        `$[0]["toBlock"]`,
+			params:      `[{"toBlock":null}]`,
+			value:       "0x2",
+			expectedSet: `[{"toBlock":"0x2"}]`,
+		},
+		{
+			name:        "Missing element",
+			path:        "$[1].block_id",
+			params:      `["0x1"]`,
+			value:       "latest",
+			expectedSet: `["0x1",{"block_id":"latest"}]`,
+		},
+		{
+			name:          "Named params",
+			path:          "$.block.height",
+			params:        `{"block":{"height":10}}`,
+			expectedValue: float64(10),
+			expectedOk:    true,
+			value:         11,
+			expectedSet:   `{"block":{"height":11}}`,
+		},
+		{
+			name:        "Big integer and key order",
+			path:        "$[0].toBlock",
+			params:      `[{"topics":[],"amount":12345678901234567890,"fromBlock":"0x1"}]`,
+			value:       "0x2",
+			expectedSet: `[{"topics":[],"amount":12345678901234567890,"fromBlock":"0x1","toBlock":"0x2"}]`,
+		},
+		{
+			name:        "Missing params",
+			path:        "$[1]",
+			params:      `null`,
+			value:       "latest",
+			expectedSet: `[null,"latest"]`,
+		},
+		{
+			name:        "Another type on the path",
+			path:        "$[0].fromBlock",
+			params:      `["0x1"]`,
+			value:       "0x2",
+			expectedSet: `["0x1"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := parseJSONPath(tt.path)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+
+			var p interface{}
+			err = json.Unmarshal([]byte(tt.params), &p)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+
+			value, ok := path.get(p)
+			if value != tt.expectedValue || ok != tt.expectedOk {
+				t.Errorf("Test case %s: Expected %v and %v, got %v and %v", tt.name, tt.expectedValue, tt.expectedOk, value, ok)
+			}
+
+			raw, _ := json.Marshal(tt.value)
+			set, _ := path.splice(json.RawMessage(tt.params), raw)
+			if string(set) != tt.expectedSet {
+				t.Errorf("Test case %s: Expected %s, got %s", tt.name, tt.expectedSet, set)
+			}
+		})
+	}
+}
+
+func TestJSONPathRemove(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		params         string
+		expectedParams string
+		expectedOk     bool
+	}{
+		{
+			name:           "First key",
+			path:           "$.finality",
+			params:         `{"finality":"final", "request_type":"view_account"}`,
+			expectedParams: `{"request_type":"view_account"}`,
+			expectedOk:     true,
+		},
+		{
+			name:           "Last key",
+			path:           "$.finality",
+			params:         `{"request_type":"view_account","finality":"final"}`,
+			expectedParams: `{"request_type":"view_account"}`,
+			expectedOk:     true,
+		},
+		{
+			name:           "Only key",
+			path:           "$.finality",
+			params:         `{"finality":"final"}`,
+			expectedParams: `{}`,
+			expectedOk:     true,
+		},
+		{
+			name:           "Nested key",
+			path:           "$[0].toBlock",
+			params:         `[{"fromBlock":"0x1","toBlock":"latest","amount":12345678901234567890}]`,
+			expectedParams: `[{"fromBlock":"0x1","amount":12345678901234567890}]`,
+			expectedOk:     true,
+		},
+		{
+			name:           "Missing key",
+			path:           "$.finality",
+			params:         `{"block_id":21}`,
+			expectedParams: `{"block_id":21}`,
+		},
+		{
+			name:           "Another type on the path",
+			path:           "$.finality",
+			params:         `["final"]`,
+			expectedParams: `["final"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := parseJSONPath(tt.path)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+
+			params, ok := path.remove(json.RawMessage(tt.params))
+			if string(params) != tt.expectedParams || ok != tt.expectedOk {
+				t.Errorf("Test case %s: Expected %s and %v, got %s and %v", tt.name, tt.expectedParams, tt.expectedOk, params, ok)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	for _, path := range []string{"[0]", "$[0", "$[-1]", "$[a]", "$.", "$..a", "$a"} {
+		_, err := parseJSONPath(path)
+		if err == nil {
+			t.Errorf("Path %s: Expected an error", path)
+		}
+	}
+}
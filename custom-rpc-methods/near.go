@@ -0,0 +1,234 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// NearBlockReference is the getter of the NEAR methods, a finality or a block id that is a height or a hash
+// only one of them is set, a block reference without finality or hash is a height
+type NearBlockReference struct {
+	Finality string
+	Height   uint64
+	Hash     string
+}
+
+var (
+	ErrInternalBlockWithoutHeader = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 33,
+		Message:       "block response does not have the header height and hash",
+		HTTPErrorCode: 500,
+	}
+
+	// base58 of the 32 bytes of the hash
+	nearBlockHashRegex = regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{43,44}$`)
+
+	nearFinalities = map[string]bool{
+		"optimistic": true,
+		"near-final": true,
+		"final":      true,
+	}
+
+	nearMethodNameToCustomHandler = make(map[string]func(*models.RPCReq) ([]NearBlockReference, error))
+)
+
+func init() {
+	SaveCustomHandlersToMap(nearCustomHandlersHolder{}, nearMethodNameToCustomHandler)
+}
+
+type NearImpl struct{}
+
+func (n NearImpl) GetChainType() ChainType {
+	return ChainTypeNear
+}
+
+func (n NearImpl) SupportsRange() bool {
+	return false
+}
+
+func (n NearImpl) GetHealthCheckReq() *models.RPCReq {
+	return buildStatusReq("1")
+}
+
+func (n NearImpl) ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error) {
+	var status struct {
+		SyncInfo struct {
+			LatestBlockHeight *uint64 `json:"latest_block_height"`
+		} `json:"sync_info"`
+	}
+	err := json.Unmarshal(res.Result, &status)
+	if err != nil || status.SyncInfo.LatestBlockHeight == nil {
+		return 0, ErrInternalStatusNotExpectedType
+	}
+
+	return *status.SyncInfo.LatestBlockHeight, nil
+}
+
+func (n NearImpl) GetBlockTime() time.Duration {
+	return time.Second
+}
+
+func (n NearImpl) GetFinalizedHeightReq() *models.RPCReq {
+	req, _ := n.BuildGetterReq("1", NearBlockReference{Finality: "final"})
+	return req
+}
+
+func (n NearImpl) ExtractFinalizedHeight(res *models.RPCResJSON) (uint64, error) {
+	block, err := n.ExtractGetterReturnFromResponse(res)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(block.Height), nil
+}
+
+// methods without a block reference are served at the head of the chain, that is the optimistic block
+func (n NearImpl) GetDefaultGetter() NearBlockReference {
+	return NearBlockReference{Finality: "optimistic"}
+}
+
+func (n NearImpl) GetDefaultGetterRange() []NearBlockReference {
+	return nil
+}
+
+func (n NearImpl) GetCustomHandlerMap() map[string]func(*models.RPCReq) ([]NearBlockReference, error) {
+	return nearMethodNameToCustomHandler
+}
+
+func (n NearImpl) FromGetterTypeToHolder(gth GetterTypesHolder) NearBlockReference {
+	return gth.Near
+}
+
+func (n NearImpl) FromHolderToGetterType(gt NearBlockReference) GetterTypesHolder {
+	return GetterTypesHolder{
+		Near: gt,
+	}
+}
+
+// the param is a block id, heights are numbers and hashes are strings
+func (n NearImpl) ExtractGetter(param interface{}) (NearBlockReference, error) {
+	switch blockID := param.(type) {
+	case nil:
+		return n.GetDefaultGetter(), nil
+	case float64:
+		if blockID < 0 || blockID != math.Trunc(blockID) || blockID > math.MaxInt64 {
+			return NearBlockReference{}, ErrParseErr
+		}
+		return NearBlockReference{Height: uint64(blockID)}, nil
+	case string:
+		if !nearBlockHashRegex.MatchString(blockID) {
+			return NearBlockReference{}, ErrParseErr
+		}
+		return NearBlockReference{Hash: blockID}, nil
+	}
+
+	return NearBlockReference{}, ErrParseErr
+}
+
+// the block of heights is requested too, so the results have its hash
+func (n NearImpl) BuildGetterReq(id string, gt NearBlockReference) (*models.RPCReq, error) {
+	var params string
+	switch {
+	case gt.Finality != "":
+		params = fmt.Sprintf(`{"finality":"%s"}`, gt.Finality)
+	case gt.Hash != "":
+		params = fmt.Sprintf(`{"block_id":"%s"}`, gt.Hash)
+	default:
+		params = fmt.Sprintf(`{"block_id":%d}`, gt.Height)
+	}
+
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "block",
+		ID:      json.RawMessage(id),
+		Params:  json.RawMessage(params),
+	}, nil
+}
+
+func (n NearImpl) GetIndexOfIDHolder(gt NearBlockReference) (string, error) {
+	switch {
+	case gt.Finality != "":
+		return gt.Finality, nil
+	case gt.Hash != "":
+		return gt.Hash, nil
+	}
+
+	return strconv.FormatUint(gt.Height, 10), nil
+}
+
+func (n NearImpl) ExtractGetterReturnFromResponse(res *models.RPCResJSON) (blockRef, error) {
+	var block struct {
+		Header struct {
+			Height *int   `json:"height"`
+			Hash   string `json:"hash"`
+		} `json:"header"`
+	}
+	err := json.Unmarshal(res.Result, &block)
+	if err != nil || block.Header.Height == nil || block.Header.Hash == "" {
+		return blockRef{}, ErrInternalBlockWithoutHeader
+	}
+
+	return blockRef{Height: *block.Header.Height, Hash: block.Header.Hash}, nil
+}
+
+func (n NearImpl) ExtractGetterReturnFromType(gt NearBlockReference) (blockRef, error) {
+	return blockRef{}, ErrParseErr
+}
+
+func (n NearImpl) ExtractGetterStruct(res *models.RPCResJSON, gr blockRef, contextRes *models.RPCResJSON) (blockHeightResult, error) {
+	return blockHeightResult{
+		Data:        res.Result,
+		BlockHeight: gr.Height,
+		BlockHash:   gr.Hash,
+	}, nil
+}
+
+func (n NearImpl) ExtractGetterRangeStruct(res *models.RPCResJSON, grTo, grFrom blockRef) (noRangeSupported, error) {
+	return noRangeSupported{}, ErrParseErr
+}
+
+// finalities are rewritten to the resolved height, it is only done on block id params
+// since a block id can't be sent with a finality
+func (n NearImpl) PinGetter(param interface{}, gt NearBlockReference, gr blockRef) (interface{}, error) {
+	if gt.Finality == "" {
+		return param, nil // block ids already point to a single block
+	}
+
+	return gr.Height, nil
+}
+
+// a block id can't be sent with a finality, so the finality is removed when it is pinned to a block id
+func (n NearImpl) ReplacedKeys() []string {
+	return []string{"finality"}
+}
+
+// NEAR nodes have no websocket subscriptions
+func (n NearImpl) ExtractGetterReturnFromNotification(notification *models.RPCNotification) (blockRef, bool, error) {
+	return blockRef{}, false, nil
+}
+
+func NewNearMethodBuilder() CustomRpcMethodBuilder {
+	return NewGenericConv(NearImpl{})
+}
+
+func (n NearImpl) IsImmutableGetter(gt NearBlockReference, finalizedHeight uint64) bool {
+	if gt.Hash != "" {
+		return true
+	}
+
+	return gt.Finality == "" && gt.Height <= finalizedHeight
+}
+
+func (n NearImpl) BuildContextReq(id string) *models.RPCReq {
+	return nil // the block height and hash are the only data added to the results
+}
+
+func (n NearImpl) GetIndexOfContextReq() string {
+	return ""
+}
@@ -0,0 +1,65 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// nearBlockReferenceParam returns the block reference of the finality or block_id keys of named params,
+// or the block id of the position of positional params. A negative position means the positional params have no block id
+func nearBlockReferenceParam(req *models.RPCReq, pos int) ([]NearBlockReference, error) {
+	if !isNamedParams(req.Params) {
+		var p []interface{}
+		err := json.Unmarshal(req.Params, &p)
+		if err != nil {
+			return nil, err
+		}
+		if pos < 0 || len(p) <= pos {
+			return []NearBlockReference{NearImpl{}.GetDefaultGetter()}, nil
+		}
+
+		gt, err := NearImpl{}.ExtractGetter(p[pos])
+		if err != nil {
+			return nil, err
+		}
+		return []NearBlockReference{gt}, nil
+	}
+
+	var p map[string]interface{}
+	err := json.Unmarshal(req.Params, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	if finality, ok := p["finality"]; ok {
+		f, ok := finality.(string)
+		if !ok || !nearFinalities[f] {
+			return nil, ErrParseErr
+		}
+		return []NearBlockReference{{Finality: f}}, nil
+	}
+	if _, ok := p["sync_checkpoint"]; ok {
+		return nil, ErrParseErr // the height of sync checkpoints is not known before the req is served
+	}
+
+	gt, err := NearImpl{}.ExtractGetter(p["block_id"])
+	if err != nil {
+		return nil, err
+	}
+
+	return []NearBlockReference{gt}, nil
+}
+
+type nearCustomHandlersHolder struct{}
+
+// HandleBlockReference extracts the block reference of the methods that accept the block id as their first positional param, e.g. block
+func (nearCustomHandlersHolder) HandleBlockReference(req *models.RPCReq) ([]NearBlockReference, error) {
+	return nearBlockReferenceParam(req, 0)
+}
+
+// HandleNamedBlockReference extracts the block reference of the methods that only have it on named params, e.g. query
+// whose positional form is the legacy path query that is served at the optimistic block
+func (nearCustomHandlersHolder) HandleNamedBlockReference(req *models.RPCReq) ([]NearBlockReference, error) {
+	return nearBlockReferenceParam(req, -1)
+}
@@ -0,0 +1,362 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+const testNearBlockHash = "6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"
+
+func TestNear(t *testing.T) {
+	tests := []testCaseGenericConv{
+		{
+			name: "Query finality",
+			req: []*models.RPCReq{{
+				Method: "queryAndBlockHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"request_type":"view_account","finality":"final","account_id":"near"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "query",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"amount":"1","block_height":21}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"author":"node0","header":{"height":21,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"},"chunks":[]}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockHeightResult{
+					Data:        json.RawMessage(`{"amount":"1","block_height":21}`),
+					BlockHeight: 21,
+					BlockHash:   testNearBlockHash,
+				}.raw(),
+			},
+			contentsToRewrite: []string{"final"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Query block height",
+			req: []*models.RPCReq{{
+				Method: "queryAndBlockHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"request_type":"view_account","block_id":15,"account_id":"near"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "query",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"amount":"1","block_height":15}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"header":{"height":15,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockHeightResult{
+					Data:        json.RawMessage(`{"amount":"1","block_height":15}`),
+					BlockHeight: 15,
+					BlockHash:   testNearBlockHash,
+				}.raw(),
+			},
+			contentsToRewrite: []string{"15"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Legacy path query",
+			req: []*models.RPCReq{{
+				Method: "queryAndBlockHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["account/near",""]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "query",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"amount":"1"}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"header":{"height":22,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockHeightResult{
+					Data:        json.RawMessage(`{"amount":"1"}`),
+					BlockHeight: 22,
+					BlockHash:   testNearBlockHash,
+				}.raw(),
+			},
+			contentsToRewrite: []string{"optimistic"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Block positional hash",
+			req: []*models.RPCReq{{
+				Method: "blockAndBlockHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "block",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"header":{"height":18,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"header":{"height":18,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockHeightResult{
+					Data:        json.RawMessage(`{"header":{"height":18,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`),
+					BlockHeight: 18,
+					BlockHash:   testNearBlockHash,
+				}.raw(),
+			},
+			contentsToRewrite: []string{testNearBlockHash},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Gas price named block id",
+			req: []*models.RPCReq{{
+				Method: "gas_priceAndBlockHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"block_id":15}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "gas_price",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"gas_price":"100000000"}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"header":{"height":15,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockHeightResult{
+					Data:        json.RawMessage(`{"gas_price":"100000000"}`),
+					BlockHeight: 15,
+					BlockHash:   testNearBlockHash,
+				}.raw(),
+			},
+			contentsToRewrite: []string{"15"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Block response without header",
+			req: []*models.RPCReq{{
+				Method: "gas_priceAndBlockHeight",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[null]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "gas_price",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"gas_price":"100000000"}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"author":"node0"}`)}},
+			expectedErr:       ErrInternalBlockWithoutHeader,
+			contentsToRewrite: []string{"optimistic"},
+			idsToRewrite:      []string{"22"},
+		},
+	}
+
+	runTests(t, "../supported-chains/near.json", tests)
+}
+
+func TestNearPinGetterParams(t *testing.T) {
+	tests := []testCasePinGetterParams{
+		{
+			name: "Positional default",
+			req: []*models.RPCReq{{
+				Method: "gas_priceAndBlockHeight",
+				ID:     json.RawMessage("1"),
+				Params: json.RawMessage(`[null]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"optimistic": {Result: json.RawMessage(`{"header":{"height":21,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)},
+			},
+			expectedParams: []string{`[21]`},
+		},
+		{
+			name: "Named missing key",
+			req: []*models.RPCReq{{
+				Method: "gas_priceAndBlockHeight",
+				ID:     json.RawMessage("1"),
+				Params: json.RawMessage(`{}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"optimistic": {Result: json.RawMessage(`{"header":{"height":21,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)},
+			},
+			expectedParams: []string{`{"block_id":21}`},
+		},
+		{
+			name: "Named block id",
+			req: []*models.RPCReq{{
+				Method: "gas_priceAndBlockHeight",
+				ID:     json.RawMessage("1"),
+				Params: json.RawMessage(`{"block_id":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				testNearBlockHash: {Result: json.RawMessage(`{"header":{"height":18,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)},
+			},
+			expectedParams: []string{`{"block_id":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}`},
+		},
+		{
+			name: "Custom handler finality",
+			req: []*models.RPCReq{{
+				Method: "queryAndBlockHeight",
+				ID:     json.RawMessage("1"),
+				Params: json.RawMessage(`{"request_type":"view_account","finality":"final","account_id":"near"}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"final": {Result: json.RawMessage(`{"header":{"height":21,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)},
+			},
+			expectedParams: []string{`{"request_type":"view_account","account_id":"near","block_id":21}`},
+		},
+		{
+			name: "Custom handler changes finality",
+			req: []*models.RPCReq{{
+				Method: "EXPERIMENTAL_changesAndBlockHeight",
+				ID:     json.RawMessage("1"),
+				Params: json.RawMessage(`{"finality":"final","changes_type":"account_changes","account_ids":["near"]}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"final": {Result: json.RawMessage(`{"header":{"height":21,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)},
+			},
+			expectedParams: []string{`{"changes_type":"account_changes","account_ids":["near"],"block_id":21}`},
+		},
+		{
+			name: "Custom handler block finality",
+			req: []*models.RPCReq{{
+				Method: "blockAndBlockHeight",
+				ID:     json.RawMessage("1"),
+				Params: json.RawMessage(`{"finality":"final"}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"final": {Result: json.RawMessage(`{"header":{"height":21,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)},
+			},
+			expectedParams: []string{`{"block_id":21}`},
+		},
+		{
+			name: "Custom handler block positional default",
+			req: []*models.RPCReq{{
+				Method: "blockAndBlockHeight",
+				ID:     json.RawMessage("1"),
+				Params: json.RawMessage(`[]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"optimistic": {Result: json.RawMessage(`{"header":{"height":21,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)},
+			},
+			expectedParams: []string{`[21]`},
+		},
+		{
+			name: "Custom handler block id",
+			req: []*models.RPCReq{{
+				Method: "queryAndBlockHeight",
+				ID:     json.RawMessage("1"),
+				Params: json.RawMessage(`{"request_type":"view_account","block_id":18,"account_id":"near"}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"18": {Result: json.RawMessage(`{"header":{"height":18,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)},
+			},
+			expectedParams: []string{`{"request_type":"view_account","block_id":18,"account_id":"near"}`},
+		},
+		{
+			name: "Custom handler legacy query",
+			req: []*models.RPCReq{{
+				Method: "queryAndBlockHeight",
+				ID:     json.RawMessage("1"),
+				Params: json.RawMessage(`["account/near",""]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"optimistic": {Result: json.RawMessage(`{"header":{"height":21,"hash":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}}`)},
+			},
+			expectedErr: ErrMethodNotPinnable,
+		},
+	}
+
+	runPinTests(t, "../supported-chains/near.json", tests)
+}
+
+func TestNearFilterInvalidRPCReqs(t *testing.T) {
+	reqs := []*models.RPCReq{
+		{
+			Method: "queryAndBlockHeight",
+			ID:     json.RawMessage("1"),
+			Params: json.RawMessage(`{"request_type":"view_account","finality":"optimistic","account_id":"near"}`),
+		},
+		{
+			Method: "queryAndBlockHeight",
+			ID:     json.RawMessage("2"),
+			Params: json.RawMessage(`{"request_type":"view_account","finality":"latest","account_id":"near"}`),
+		},
+		{
+			Method: "queryAndBlockHeight",
+			ID:     json.RawMessage("3"),
+			Params: json.RawMessage(`{"request_type":"view_account","sync_checkpoint":"genesis","account_id":"near"}`),
+		},
+		{
+			Method: "gas_priceAndBlockHeight",
+			ID:     json.RawMessage("4"),
+			Params: json.RawMessage(`{"block_id":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}`),
+		},
+		{
+			Method: "gas_priceAndBlockHeight",
+			ID:     json.RawMessage("5"),
+			Params: json.RawMessage(`{"block_id":"0x15"}`),
+		},
+		{
+			Method: "blockAndBlockHeight",
+			ID:     json.RawMessage("6"),
+			Params: json.RawMessage(`[-1]`),
+		},
+	}
+
+	runFilterInvalidTests(t, "../supported-chains/near.json", reqs, []string{"1", "4"}, []string{"2", "3", "5", "6"})
+}
+
+func TestNearIsImmutableReq(t *testing.T) {
+	tests := []testCaseIsImmutableReq{
+		{
+			name:     "Height below finalized",
+			req:      &models.RPCReq{Method: "queryAndBlockHeight", Params: json.RawMessage(`{"request_type":"view_account","block_id":90,"account_id":"near"}`)},
+			expected: true,
+		},
+		{
+			name:     "Block hash",
+			req:      &models.RPCReq{Method: "block", Params: json.RawMessage(`{"block_id":"6RJAbD1nmV7buCvTjY1Ug8y1LqBXjrkmy2sBqNj1QqMW"}`)},
+			expected: true,
+		},
+		{
+			name:     "Named key below finalized",
+			req:      &models.RPCReq{Method: "gas_priceAndBlockHeight", Params: json.RawMessage(`{"block_id":90}`)},
+			expected: true,
+		},
+		{
+			name:     "Height above finalized",
+			req:      &models.RPCReq{Method: "queryAndBlockHeight", Params: json.RawMessage(`{"request_type":"view_account","block_id":110,"account_id":"near"}`)},
+			expected: false,
+		},
+		{
+			name:     "Final finality",
+			req:      &models.RPCReq{Method: "queryAndBlockHeight", Params: json.RawMessage(`{"request_type":"view_account","finality":"final","account_id":"near"}`)},
+			expected: false,
+		},
+	}
+
+	runIsImmutableTests(t, "../supported-chains/near.json", 100, tests)
+}
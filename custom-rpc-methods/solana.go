@@ -4,19 +4,32 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
-	"strings"
+	"time"
 
 	solanaRPC "github.com/gagliardetto/solana-go/rpc"
 	"github.com/stateless-solutions/compatibility-layer/models"
 )
 
 type context struct {
-	Slot int `json:"slot"`
+	APIVersion string `json:"apiVersion,omitempty"`
+	Slot       int    `json:"slot"`
 }
 
 type contextResult struct {
-	Value   interface{} `json:"value"`
-	Context context     `json:"context"`
+	Value   json.RawMessage `json:"value"`
+	Context context         `json:"context"`
+}
+
+func (r contextResult) envelope() ([]byte, []byte) {
+	context := []byte(`{"slot":` + strconv.Itoa(r.Context.Slot) + `}`)
+	if r.Context.APIVersion != "" {
+		context = []byte(`{"apiVersion":` + string(rawString(r.Context.APIVersion)) + `,"slot":` + strconv.Itoa(r.Context.Slot) + `}`)
+	}
+	return rawEnvelope("value", []string{"context"}, context)
+}
+
+func (r contextResult) resolvedBlock() string {
+	return strconv.Itoa(r.Context.Slot)
 }
 
 var (
@@ -25,8 +38,20 @@ var (
 		Message:       "slot response is not of an expected type",
 		HTTPErrorCode: 500,
 	}
+
+	solanaMethodNameToCustomHandler = make(map[string]func(*models.RPCReq) ([]SolanaGetter, error))
 )
 
+func init() {
+	SaveCustomHandlersToMap(solanaCustomHandlersHolder{}, solanaMethodNameToCustomHandler)
+}
+
+// SolanaGetter is the getter of the Solana methods, the commitment and the min context slot of the config param
+type SolanaGetter struct {
+	Commitment     solanaRPC.CommitmentType
+	MinContextSlot int // 0 if it was not set
+}
+
 type SolanaImpl struct{}
 
 func (s SolanaImpl) GetChainType() ChainType {
@@ -37,45 +62,102 @@ func (s SolanaImpl) SupportsRange() bool {
 	return false
 }
 
-func (s SolanaImpl) GetDefaultGetter() solanaRPC.CommitmentType {
-	return solanaRPC.CommitmentFinalized
+func (s SolanaImpl) GetHealthCheckReq() *models.RPCReq {
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "getSlot",
+		ID:      json.RawMessage("1"),
+	}
 }
 
-func (s SolanaImpl) GetDefaultGetterRange() []solanaRPC.CommitmentType {
-	return nil
+func (s SolanaImpl) ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error) {
+	slot, err := s.ExtractGetterReturnFromResponse(res)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(slot), nil
+}
+
+func (s SolanaImpl) GetBlockTime() time.Duration {
+	return 400 * time.Millisecond
 }
 
-func (s SolanaImpl) GetCustomHandlerMap() map[string]func(*models.RPCReq) ([]solanaRPC.CommitmentType, error) {
+func (s SolanaImpl) GetFinalizedHeightReq() *models.RPCReq {
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "getSlot",
+		ID:      json.RawMessage("1"),
+		Params:  json.RawMessage(`[{"commitment":"finalized"}]`),
+	}
+}
+
+func (s SolanaImpl) ExtractFinalizedHeight(res *models.RPCResJSON) (uint64, error) {
+	return s.ExtractHeightFromHealthCheck(res)
+}
+
+func (s SolanaImpl) GetDefaultGetter() SolanaGetter {
+	return SolanaGetter{Commitment: solanaRPC.CommitmentFinalized}
+}
+
+func (s SolanaImpl) GetDefaultGetterRange() []SolanaGetter {
 	return nil
 }
 
-func (s SolanaImpl) FromGetterTypeToHolder(gth GetterTypesHolder) solanaRPC.CommitmentType {
+func (s SolanaImpl) GetCustomHandlerMap() map[string]func(*models.RPCReq) ([]SolanaGetter, error) {
+	return solanaMethodNameToCustomHandler
+}
+
+// GetterPositions returns the position of the config of getBlocks, it depends on the end slot being sent
+func (s SolanaImpl) GetterPositions(customHandler string, req *models.RPCReq) ([]int, bool) {
+	if customHandler != "HandleGetBlocks" {
+		return nil, false
+	}
+
+	p, err := solanaParams(req)
+	if err != nil {
+		return nil, false
+	}
+
+	return []int{getBlocksConfigPosition(p)}, true
+}
+
+func (s SolanaImpl) FromGetterTypeToHolder(gth GetterTypesHolder) SolanaGetter {
 	return gth.Solana
 }
 
-func (s SolanaImpl) FromHolderToGetterType(gt solanaRPC.CommitmentType) GetterTypesHolder {
+func (s SolanaImpl) FromHolderToGetterType(gt SolanaGetter) GetterTypesHolder {
 	return GetterTypesHolder{
 		Solana: gt,
 	}
 }
 
-func (s SolanaImpl) ExtractGetter(param interface{}) (solanaRPC.CommitmentType, error) {
+func (s SolanaImpl) ExtractGetter(param interface{}) (SolanaGetter, error) {
 	cMap, ok := param.(map[string]interface{})
 	if !ok {
 		return s.GetDefaultGetter(), nil // params can be a string in some cases, in which default commitment should be used
 	}
 
+	gt := s.GetDefaultGetter()
+	if _, ok := cMap["minContextSlot"]; ok {
+		gt.MinContextSlot, ok = minContextSlotOf(cMap)
+		if !ok {
+			return SolanaGetter{}, ErrParseErr
+		}
+	}
+
 	cTypeRaw, ok := cMap["commitment"]
 	if !ok {
-		return s.GetDefaultGetter(), nil // this just means no commitment was input and should return default
+		return gt, nil // this just means no commitment was input and should return default
 	}
 
 	cTypeString, ok := cTypeRaw.(string)
 	if !ok {
-		return "", ErrParseErr
+		return SolanaGetter{}, ErrParseErr
 	}
+	gt.Commitment = solanaRPC.CommitmentType(cTypeString)
 
-	return solanaRPC.CommitmentType(cTypeString), nil
+	return gt, nil
 }
 
 var validCommitmentTypes = map[solanaRPC.CommitmentType]bool{
@@ -84,49 +166,57 @@ var validCommitmentTypes = map[solanaRPC.CommitmentType]bool{
 	solanaRPC.CommitmentProcessed: true,
 }
 
-func (s SolanaImpl) BuildGetterReq(id string, gt solanaRPC.CommitmentType) (*models.RPCReq, error) {
-	if !validCommitmentTypes[gt] {
+// the min context slot is sent with the getter req so the node fails it
+// with the min context slot not reached error as it would fail the original req
+func (s SolanaImpl) BuildGetterReq(id string, gt SolanaGetter) (*models.RPCReq, error) {
+	if !validCommitmentTypes[gt.Commitment] {
 		return nil, ErrParseErr
 	}
 
+	params := fmt.Sprintf(`[{"commitment":"%s"}]`, gt.Commitment)
+	if gt.MinContextSlot > 0 {
+		params = fmt.Sprintf(`[{"commitment":"%s","minContextSlot":%d}]`, gt.Commitment, gt.MinContextSlot)
+	}
+
 	return &models.RPCReq{
 		JSONRPC: "2.0",
 		Method:  "getSlot",
 		ID:      json.RawMessage(id),
-		Params:  json.RawMessage(fmt.Sprintf(`[{"commitment":"%s"}]`, gt)),
+		Params:  json.RawMessage(params),
 	}, nil
 }
 
-func (s SolanaImpl) GetIndexOfIDHolder(gt solanaRPC.CommitmentType) (string, error) {
-	if !validCommitmentTypes[gt] {
+func (s SolanaImpl) GetIndexOfIDHolder(gt SolanaGetter) (string, error) {
+	if !validCommitmentTypes[gt.Commitment] {
 		return "", ErrParseErr
 	}
+	if gt.MinContextSlot > 0 {
+		return fmt.Sprintf("%s:%d", gt.Commitment, gt.MinContextSlot), nil
+	}
 
-	return string(gt), nil
+	return string(gt.Commitment), nil
 }
 
 func (s SolanaImpl) ExtractGetterReturnFromResponse(res *models.RPCResJSON) (int, error) {
-	switch v := res.Result.(type) {
-	case float64:
-		floatStr := strconv.FormatFloat(v, 'f', -1, 64)
-		floatStrWithoutDot := strings.Replace(floatStr, ".", "", 1)
-		return strconv.Atoi(floatStrWithoutDot)
-	case int:
-		return v, nil
-	default:
+	// the slot is parsed from its raw digits so it is exact
+	slot, err := strconv.Atoi(string(res.Result))
+	if err != nil {
 		return 0, ErrInternalSlotResultNotExpectedType
 	}
+
+	return slot, nil
 }
 
-func (s SolanaImpl) ExtractGetterReturnFromType(gt solanaRPC.CommitmentType) (int, error) {
+func (s SolanaImpl) ExtractGetterReturnFromType(gt SolanaGetter) (int, error) {
 	return 0, ErrParseErr
 }
 
-func (s SolanaImpl) ExtractGetterStruct(res *models.RPCResJSON, gr int) (contextResult, error) {
+func (s SolanaImpl) ExtractGetterStruct(res *models.RPCResJSON, gr int, contextRes *models.RPCResJSON) (contextResult, error) {
 	return contextResult{
 		Value: res.Result,
 		Context: context{
-			Slot: gr,
+			APIVersion: apiVersionOf(contextRes),
+			Slot:       gr,
 		},
 	}, nil
 }
@@ -135,6 +225,99 @@ func (s SolanaImpl) ExtractGetterRangeStruct(res *models.RPCResJSON, grTo, grFro
 	return noRangeSupported{}, ErrParseErr
 }
 
+// slots can't be requested directly so the min context slot is used
+// to make sure the node serves the request at least at the resolved slot
+func (s SolanaImpl) PinGetter(param interface{}, gt SolanaGetter, gr int) (interface{}, error) {
+	if param == nil {
+		return map[string]interface{}{
+			"commitment":     gt.Commitment,
+			"minContextSlot": gr,
+		}, nil
+	}
+
+	cMap, ok := param.(map[string]interface{})
+	if !ok {
+		return param, nil // string params can't hold the min context slot
+	}
+	if slot, ok := minContextSlotOf(cMap); ok && slot > gr {
+		return cMap, nil // a higher min context slot sent by the client is kept
+	}
+	cMap["minContextSlot"] = gr
+
+	return cMap, nil
+}
+
+// most notifications already have the context on their result
+// the ones that don't have the slot as part of the result
+func (s SolanaImpl) ExtractGetterReturnFromNotification(notification *models.RPCNotification) (int, bool, error) {
+	result := notification.Params.Result
+	if len(result) == 0 {
+		return 0, false, nil
+	}
+
+	switch {
+	case result[0] == '{':
+		var resMap map[string]json.RawMessage
+		err := json.Unmarshal(result, &resMap)
+		if err != nil {
+			return 0, false, nil
+		}
+		if _, ok := resMap["context"]; ok {
+			return 0, false, nil
+		}
+		slot, ok := resMap["slot"]
+		if !ok {
+			return 0, false, nil
+		}
+		gr, err := s.ExtractGetterReturnFromResponse(&models.RPCResJSON{Result: slot})
+		if err != nil {
+			return 0, false, err
+		}
+		return gr, true, nil
+	case result[0] == '-' || (result[0] >= '0' && result[0] <= '9'):
+		gr, err := s.ExtractGetterReturnFromResponse(&models.RPCResJSON{Result: result}) // root notifications are just the slot
+		if err != nil {
+			return 0, false, err
+		}
+		return gr, true, nil
+	default:
+		return 0, false, nil
+	}
+}
+
 func NewSolanaMethodBuilder() CustomRpcMethodBuilder {
 	return NewGenericConv(SolanaImpl{})
 }
+
+func (s SolanaImpl) IsImmutableGetter(gt SolanaGetter, finalizedHeight uint64) bool {
+	return false // commitments always point to the tip of the chain
+}
+
+// the api version of the node is added to the context as the nodes do on their own context responses
+func (s SolanaImpl) BuildContextReq(id string) *models.RPCReq {
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "getVersion",
+		ID:      json.RawMessage(id),
+	}
+}
+
+func (s SolanaImpl) GetIndexOfContextReq() string {
+	return "getVersion"
+}
+
+// apiVersionOf returns the version of the node of the get version response, empty if it is unknown
+func apiVersionOf(res *models.RPCResJSON) string {
+	if res == nil || res.Error != nil {
+		return ""
+	}
+
+	var version map[string]json.RawMessage
+	err := json.Unmarshal(res.Result, &version)
+	if err != nil {
+		return ""
+	}
+	apiVersion, _ := stringOf(version["solana-core"])
+
+	return apiVersion
+}
@@ -0,0 +1,79 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+func solanaParams(req *models.RPCReq) ([]interface{}, error) {
+	var p []interface{}
+	err := json.Unmarshal(req.Params, &p)
+
+	return p, err
+}
+
+// solanaConfigParam returns the config param on the position, nil if no config was sent
+func solanaConfigParam(p []interface{}, pos int) map[string]interface{} {
+	if len(p) <= pos {
+		return nil
+	}
+
+	config, _ := p[pos].(map[string]interface{})
+
+	return config
+}
+
+// getBlocksConfigPosition returns the position of the config of getBlocks, the end slot is optional
+// so the config is the second param if it wasn't sent and the third one otherwise
+func getBlocksConfigPosition(p []interface{}) int {
+	if len(p) > 1 {
+		_, ok := p[1].(map[string]interface{})
+		if !ok {
+			return 2
+		}
+	}
+
+	return 1
+}
+
+type solanaCustomHandlersHolder struct{}
+
+// HandleGetBlocks extracts the getter of getBlocks from the config found by getBlocksConfigPosition
+func (solanaCustomHandlersHolder) HandleGetBlocks(req *models.RPCReq) ([]SolanaGetter, error) {
+	p, err := solanaParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	config := solanaConfigParam(p, getBlocksConfigPosition(p))
+	if config == nil {
+		return []SolanaGetter{SolanaImpl{}.GetDefaultGetter()}, nil
+	}
+
+	gt, err := SolanaImpl{}.ExtractGetter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return []SolanaGetter{gt}, nil
+}
+
+// minContextSlotOf returns the min context slot of the config, false if it is not set or is not a slot
+func minContextSlotOf(config map[string]interface{}) (int, bool) {
+	switch slot := config["minContextSlot"].(type) {
+	case float64:
+		if slot < 0 || slot != math.Trunc(slot) || slot > math.MaxInt64 {
+			return 0, false
+		}
+		return int(slot), true
+	case int:
+		return slot, slot >= 0
+	case json.Number:
+		n, err := slot.Int64()
+		return int(n), err == nil && n >= 0
+	}
+
+	return 0, false
+}
@@ -20,20 +20,20 @@ func TestSolana(t *testing.T) {
 				Method: "getBlockHeight",
 				ID:     json.RawMessage("21"),
 			},
-			expectedReqLength: 2,
+			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: 211,
+				Result: json.RawMessage("211"),
 			}, {ID: json.RawMessage("22"),
-				Result: 2.1}},
+				Result: json.RawMessage("21")}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: contextResult{
-					Value: 211,
+					Value: json.RawMessage("211"),
 					Context: context{
 						Slot: 21,
 					},
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"processed"},
 			idsToRewrite:      []string{"22"},
@@ -52,11 +52,11 @@ func TestSolana(t *testing.T) {
 			expectedReqLength: 1,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: 1,
+				Result: json.RawMessage("1"),
 			}},
 			expectedRes: &models.RPCResJSON{
 				ID:     json.RawMessage("21"),
-				Result: 1,
+				Result: json.RawMessage("1"),
 			},
 		},
 		{
@@ -70,12 +70,12 @@ func TestSolana(t *testing.T) {
 				Method: "getBlockHeight",
 				ID:     json.RawMessage("21"),
 			},
-			expectedReqLength: 2,
+			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
-				Result: "a"}},
+				Result: json.RawMessage(`"a"`)}},
 			contentsToRewrite: []string{"processed"},
 			idsToRewrite:      []string{"22"},
 			expectedErr:       ErrInternalSlotResultNotExpectedType,
@@ -91,7 +91,7 @@ func TestSolana(t *testing.T) {
 				Method: "getBlockHeight",
 				ID:     json.RawMessage("21"),
 			},
-			expectedReqLength: 2,
+			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID: json.RawMessage("21"),
 				Error: &models.RPCErr{
@@ -99,7 +99,7 @@ func TestSolana(t *testing.T) {
 					Message: "block height not good",
 				},
 			}, {ID: json.RawMessage("22"),
-				Result: 211}},
+				Result: json.RawMessage("211")}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Error: &models.RPCErr{
@@ -121,10 +121,10 @@ func TestSolana(t *testing.T) {
 				Method: "getBlockHeight",
 				ID:     json.RawMessage("21"),
 			},
-			expectedReqLength: 2,
+			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: "aaa",
+				Result: json.RawMessage(`"aaa"`),
 			}, {ID: json.RawMessage("22"),
 				Error: &models.RPCErr{
 					Code:    21,
@@ -150,20 +150,20 @@ func TestSolana(t *testing.T) {
 				Method: "getBlockHeight",
 				ID:     json.RawMessage("21"),
 			},
-			expectedReqLength: 2,
+			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: 211,
+				Result: json.RawMessage("211"),
 			}, {ID: json.RawMessage("22"),
-				Result: 2.1}},
+				Result: json.RawMessage("21")}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: contextResult{
-					Value: 211,
+					Value: json.RawMessage("211"),
 					Context: context{
 						Slot: 21,
 					},
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"finalized"},
 			idsToRewrite:      []string{"22"},
@@ -179,20 +179,20 @@ func TestSolana(t *testing.T) {
 				Method: "getBlockHeight",
 				ID:     json.RawMessage("21"),
 			},
-			expectedReqLength: 2,
+			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: 211,
+				Result: json.RawMessage("211"),
 			}, {ID: json.RawMessage("22"),
-				Result: 2.1}},
+				Result: json.RawMessage("21")}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: contextResult{
-					Value: 211,
+					Value: json.RawMessage("211"),
 					Context: context{
 						Slot: 21,
 					},
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"finalized"},
 			idsToRewrite:      []string{"22"},
@@ -208,20 +208,20 @@ func TestSolana(t *testing.T) {
 				Method: "getBlock",
 				ID:     json.RawMessage("21"),
 			},
-			expectedReqLength: 2,
+			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: 211,
+				Result: json.RawMessage("211"),
 			}, {ID: json.RawMessage("22"),
-				Result: 2.1}},
+				Result: json.RawMessage("21")}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: contextResult{
-					Value: 211,
+					Value: json.RawMessage("211"),
 					Context: context{
 						Slot: 21,
 					},
-				},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"finalized"},
 			idsToRewrite:      []string{"22"},
@@ -237,25 +237,494 @@ func TestSolana(t *testing.T) {
 				Method: "getBlockHeight",
 				ID:     json.RawMessage("21"),
 			},
-			expectedReqLength: 2,
+			expectedReqLength: 3,
 			res: []*models.RPCResJSON{{
 				ID:     json.RawMessage("21"),
-				Result: 211,
+				Result: json.RawMessage("211"),
 			}, {ID: json.RawMessage("22"),
-				Result: 2.1}},
+				Result: json.RawMessage("21")}},
 			expectedRes: &models.RPCResJSON{
 				ID: json.RawMessage("21"),
 				Result: contextResult{
-					Value: 211,
+					Value: json.RawMessage("211"),
 					Context: context{
 						Slot: 21,
 					},
-				},
+				}.raw(),
+			},
+			contentsToRewrite: []string{"finalized"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Get blocks without end slot and commitment",
+			req: []*models.RPCReq{{
+				Method: "getBlocksAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[21,{"commitment":"confirmed"}]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getBlocks",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 3,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage("[21]"),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage("21")}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: contextResult{
+					Value: json.RawMessage("[21]"),
+					Context: context{
+						Slot: 21,
+					},
+				}.raw(),
+			},
+			contentsToRewrite: []string{"confirmed"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Get blocks with end slot and commitment",
+			req: []*models.RPCReq{{
+				Method: "getBlocksAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[21,30,{"commitment":"processed"}]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getBlocks",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 3,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage("[21]"),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage("21")}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: contextResult{
+					Value: json.RawMessage("[21]"),
+					Context: context{
+						Slot: 21,
+					},
+				}.raw(),
+			},
+			contentsToRewrite: []string{"processed"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Get blocks without config",
+			req: []*models.RPCReq{{
+				Method: "getBlocksAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[21,30]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getBlocks",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 3,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage("[21]"),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage("21")}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: contextResult{
+					Value: json.RawMessage("[21]"),
+					Context: context{
+						Slot: 21,
+					},
+				}.raw(),
 			},
 			contentsToRewrite: []string{"finalized"},
 			idsToRewrite:      []string{"22"},
 		},
+		{
+			name: "Get signatures with min context slot",
+			req: []*models.RPCReq{{
+				Method: "getSignaturesForAddressAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["Vote111111111111111111111111111111111111111",{"commitment":"confirmed","minContextSlot":20}]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getSignaturesForAddress",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 3,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage("[21]"),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage("21")}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: contextResult{
+					Value: json.RawMessage("[21]"),
+					Context: context{
+						Slot: 21,
+					},
+				}.raw(),
+			},
+			contentsToRewrite: []string{"confirmed:20"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Context with api version",
+			req: []*models.RPCReq{{
+				Method: "getBlockHeightAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"commitment":"processed"}]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getBlockHeight",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 3,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage("211"),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage("21"),
+			}, {ID: json.RawMessage("23"),
+				Result: json.RawMessage(`{"feature-set":3746964731,"solana-core":"2.0.15"}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"value":211,"context":{"apiVersion":"2.0.15","slot":21}}`),
+			},
+			contentsToRewrite: []string{"processed", "getVersion"},
+			idsToRewrite:      []string{"22", "23"},
+		},
+		{
+			name: "Context with api version error",
+			req: []*models.RPCReq{{
+				Method: "getBlockHeightAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"commitment":"processed"}]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getBlockHeight",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 3,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage("211"),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage("21"),
+			}, {ID: json.RawMessage("23"),
+				Error: &models.RPCErr{
+					Code:    -32601,
+					Message: "Method not found",
+				}}},
+			expectedRes: &models.RPCResJSON{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"value":211,"context":{"slot":21}}`),
+			},
+			contentsToRewrite: []string{"processed", "getVersion"},
+			idsToRewrite:      []string{"22", "23"},
+		},
+		{
+			name: "Min context slot not reached",
+			req: []*models.RPCReq{{
+				Method: "getBlockHeightAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"commitment":"processed","minContextSlot":30}]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "getBlockHeight",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 3,
+			res: []*models.RPCResJSON{{
+				ID: json.RawMessage("21"),
+				Error: &models.RPCErr{
+					Code:    -32016,
+					Message: "Minimum context slot has not been reached",
+					Data:    json.RawMessage(`{"contextSlot":21}`),
+				},
+			}, {ID: json.RawMessage("22"),
+				Error: &models.RPCErr{
+					Code:    -32016,
+					Message: "Minimum context slot has not been reached",
+					Data:    json.RawMessage(`{"contextSlot":21}`),
+				}}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Error: &models.RPCErr{
+					Code:    -32016,
+					Message: "Minimum context slot has not been reached",
+					Data:    json.RawMessage(`{"contextSlot":21}`),
+				},
+			},
+			contentsToRewrite: []string{"processed:30"},
+			idsToRewrite:      []string{"22"},
+		},
 	}
 
 	runTests(t, "../supported-chains/solana.json", tests)
 }
+
+func TestSolanaPinGetterParams(t *testing.T) {
+	tests := []testCasePinGetterParams{
+		{
+			name: "Block height processed tag",
+			req: []*models.RPCReq{{
+				Method: "getBlockHeightAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"commitment":"processed"}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"processed":  {Result: json.RawMessage("21")},
+				"getVersion": {Result: json.RawMessage(`{"solana-core":"2.0.15"}`)},
+			},
+			expectedParams: []string{`[{"commitment":"processed","minContextSlot":21}]`},
+		},
+		{
+			name: "Block height and no param",
+			req: []*models.RPCReq{{
+				Method: "getBlockHeightAndContext",
+				ID:     json.RawMessage("21"),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"finalized":  {Result: json.RawMessage("21")},
+				"getVersion": {Result: json.RawMessage(`{"solana-core":"2.0.15"}`)},
+			},
+			expectedParams: []string{`[{"commitment":"finalized","minContextSlot":21}]`},
+		},
+		{
+			name: "Get block and string param",
+			req: []*models.RPCReq{{
+				Method: "getBlockAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[430,"json"]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"finalized":  {Result: json.RawMessage("21")},
+				"getVersion": {Result: json.RawMessage(`{"solana-core":"2.0.15"}`)},
+			},
+			expectedParams: []string{`[430,"json"]`},
+		},
+		{
+			name: "Get signatures and lower min context slot",
+			req: []*models.RPCReq{{
+				Method: "getSignaturesForAddressAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["Vote111111111111111111111111111111111111111",{"minContextSlot":20}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"finalized:20": {Result: json.RawMessage("21")},
+				"getVersion":   {Result: json.RawMessage(`{"solana-core":"2.0.15"}`)},
+			},
+			expectedParams: []string{`["Vote111111111111111111111111111111111111111",{"minContextSlot":21}]`},
+		},
+		{
+			name: "Get signatures and higher min context slot",
+			req: []*models.RPCReq{{
+				Method: "getSignaturesForAddressAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["Vote111111111111111111111111111111111111111",{"minContextSlot":30}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"finalized:30": {Result: json.RawMessage("21")},
+				"getVersion":   {Result: json.RawMessage(`{"solana-core":"2.0.15"}`)},
+			},
+			expectedParams: []string{`["Vote111111111111111111111111111111111111111",{"minContextSlot":30}]`},
+		},
+		{
+			name: "Get blocks and start slot",
+			req: []*models.RPCReq{{
+				Method: "getBlocksAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[21]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"finalized":  {Result: json.RawMessage("30")},
+				"getVersion": {Result: json.RawMessage(`{"solana-core":"2.0.15"}`)},
+			},
+			expectedParams: []string{`[21,{"commitment":"finalized","minContextSlot":30}]`},
+		},
+		{
+			name: "Get blocks and config",
+			req: []*models.RPCReq{{
+				Method: "getBlocksAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[21,{"commitment":"confirmed"}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"confirmed":  {Result: json.RawMessage("30")},
+				"getVersion": {Result: json.RawMessage(`{"solana-core":"2.0.15"}`)},
+			},
+			expectedParams: []string{`[21,{"commitment":"confirmed","minContextSlot":30}]`},
+		},
+		{
+			name: "Get blocks and end slot",
+			req: []*models.RPCReq{{
+				Method: "getBlocksAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[21,25]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"finalized":  {Result: json.RawMessage("30")},
+				"getVersion": {Result: json.RawMessage(`{"solana-core":"2.0.15"}`)},
+			},
+			expectedParams: []string{`[21,25,{"commitment":"finalized","minContextSlot":30}]`},
+		},
+		{
+			name: "Get blocks, end slot and config",
+			req: []*models.RPCReq{{
+				Method: "getBlocksAndContext",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[21,25,{"commitment":"confirmed"}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"confirmed":  {Result: json.RawMessage("30")},
+				"getVersion": {Result: json.RawMessage(`{"solana-core":"2.0.15"}`)},
+			},
+			expectedParams: []string{`[21,25,{"commitment":"confirmed","minContextSlot":30}]`},
+		},
+	}
+
+	runPinTests(t, "../supported-chains/solana.json", tests)
+}
+
+func TestSolanaChangeSubscriptionNotification(t *testing.T) {
+	tests := []struct {
+		name           string
+		result         string
+		expectedResult string
+	}{
+		{
+			name:           "Slot notification",
+			result:         `{"slot":21,"parent":20,"root":10}`,
+			expectedResult: `{"value":{"slot":21,"parent":20,"root":10},"context":{"slot":21}}`,
+		},
+		{
+			name:           "Root notification",
+			result:         `21`,
+			expectedResult: `{"value":21,"context":{"slot":21}}`,
+		},
+		{
+			name:           "Root notification above 2^53",
+			result:         `9007199254740993`,
+			expectedResult: `{"value":9007199254740993,"context":{"slot":9007199254740993}}`,
+		},
+		{
+			name:           "Account notification already with context",
+			result:         `{"context":{"slot":21},"value":null}`,
+			expectedResult: `{"context":{"slot":21},"value":null}`,
+		},
+	}
+
+	ch := NewCustomMethodHolder(false, "../supported-chains/solana.json")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notification := &models.RPCNotification{
+				Method: "slotNotification",
+				Params: models.RPCNotificationParams{Result: json.RawMessage(tt.result)},
+			}
+
+			err := ch.ChangeSubscriptionNotification(ChainTypeSolana, notification)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+
+			if string(notification.Params.Result) != tt.expectedResult {
+				t.Errorf("Test case %s: Expected result %v, got %v", tt.name, tt.expectedResult, notification.Params.Result)
+			}
+		})
+	}
+}
+
+func TestSolanaFilterInvalidRPCReqs(t *testing.T) {
+	reqs := []*models.RPCReq{
+		{
+			Method: "getSignaturesForAddressAndContext",
+			ID:     json.RawMessage("1"),
+			Params: json.RawMessage(`["Vote111111111111111111111111111111111111111",{"minContextSlot":20}]`),
+		},
+		{
+			Method: "getSignaturesForAddressAndContext",
+			ID:     json.RawMessage("2"),
+			Params: json.RawMessage(`["Vote111111111111111111111111111111111111111",{"minContextSlot":"20"}]`),
+		},
+		{
+			Method: "getSignaturesForAddressAndContext",
+			ID:     json.RawMessage("3"),
+			Params: json.RawMessage(`["Vote111111111111111111111111111111111111111",{"minContextSlot":-1}]`),
+		},
+		{
+			Method: "getBlocksAndContext",
+			ID:     json.RawMessage("4"),
+			Params: json.RawMessage(`[21,{"commitment":1}]`),
+		},
+		{
+			Method: "getBlocksAndContext",
+			ID:     json.RawMessage("5"),
+			Params: json.RawMessage(`[21,30,{"commitment":"confirmed"}]`),
+		},
+	}
+
+	runFilterInvalidTests(t, "../supported-chains/solana.json", reqs, []string{"1", "5"}, []string{"2", "3", "4"})
+}
+
+func TestSolanaBuildGetterReq(t *testing.T) {
+	tests := []struct {
+		name           string
+		param          interface{}
+		expectedParams string
+		expectedIndex  string
+	}{
+		{
+			name:           "Commitment",
+			param:          map[string]interface{}{"commitment": "confirmed"},
+			expectedParams: `[{"commitment":"confirmed"}]`,
+			expectedIndex:  "confirmed",
+		},
+		{
+			name:           "Commitment and min context slot",
+			param:          map[string]interface{}{"commitment": "confirmed", "minContextSlot": float64(300)},
+			expectedParams: `[{"commitment":"confirmed","minContextSlot":300}]`,
+			expectedIndex:  "confirmed:300",
+		},
+		{
+			name:           "Min context slot",
+			param:          map[string]interface{}{"minContextSlot": float64(300)},
+			expectedParams: `[{"commitment":"finalized","minContextSlot":300}]`,
+			expectedIndex:  "finalized:300",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gt, err := SolanaImpl{}.ExtractGetter(tt.param)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+
+			req, err := SolanaImpl{}.BuildGetterReq("1", gt)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+			if string(req.Params) != tt.expectedParams {
+				t.Errorf("Test case %s: Expected params %s, got %s", tt.name, tt.expectedParams, req.Params)
+			}
+
+			index, err := SolanaImpl{}.GetIndexOfIDHolder(gt)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+			if index != tt.expectedIndex {
+				t.Errorf("Test case %s: Expected index %s, got %s", tt.name, tt.expectedIndex, index)
+			}
+		})
+	}
+}
+
+// raw returns the JSON of the result as the buffered responses wrap its value
+func (r contextResult) raw() json.RawMessage {
+	return splice(r, r.Value)
+}
@@ -0,0 +1,281 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// StarknetBlockID is the getter of the Starknet methods, the block_id param that is a tag,
+// a block number or a block hash. Only one of them is set, a block id without tag or hash is a block number
+type StarknetBlockID struct {
+	Tag    string
+	Number uint64
+	Hash   string
+}
+
+type starknetBlockResult struct {
+	Data        json.RawMessage `json:"data"`
+	BlockNumber int             `json:"blockNumber"`
+}
+
+type starknetBlockRangeResult struct {
+	Data          json.RawMessage `json:"data"`
+	StartingBlock int             `json:"startingBlock"`
+	EndingBlock   int             `json:"endingBlock"`
+}
+
+// block numbers are numbers as on the Starknet responses
+func (r starknetBlockResult) envelope() ([]byte, []byte) {
+	return rawEnvelope("data", []string{"blockNumber"}, json.RawMessage(strconv.Itoa(r.BlockNumber)))
+}
+
+func (r starknetBlockResult) resolvedBlock() string {
+	return strconv.Itoa(r.BlockNumber)
+}
+
+func (r starknetBlockRangeResult) envelope() ([]byte, []byte) {
+	return rawEnvelope("data", []string{"startingBlock", "endingBlock"}, json.RawMessage(strconv.Itoa(r.StartingBlock)), json.RawMessage(strconv.Itoa(r.EndingBlock)))
+}
+
+// the ending block is the one the whole range is consistent with
+func (r starknetBlockRangeResult) resolvedBlock() string {
+	return strconv.Itoa(r.EndingBlock)
+}
+
+var (
+	ErrInternalBlockWithoutBlockNumber = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 31,
+		Message:       "block response does not have the block number",
+		HTTPErrorCode: 500,
+	}
+
+	ErrInternalBlockNumberNotNumber = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 32,
+		Message:       "block number response is not a number",
+		HTTPErrorCode: 500,
+	}
+
+	starknetBlockHashRegex = regexp.MustCompile(`^0x[0-9a-fA-F]{1,64}$`)
+
+	// pending is replaced by pre_confirmed on the newer versions of the spec, both are accepted
+	starknetBlockTags = map[string]bool{
+		"latest":        true,
+		"pending":       true,
+		"pre_confirmed": true,
+		"l1_accepted":   true,
+	}
+
+	starknetMethodNameToCustomHandler = make(map[string]func(*models.RPCReq) ([]StarknetBlockID, error))
+)
+
+func init() {
+	SaveCustomHandlersToMap(starknetCustomHandlersHolder{}, starknetMethodNameToCustomHandler)
+}
+
+type StarknetImpl struct{}
+
+func (s StarknetImpl) GetChainType() ChainType {
+	return ChainTypeStarknet
+}
+
+func (s StarknetImpl) SupportsRange() bool {
+	return true
+}
+
+func buildBlockNumberReq(id string) *models.RPCReq {
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "starknet_blockNumber",
+		ID:      json.RawMessage(id),
+	}
+}
+
+func (s StarknetImpl) GetHealthCheckReq() *models.RPCReq {
+	return buildBlockNumberReq("1")
+}
+
+func (s StarknetImpl) ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error) {
+	height, err := strconv.ParseUint(string(res.Result), 10, 64)
+	if err != nil {
+		return 0, ErrInternalBlockNumberNotNumber
+	}
+
+	return height, nil
+}
+
+func (s StarknetImpl) GetBlockTime() time.Duration {
+	return 6 * time.Second
+}
+
+// blocks accepted on L2 are not reverted by the sequencer, so the latest block is treated as final
+// the l1_accepted tag is not used since nodes before v0.9 of the spec don't support it
+func (s StarknetImpl) GetFinalizedHeightReq() *models.RPCReq {
+	return buildBlockNumberReq("1")
+}
+
+func (s StarknetImpl) ExtractFinalizedHeight(res *models.RPCResJSON) (uint64, error) {
+	return s.ExtractHeightFromHealthCheck(res)
+}
+
+func (s StarknetImpl) GetDefaultGetter() StarknetBlockID {
+	return StarknetBlockID{Tag: "latest"}
+}
+
+func (s StarknetImpl) GetDefaultGetterRange() []StarknetBlockID {
+	return []StarknetBlockID{{Number: 0}, {Tag: "latest"}}
+}
+
+func (s StarknetImpl) GetCustomHandlerMap() map[string]func(*models.RPCReq) ([]StarknetBlockID, error) {
+	return starknetMethodNameToCustomHandler
+}
+
+func (s StarknetImpl) FromGetterTypeToHolder(gth GetterTypesHolder) StarknetBlockID {
+	return gth.Starknet
+}
+
+func (s StarknetImpl) FromHolderToGetterType(gt StarknetBlockID) GetterTypesHolder {
+	return GetterTypesHolder{
+		Starknet: gt,
+	}
+}
+
+// block ids are a tag or an object with either the block number or the block hash
+func (s StarknetImpl) ExtractGetter(param interface{}) (StarknetBlockID, error) {
+	switch blockID := param.(type) {
+	case nil:
+		return s.GetDefaultGetter(), nil
+	case string:
+		if !starknetBlockTags[blockID] {
+			return StarknetBlockID{}, ErrParseErr
+		}
+		return StarknetBlockID{Tag: blockID}, nil
+	case map[string]interface{}:
+		if len(blockID) != 1 {
+			return StarknetBlockID{}, ErrParseErr
+		}
+		if number, ok := blockID["block_number"].(float64); ok {
+			if number < 0 || number != math.Trunc(number) || number > math.MaxInt64 {
+				return StarknetBlockID{}, ErrParseErr
+			}
+			return StarknetBlockID{Number: uint64(number)}, nil
+		}
+		if hash, ok := blockID["block_hash"].(string); ok && starknetBlockHashRegex.MatchString(hash) {
+			return StarknetBlockID{Hash: hash}, nil
+		}
+	}
+
+	return StarknetBlockID{}, ErrParseErr
+}
+
+// the latest block is resolved with starknet_blockHashAndNumber, the rest of the tags and the hashes
+// with the block they point to, block numbers don't need a getter req
+func (s StarknetImpl) BuildGetterReq(id string, gt StarknetBlockID) (*models.RPCReq, error) {
+	var blockID string
+	switch {
+	case gt.Hash != "":
+		blockID = fmt.Sprintf(`{"block_hash":"%s"}`, gt.Hash)
+	case gt.Tag == "latest":
+		return &models.RPCReq{
+			JSONRPC: "2.0",
+			Method:  "starknet_blockHashAndNumber",
+			ID:      json.RawMessage(id),
+		}, nil
+	case gt.Tag != "":
+		blockID = fmt.Sprintf(`"%s"`, gt.Tag)
+	default:
+		return nil, ErrParseErr
+	}
+
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "starknet_getBlockWithTxHashes",
+		ID:      json.RawMessage(id),
+		Params:  json.RawMessage(`[` + blockID + `]`),
+	}, nil
+}
+
+func (s StarknetImpl) GetIndexOfIDHolder(gt StarknetBlockID) (string, error) {
+	if gt.Hash != "" {
+		return gt.Hash, nil
+	}
+
+	return gt.Tag, nil
+}
+
+// block responses and the block hash and number response have the number on the same entry
+func (s StarknetImpl) ExtractGetterReturnFromResponse(res *models.RPCResJSON) (int, error) {
+	var block struct {
+		BlockNumber *int `json:"block_number"`
+	}
+	err := json.Unmarshal(res.Result, &block)
+	if err != nil || block.BlockNumber == nil {
+		return 0, ErrInternalBlockWithoutBlockNumber
+	}
+
+	return *block.BlockNumber, nil
+}
+
+func (s StarknetImpl) ExtractGetterReturnFromType(gt StarknetBlockID) (int, error) {
+	if gt.Tag != "" || gt.Hash != "" {
+		return 0, ErrParseErr
+	}
+
+	return int(gt.Number), nil
+}
+
+func (s StarknetImpl) ExtractGetterStruct(res *models.RPCResJSON, gr int, contextRes *models.RPCResJSON) (starknetBlockResult, error) {
+	return starknetBlockResult{
+		Data:        res.Result,
+		BlockNumber: gr,
+	}, nil
+}
+
+func (s StarknetImpl) ExtractGetterRangeStruct(res *models.RPCResJSON, grTo, grFrom int) (starknetBlockRangeResult, error) {
+	return starknetBlockRangeResult{
+		Data:          res.Result,
+		StartingBlock: grTo,
+		EndingBlock:   grFrom,
+	}, nil
+}
+
+func (s StarknetImpl) PinGetter(param interface{}, gt StarknetBlockID, gr int) (interface{}, error) {
+	if gt.Tag == "" {
+		return param, nil // block numbers and hashes already point to a single block
+	}
+	if gt.Tag == "pending" || gt.Tag == "pre_confirmed" {
+		return param, nil // pending block can't be requested by number
+	}
+
+	return map[string]interface{}{"block_number": gr}, nil
+}
+
+// subscriptions have their id on the subscription_id entry, so their notifications are not wrapped
+func (s StarknetImpl) ExtractGetterReturnFromNotification(notification *models.RPCNotification) (int, bool, error) {
+	return 0, false, nil
+}
+
+func NewStarknetMethodBuilder() CustomRpcMethodBuilder {
+	return NewGenericConv(StarknetImpl{})
+}
+
+func (s StarknetImpl) IsImmutableGetter(gt StarknetBlockID, finalizedHeight uint64) bool {
+	if gt.Hash != "" {
+		return true
+	}
+
+	return gt.Tag == "" && gt.Number <= finalizedHeight
+}
+
+func (s StarknetImpl) BuildContextReq(id string) *models.RPCReq {
+	return nil // the block number is the only data added to the results
+}
+
+func (s StarknetImpl) GetIndexOfContextReq() string {
+	return ""
+}
@@ -0,0 +1,99 @@
+package customrpcmethods
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// starknetParam returns the param of the key if the params are named or the one of the position if they are positional
+// the Starknet spec accepts both forms, nil is returned if the param was not sent
+func starknetParam(req *models.RPCReq, pos int, key string) (interface{}, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(req.Params), []byte("{")) {
+		var p map[string]interface{}
+		err := json.Unmarshal(req.Params, &p)
+		if err != nil {
+			return nil, err
+		}
+		return p[key], nil
+	}
+
+	var p []interface{}
+	err := json.Unmarshal(req.Params, &p)
+	if err != nil {
+		return nil, err
+	}
+	if len(p) <= pos {
+		return nil, nil
+	}
+
+	return p[pos], nil
+}
+
+// starknetBlockIDParam extracts the block id of the methods that have it on the position or on the block_id key
+func starknetBlockIDParam(req *models.RPCReq, pos int) ([]StarknetBlockID, error) {
+	blockID, err := starknetParam(req, pos, "block_id")
+	if err != nil {
+		return nil, err
+	}
+
+	gt, err := StarknetImpl{}.ExtractGetter(blockID)
+	if err != nil {
+		return nil, err
+	}
+
+	return []StarknetBlockID{gt}, nil
+}
+
+type starknetCustomHandlersHolder struct{}
+
+// HandleBlockID extracts the block id of the methods that have it as their first param, e.g. starknet_getNonce
+func (starknetCustomHandlersHolder) HandleBlockID(req *models.RPCReq) ([]StarknetBlockID, error) {
+	return starknetBlockIDParam(req, 0)
+}
+
+// HandleCall extracts the block id of starknet_call, its params are request and block id
+func (starknetCustomHandlersHolder) HandleCall(req *models.RPCReq) ([]StarknetBlockID, error) {
+	return starknetBlockIDParam(req, 1)
+}
+
+// HandleGetStorageAt extracts the block id of starknet_getStorageAt, its params are contract address, key and block id
+func (starknetCustomHandlersHolder) HandleGetStorageAt(req *models.RPCReq) ([]StarknetBlockID, error) {
+	return starknetBlockIDParam(req, 2)
+}
+
+// HandleEstimateFee extracts the block id of starknet_estimateFee, its params are request, simulation flags and block id
+func (starknetCustomHandlersHolder) HandleEstimateFee(req *models.RPCReq) ([]StarknetBlockID, error) {
+	return starknetBlockIDParam(req, 2)
+}
+
+// HandleGetEvents extracts the range of starknet_getEvents from the from_block and to_block of its filter
+// a missing from block is the genesis block and a missing to block is the latest one
+func (starknetCustomHandlersHolder) HandleGetEvents(req *models.RPCReq) ([]StarknetBlockID, error) {
+	param, err := starknetParam(req, 0, "filter")
+	if err != nil {
+		return nil, err
+	}
+	filter, ok := param.(map[string]interface{})
+	if !ok {
+		return nil, ErrParseErr
+	}
+
+	defaults := StarknetImpl{}.GetDefaultGetterRange()
+	gts := make([]StarknetBlockID, 0, len(defaults))
+	for i, key := range []string{"from_block", "to_block"} {
+		if filter[key] == nil {
+			gts = append(gts, defaults[i])
+			continue
+		}
+
+		gt, err := StarknetImpl{}.ExtractGetter(filter[key])
+		if err != nil {
+			return nil, err
+		}
+		gts = append(gts, gt)
+	}
+
+	return gts, nil // always keep this order
+}
@@ -0,0 +1,414 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+func TestStarknet(t *testing.T) {
+	tests := []testCaseGenericConv{
+		{
+			name: "Call named params latest",
+			req: []*models.RPCReq{{
+				Method: "starknet_callAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"request":{"contract_address":"0x1","entry_point_selector":"0x2","calldata":[]},"block_id":"latest"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "starknet_call",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`["0x5"]`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"block_hash":"0xab","block_number":21}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: starknetBlockResult{
+					Data:        json.RawMessage(`["0x5"]`),
+					BlockNumber: 21,
+				}.raw(),
+			},
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Call positional block number",
+			req: []*models.RPCReq{{
+				Method: "starknet_callAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"contract_address":"0x1","entry_point_selector":"0x2","calldata":[]},{"block_number":15}]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "starknet_call",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`["0x5"]`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: starknetBlockResult{
+					Data:        json.RawMessage(`["0x5"]`),
+					BlockNumber: 15,
+				}.raw(),
+			},
+		},
+		{
+			name: "Storage at block hash",
+			req: []*models.RPCReq{{
+				Method: "starknet_getStorageAtAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["0x1","0x2",{"block_hash":"0xab"}]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "starknet_getStorageAt",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`"0x0"`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"status":"ACCEPTED_ON_L2","block_hash":"0xab","block_number":18,"transactions":[]}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: starknetBlockResult{
+					Data:        json.RawMessage(`"0x0"`),
+					BlockNumber: 18,
+				}.raw(),
+			},
+			contentsToRewrite: []string{"0xab"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Nonce pending block without number",
+			req: []*models.RPCReq{{
+				Method: "starknet_getNonceAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"block_id":"pending","contract_address":"0x1"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "starknet_getNonce",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`"0x3"`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"parent_hash":"0xab","transactions":[]}`)}},
+			expectedErr:       ErrInternalBlockWithoutBlockNumber,
+			contentsToRewrite: []string{"pending"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Events range",
+			req: []*models.RPCReq{{
+				Method: "starknet_getEventsAndBlockRange",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"filter":{"from_block":{"block_number":10},"to_block":"latest","chunk_size":10}}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "starknet_getEvents",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"events":[]}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"block_hash":"0xab","block_number":21}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: starknetBlockRangeResult{
+					Data:          json.RawMessage(`{"events":[]}`),
+					StartingBlock: 10,
+					EndingBlock:   21,
+				}.raw(),
+			},
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Events default range",
+			req: []*models.RPCReq{{
+				Method: "starknet_getEventsAndBlockRange",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"address":"0x1","chunk_size":10}]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "starknet_getEvents",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"events":[]}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"block_hash":"0xab","block_number":21}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: starknetBlockRangeResult{
+					Data:          json.RawMessage(`{"events":[]}`),
+					StartingBlock: 0,
+					EndingBlock:   21,
+				}.raw(),
+			},
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "No block method",
+			req: []*models.RPCReq{{
+				Method: "starknet_chainId",
+				ID:     json.RawMessage("21"),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "starknet_chainId",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`"0x534e5f4d41494e"`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`"0x534e5f4d41494e"`),
+			},
+		},
+	}
+
+	runTests(t, "../supported-chains/starknet.json", tests)
+}
+
+func TestStarknetPinGetterParams(t *testing.T) {
+	tests := []testCasePinGetterParams{
+		{
+			name: "Call named params latest",
+			req: []*models.RPCReq{{
+				Method: "starknet_callAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"request":{"contract_address":"0x1","entry_point_selector":"0x2","calldata":[]},"block_id":"latest"}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"block_hash":"0xab","block_number":21}`)},
+			},
+			expectedParams: []string{`{"request":{"contract_address":"0x1","entry_point_selector":"0x2","calldata":[]},"block_id":{"block_number":21}}`},
+		},
+		{
+			name: "Call positional latest",
+			req: []*models.RPCReq{{
+				Method: "starknet_callAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"contract_address":"0x1","entry_point_selector":"0x2","calldata":[]},"latest"]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"block_hash":"0xab","block_number":21}`)},
+			},
+			expectedParams: []string{`[{"contract_address":"0x1","entry_point_selector":"0x2","calldata":[]},{"block_number":21}]`},
+		},
+		{
+			name: "Nonce positional block number",
+			req: []*models.RPCReq{{
+				Method: "starknet_getNonceAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"block_number":15},"0x1"]`),
+			}},
+			getterRes:      map[string]*models.RPCResJSON{},
+			expectedParams: []string{`[{"block_number":15},"0x1"]`},
+		},
+		{
+			name: "Nonce named params without block id",
+			req: []*models.RPCReq{{
+				Method: "starknet_getNonceAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"contract_address":"0x1"}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"block_hash":"0xab","block_number":21}`)},
+			},
+			expectedParams: []string{`{"contract_address":"0x1","block_id":{"block_number":21}}`},
+		},
+		{
+			name: "Storage positional pending",
+			req: []*models.RPCReq{{
+				Method: "starknet_getStorageAtAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["0x1","0x2","pending"]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"pending": {Result: json.RawMessage(`{"block_number":22}`)},
+			},
+			expectedParams: []string{`["0x1","0x2","pending"]`},
+		},
+		{
+			name: "Events positional without to block",
+			req: []*models.RPCReq{{
+				Method: "starknet_getEventsAndBlockRange",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"from_block":{"block_number":1},"chunk_size":10}]`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"block_hash":"0xab","block_number":21}`)},
+			},
+			expectedParams: []string{`[{"from_block":{"block_number":1},"chunk_size":10,"to_block":{"block_number":21}}]`},
+		},
+		{
+			name: "Events named latest",
+			req: []*models.RPCReq{{
+				Method: "starknet_getEventsAndBlockRange",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"filter":{"from_block":"latest","to_block":"latest","chunk_size":10}}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"latest": {Result: json.RawMessage(`{"block_hash":"0xab","block_number":21}`)},
+			},
+			expectedParams: []string{`{"filter":{"from_block":{"block_number":21},"to_block":{"block_number":21},"chunk_size":10}}`},
+		},
+	}
+
+	runPinTests(t, "../supported-chains/starknet.json", tests)
+}
+
+func TestStarknetBuildGetterReq(t *testing.T) {
+	tests := []struct {
+		name           string
+		gt             StarknetBlockID
+		expectedMethod string
+		expectedParams string
+		expectedErr    error
+	}{
+		{
+			name:           "Latest",
+			gt:             StarknetBlockID{Tag: "latest"},
+			expectedMethod: "starknet_blockHashAndNumber",
+		},
+		{
+			name:           "Tag",
+			gt:             StarknetBlockID{Tag: "l1_accepted"},
+			expectedMethod: "starknet_getBlockWithTxHashes",
+			expectedParams: `["l1_accepted"]`,
+		},
+		{
+			name:           "Block hash",
+			gt:             StarknetBlockID{Hash: "0xab"},
+			expectedMethod: "starknet_getBlockWithTxHashes",
+			expectedParams: `[{"block_hash":"0xab"}]`,
+		},
+		{
+			name:        "Block number",
+			gt:          StarknetBlockID{Number: 15},
+			expectedErr: ErrParseErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := StarknetImpl{}.BuildGetterReq("1", tt.gt)
+			if err != tt.expectedErr {
+				t.Fatalf("Test case %s: Expected error %v, got %v", tt.name, tt.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if req.Method != tt.expectedMethod {
+				t.Errorf("Test case %s: Expected method %s, got %s", tt.name, tt.expectedMethod, req.Method)
+			}
+			if string(req.Params) != tt.expectedParams {
+				t.Errorf("Test case %s: Expected params %s, got %s", tt.name, tt.expectedParams, req.Params)
+			}
+		})
+	}
+}
+
+func TestStarknetFilterInvalidRPCReqs(t *testing.T) {
+	reqs := []*models.RPCReq{
+		{
+			Method: "starknet_getNonceAndBlockNumber",
+			ID:     json.RawMessage("1"),
+			Params: json.RawMessage(`[{"block_number":10},"0x1"]`),
+		},
+		{
+			Method: "starknet_getNonceAndBlockNumber",
+			ID:     json.RawMessage("2"),
+			Params: json.RawMessage(`["earliest","0x1"]`),
+		},
+		{
+			Method: "starknet_getStorageAtAndBlockNumber",
+			ID:     json.RawMessage("3"),
+			Params: json.RawMessage(`{"contract_address":"0x1","key":"0x2","block_id":{"block_number":10,"block_hash":"0xab"}}`),
+		},
+		{
+			Method: "starknet_getEventsAndBlockRange",
+			ID:     json.RawMessage("4"),
+			Params: json.RawMessage(`{"filter":{"to_block":{"block_hash":"0xab"},"chunk_size":10}}`),
+		},
+		{
+			Method: "starknet_getEventsAndBlockRange",
+			ID:     json.RawMessage("5"),
+			Params: json.RawMessage(`{"filter":{"from_block":{"block_number":-1},"chunk_size":10}}`),
+		},
+	}
+
+	runFilterInvalidTests(t, "../supported-chains/starknet.json", reqs, []string{"1", "4"}, []string{"2", "3", "5"})
+}
+
+func TestStarknetIsImmutableReq(t *testing.T) {
+	tests := []testCaseIsImmutableReq{
+		{
+			name:     "Block number below finalized",
+			req:      &models.RPCReq{Method: "starknet_getNonceAndBlockNumber", Params: json.RawMessage(`[{"block_number":90},"0x1"]`)},
+			expected: true,
+		},
+		{
+			name:     "Block hash",
+			req:      &models.RPCReq{Method: "starknet_getStorageAt", Params: json.RawMessage(`["0x1","0x2",{"block_hash":"0xab"}]`)},
+			expected: true,
+		},
+		{
+			name:     "Block number above finalized",
+			req:      &models.RPCReq{Method: "starknet_getNonceAndBlockNumber", Params: json.RawMessage(`[{"block_number":110},"0x1"]`)},
+			expected: false,
+		},
+		{
+			name:     "Latest tag",
+			req:      &models.RPCReq{Method: "starknet_getNonceAndBlockNumber", Params: json.RawMessage(`["latest","0x1"]`)},
+			expected: false,
+		},
+		{
+			name:     "Events range below finalized",
+			req:      &models.RPCReq{Method: "starknet_getEventsAndBlockRange", Params: json.RawMessage(`{"filter":{"from_block":{"block_number":10},"to_block":{"block_number":90},"chunk_size":10}}`)},
+			expected: true,
+		},
+		{
+			name:     "Events range to latest",
+			req:      &models.RPCReq{Method: "starknet_getEventsAndBlockRange", Params: json.RawMessage(`{"filter":{"from_block":{"block_number":10},"chunk_size":10}}`)},
+			expected: false,
+		},
+		{
+			name:     "Not cacheable",
+			req:      &models.RPCReq{Method: "starknet_estimateFeeAndBlockNumber", Params: json.RawMessage(`[[],[],{"block_number":90}]`)},
+			expected: false,
+		},
+	}
+
+	runIsImmutableTests(t, "../supported-chains/starknet.json", 100, tests)
+}
+
+// raw returns the JSON of the result as the buffered responses wrap its data
+func (r starknetBlockResult) raw() json.RawMessage {
+	return splice(r, r.Data)
+}
+
+// raw returns the JSON of the result as the buffered responses wrap its data
+func (r starknetBlockRangeResult) raw() json.RawMessage {
+	return splice(r, r.Data)
+}
@@ -0,0 +1,229 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// SuiCheckpoint is the getter of the Sui methods, the id of a checkpoint that is a sequence number or a digest
+// empty is the latest checkpoint
+type SuiCheckpoint string
+
+type checkpointResult struct {
+	Data       json.RawMessage `json:"data"`
+	Checkpoint string          `json:"checkpoint"`
+}
+
+// sequence numbers are strings as on the Sui responses
+func (r checkpointResult) envelope() ([]byte, []byte) {
+	return rawEnvelope("data", []string{"checkpoint"}, rawString(r.Checkpoint))
+}
+
+func (r checkpointResult) resolvedBlock() string {
+	return r.Checkpoint
+}
+
+var (
+	ErrInternalCheckpointWithoutSequenceNumber = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 35,
+		Message:       "checkpoint response does not have the sequence number",
+		HTTPErrorCode: 500,
+	}
+
+	// base58 of the 32 bytes of the digest
+	suiDigestRegex = regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{43,44}$`)
+)
+
+type SuiImpl struct{}
+
+func (s SuiImpl) GetChainType() ChainType {
+	return ChainTypeSui
+}
+
+func (s SuiImpl) SupportsRange() bool {
+	return false
+}
+
+func buildLatestCheckpointReq(id string) *models.RPCReq {
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "sui_getLatestCheckpointSequenceNumber",
+		ID:      json.RawMessage(id),
+	}
+}
+
+func (s SuiImpl) GetHealthCheckReq() *models.RPCReq {
+	return buildLatestCheckpointReq("1")
+}
+
+func (s SuiImpl) ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error) {
+	checkpoint, err := s.ExtractGetterReturnFromResponse(res)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(checkpoint, 10, 64)
+}
+
+func (s SuiImpl) GetBlockTime() time.Duration {
+	return 250 * time.Millisecond
+}
+
+// checkpoints are certified by the validators when they are created, so the latest one is final
+func (s SuiImpl) GetFinalizedHeightReq() *models.RPCReq {
+	return buildLatestCheckpointReq("1")
+}
+
+func (s SuiImpl) ExtractFinalizedHeight(res *models.RPCResJSON) (uint64, error) {
+	return s.ExtractHeightFromHealthCheck(res)
+}
+
+func (s SuiImpl) GetDefaultGetter() SuiCheckpoint {
+	return ""
+}
+
+func (s SuiImpl) GetDefaultGetterRange() []SuiCheckpoint {
+	return nil
+}
+
+func (s SuiImpl) GetCustomHandlerMap() map[string]func(*models.RPCReq) ([]SuiCheckpoint, error) {
+	return nil
+}
+
+func (s SuiImpl) FromGetterTypeToHolder(gth GetterTypesHolder) SuiCheckpoint {
+	return gth.Sui
+}
+
+func (s SuiImpl) FromHolderToGetterType(gt SuiCheckpoint) GetterTypesHolder {
+	return GetterTypesHolder{
+		Sui: gt,
+	}
+}
+
+// checkpoint ids are strings, sequence numbers are sent as strings since they are u64
+func (s SuiImpl) ExtractGetter(param interface{}) (SuiCheckpoint, error) {
+	if param == nil {
+		return s.GetDefaultGetter(), nil
+	}
+
+	id, ok := param.(string)
+	if !ok {
+		return "", ErrParseErr
+	}
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil && !suiDigestRegex.MatchString(id) {
+		return "", ErrParseErr
+	}
+
+	return SuiCheckpoint(id), nil
+}
+
+func isSequenceNumber(gt SuiCheckpoint) bool {
+	_, err := strconv.ParseUint(string(gt), 10, 64)
+	return err == nil
+}
+
+// the latest checkpoint is resolved with its sequence number and digests with the checkpoint they point to
+// sequence numbers don't need a getter req
+func (s SuiImpl) BuildGetterReq(id string, gt SuiCheckpoint) (*models.RPCReq, error) {
+	switch {
+	case gt == "":
+		return buildLatestCheckpointReq(id), nil
+	case isSequenceNumber(gt):
+		return nil, ErrParseErr
+	}
+
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  "sui_getCheckpoint",
+		ID:      json.RawMessage(id),
+		Params:  json.RawMessage(`["` + string(gt) + `"]`),
+	}, nil
+}
+
+func (s SuiImpl) GetIndexOfIDHolder(gt SuiCheckpoint) (string, error) {
+	switch {
+	case gt == "":
+		return "latest", nil
+	case isSequenceNumber(gt):
+		return "", nil
+	}
+
+	return string(gt), nil
+}
+
+// the latest sequence number is a string and checkpoints have it on their sequenceNumber entry
+func (s SuiImpl) ExtractGetterReturnFromResponse(res *models.RPCResJSON) (string, error) {
+	checkpoint, ok := stringOf(res.Result)
+	if !ok {
+		var obj struct {
+			SequenceNumber string `json:"sequenceNumber"`
+		}
+		err := json.Unmarshal(res.Result, &obj)
+		if err != nil {
+			return "", ErrInternalCheckpointWithoutSequenceNumber
+		}
+		checkpoint = obj.SequenceNumber
+	}
+	if !isSequenceNumber(SuiCheckpoint(checkpoint)) {
+		return "", ErrInternalCheckpointWithoutSequenceNumber
+	}
+
+	return checkpoint, nil
+}
+
+func (s SuiImpl) ExtractGetterReturnFromType(gt SuiCheckpoint) (string, error) {
+	if !isSequenceNumber(gt) {
+		return "", ErrParseErr
+	}
+
+	return string(gt), nil
+}
+
+func (s SuiImpl) ExtractGetterStruct(res *models.RPCResJSON, gr string, contextRes *models.RPCResJSON) (checkpointResult, error) {
+	return checkpointResult{
+		Data:       res.Result,
+		Checkpoint: gr,
+	}, nil
+}
+
+func (s SuiImpl) ExtractGetterRangeStruct(res *models.RPCResJSON, grTo, grFrom string) (noRangeSupported, error) {
+	return noRangeSupported{}, ErrParseErr
+}
+
+// reads are always served at the latest checkpoint, only checkpoint ids can be pinned and they already point to one
+func (s SuiImpl) PinGetter(param interface{}, gt SuiCheckpoint, gr string) (interface{}, error) {
+	return param, nil
+}
+
+// subscriptions of events and transactions are deprecated on Sui nodes, so their notifications are not wrapped
+func (s SuiImpl) ExtractGetterReturnFromNotification(notification *models.RPCNotification) (string, bool, error) {
+	return "", false, nil
+}
+
+func NewSuiMethodBuilder() CustomRpcMethodBuilder {
+	return NewGenericConv(SuiImpl{})
+}
+
+func (s SuiImpl) IsImmutableGetter(gt SuiCheckpoint, finalizedHeight uint64) bool {
+	if gt == "" {
+		return false
+	}
+	if !isSequenceNumber(gt) {
+		return true // digests point to a single checkpoint
+	}
+
+	checkpoint, _ := strconv.ParseUint(string(gt), 10, 64)
+	return checkpoint <= finalizedHeight
+}
+
+func (s SuiImpl) BuildContextReq(id string) *models.RPCReq {
+	return nil // the checkpoint is the only data added to the results
+}
+
+func (s SuiImpl) GetIndexOfContextReq() string {
+	return ""
+}
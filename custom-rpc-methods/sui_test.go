@@ -0,0 +1,202 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+const testSuiCheckpointDigest = "4btiuiMPvEENsttpZC7CZ53DruC3MAgfznDbASZ7DR6S"
+
+func TestSui(t *testing.T) {
+	tests := []testCaseGenericConv{
+		{
+			name: "Object latest checkpoint",
+			req: []*models.RPCReq{{
+				Method: "sui_getObjectAndCheckpoint",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["0x5",{"showContent":true}]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "sui_getObject",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"data":{"objectId":"0x5","version":"180"}}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`"1500"`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: checkpointResult{
+					Data:       json.RawMessage(`{"data":{"objectId":"0x5","version":"180"}}`),
+					Checkpoint: "1500",
+				}.raw(),
+			},
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Balance named params",
+			req: []*models.RPCReq{{
+				Method: "suix_getBalanceAndCheckpoint",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"owner":"0x1"}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "suix_getBalance",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"coinType":"0x2::sui::SUI","totalBalance":"100"}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`"1500"`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: checkpointResult{
+					Data:       json.RawMessage(`{"coinType":"0x2::sui::SUI","totalBalance":"100"}`),
+					Checkpoint: "1500",
+				}.raw(),
+			},
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Checkpoint sequence number",
+			req: []*models.RPCReq{{
+				Method: "sui_getCheckpointAndCheckpoint",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["1200"]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "sui_getCheckpoint",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"sequenceNumber":"1200"}`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: checkpointResult{
+					Data:       json.RawMessage(`{"sequenceNumber":"1200"}`),
+					Checkpoint: "1200",
+				}.raw(),
+			},
+		},
+		{
+			name: "Checkpoint digest",
+			req: []*models.RPCReq{{
+				Method: "sui_getCheckpointAndCheckpoint",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["4btiuiMPvEENsttpZC7CZ53DruC3MAgfznDbASZ7DR6S"]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "sui_getCheckpoint",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"digest":"4btiuiMPvEENsttpZC7CZ53DruC3MAgfznDbASZ7DR6S","sequenceNumber":"1200"}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"digest":"4btiuiMPvEENsttpZC7CZ53DruC3MAgfznDbASZ7DR6S","sequenceNumber":"1200"}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: checkpointResult{
+					Data:       json.RawMessage(`{"digest":"4btiuiMPvEENsttpZC7CZ53DruC3MAgfznDbASZ7DR6S","sequenceNumber":"1200"}`),
+					Checkpoint: "1200",
+				}.raw(),
+			},
+			contentsToRewrite: []string{testSuiCheckpointDigest},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Latest checkpoint not a number",
+			req: []*models.RPCReq{{
+				Method: "suix_getAllBalancesAndCheckpoint",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`["0x1"]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "suix_getAllBalances",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`[]`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`1500`)}},
+			expectedErr:       ErrInternalCheckpointWithoutSequenceNumber,
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+	}
+
+	runTests(t, "../supported-chains/sui.json", tests)
+}
+
+func TestSuiFilterInvalidRPCReqs(t *testing.T) {
+	reqs := []*models.RPCReq{
+		{
+			Method: "sui_getCheckpointAndCheckpoint",
+			ID:     json.RawMessage("1"),
+			Params: json.RawMessage(`["1200"]`),
+		},
+		{
+			Method: "sui_getCheckpointAndCheckpoint",
+			ID:     json.RawMessage("2"),
+			Params: json.RawMessage(`[1200]`),
+		},
+		{
+			Method: "sui_getCheckpointAndCheckpoint",
+			ID:     json.RawMessage("3"),
+			Params: json.RawMessage(`["0x15"]`),
+		},
+		{
+			Method: "sui_getObjectAndCheckpoint",
+			ID:     json.RawMessage("4"),
+			Params: json.RawMessage(`["0x5"]`),
+		},
+	}
+
+	runFilterInvalidTests(t, "../supported-chains/sui.json", reqs, []string{"1", "4"}, []string{"2", "3"})
+}
+
+func TestSuiIsImmutableReq(t *testing.T) {
+	tests := []testCaseIsImmutableReq{
+		{
+			name:     "Sequence number below finalized",
+			req:      &models.RPCReq{Method: "sui_getCheckpointAndCheckpoint", Params: json.RawMessage(`["90"]`)},
+			expected: true,
+		},
+		{
+			name:     "Digest",
+			req:      &models.RPCReq{Method: "sui_getCheckpointAndCheckpoint", Params: json.RawMessage(`["4btiuiMPvEENsttpZC7CZ53DruC3MAgfznDbASZ7DR6S"]`)},
+			expected: true,
+		},
+		{
+			name:     "Sequence number above finalized",
+			req:      &models.RPCReq{Method: "sui_getCheckpointAndCheckpoint", Params: json.RawMessage(`["110"]`)},
+			expected: false,
+		},
+		{
+			name:     "Object at the latest checkpoint",
+			req:      &models.RPCReq{Method: "sui_getObjectAndCheckpoint", Params: json.RawMessage(`["0x5"]`)},
+			expected: false,
+		},
+	}
+
+	runIsImmutableTests(t, "../supported-chains/sui.json", 100, tests)
+}
+
+// raw returns the JSON of the result as the buffered responses wrap its data
+func (r checkpointResult) raw() json.RawMessage {
+	return splice(r, r.Data)
+}
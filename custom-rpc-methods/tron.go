@@ -0,0 +1,230 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	gethRPC "github.com/ethereum/go-ethereum/rpc"
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// TronBlock is the getter of the Tron methods, the block of the EVM-like JSON-RPC methods
+// or the block of the native wallet API methods, native blocks are the latest block or a height
+type TronBlock struct {
+	Block  *gethRPC.BlockNumberOrHash
+	Native bool
+}
+
+var (
+	ErrInternalBlockWithoutHeaderNumber = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 36,
+		Message:       "block response does not have the header number",
+		HTTPErrorCode: 500,
+	}
+
+	tronMethodNameToCustomHandler = make(map[string]func(*models.RPCReq) ([]TronBlock, error))
+)
+
+func init() {
+	SaveCustomHandlersToMap(tronCustomHandlersHolder{}, tronMethodNameToCustomHandler)
+}
+
+// TronImpl uses the EVM implementation for the blocks of the JSON-RPC methods
+type TronImpl struct{}
+
+func (t TronImpl) GetChainType() ChainType {
+	return ChainTypeTron
+}
+
+func (t TronImpl) SupportsRange() bool {
+	return true
+}
+
+func buildNowBlockReq(method, id string) *models.RPCReq {
+	return &models.RPCReq{
+		JSONRPC: "2.0",
+		Method:  method,
+		ID:      json.RawMessage(id),
+	}
+}
+
+func (t TronImpl) GetHealthCheckReq() *models.RPCReq {
+	return buildNowBlockReq("wallet/getnowblock", "1")
+}
+
+func (t TronImpl) ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error) {
+	height, ok := tronHeaderNumber(res)
+	if !ok {
+		return 0, ErrInternalBlockWithoutHeaderNumber
+	}
+
+	return height, nil
+}
+
+func (t TronImpl) GetBlockTime() time.Duration {
+	return 3 * time.Second
+}
+
+// the now block of the solidity API is the latest solidified block, that is the finalized one
+func (t TronImpl) GetFinalizedHeightReq() *models.RPCReq {
+	return buildNowBlockReq("walletsolidity/getnowblock", "1")
+}
+
+func (t TronImpl) ExtractFinalizedHeight(res *models.RPCResJSON) (uint64, error) {
+	return t.ExtractHeightFromHealthCheck(res)
+}
+
+func (t TronImpl) GetDefaultGetter() TronBlock {
+	return TronBlock{Block: EVMImpl{}.GetDefaultGetter()}
+}
+
+func (t TronImpl) GetDefaultGetterRange() []TronBlock {
+	var gts []TronBlock
+	for _, gt := range (EVMImpl{}).GetDefaultGetterRange() {
+		gts = append(gts, TronBlock{Block: gt})
+	}
+
+	return gts
+}
+
+func (t TronImpl) GetCustomHandlerMap() map[string]func(*models.RPCReq) ([]TronBlock, error) {
+	return tronMethodNameToCustomHandler
+}
+
+func (t TronImpl) FromGetterTypeToHolder(gth GetterTypesHolder) TronBlock {
+	return gth.Tron
+}
+
+func (t TronImpl) FromHolderToGetterType(gt TronBlock) GetterTypesHolder {
+	return GetterTypesHolder{
+		Tron: gt,
+	}
+}
+
+// heights of the wallet API are numbers, the rest of the params are blocks of the JSON-RPC methods
+func (t TronImpl) ExtractGetter(param interface{}) (TronBlock, error) {
+	num, ok := param.(float64)
+	if ok {
+		if num < 0 || num != math.Trunc(num) || num > math.MaxInt64 {
+			return TronBlock{}, ErrParseErr
+		}
+		block := gethRPC.BlockNumberOrHashWithNumber(gethRPC.BlockNumber(num))
+		return TronBlock{Block: &block, Native: true}, nil
+	}
+
+	block, err := EVMImpl{}.ExtractGetter(param)
+	if err != nil {
+		return TronBlock{}, err
+	}
+
+	return TronBlock{Block: block}, nil
+}
+
+// only the latest native block needs a getter req, heights are known
+func (t TronImpl) BuildGetterReq(id string, gt TronBlock) (*models.RPCReq, error) {
+	if !gt.Native {
+		return EVMImpl{}.BuildGetterReq(id, gt.Block)
+	}
+	if !isLatestBlock(gt.Block) {
+		return nil, ErrParseErr
+	}
+
+	return buildNowBlockReq("wallet/getnowblock", id), nil
+}
+
+func (t TronImpl) GetIndexOfIDHolder(gt TronBlock) (string, error) {
+	if !gt.Native {
+		return EVMImpl{}.GetIndexOfIDHolder(gt.Block)
+	}
+	if isLatestBlock(gt.Block) {
+		return "wallet/getnowblock", nil
+	}
+
+	return "", nil
+}
+
+// blocks of the wallet API have their height as a number on the header, it is returned as a decimal
+// blocks of the JSON-RPC methods have their hex number
+func (t TronImpl) ExtractGetterReturnFromResponse(res *models.RPCResJSON) (string, error) {
+	height, ok := tronHeaderNumber(res)
+	if ok {
+		return strconv.FormatUint(height, 10), nil
+	}
+
+	return EVMImpl{}.ExtractGetterReturnFromResponse(res)
+}
+
+func (t TronImpl) ExtractGetterReturnFromType(gt TronBlock) (string, error) {
+	if !gt.Native {
+		return EVMImpl{}.ExtractGetterReturnFromType(gt.Block)
+	}
+	if isLatestBlock(gt.Block) {
+		return "", ErrParseErr
+	}
+
+	return strconv.FormatInt(gt.Block.BlockNumber.Int64(), 10), nil
+}
+
+func (t TronImpl) ExtractGetterStruct(res *models.RPCResJSON, gr string, contextRes *models.RPCResJSON) (blockNumberResult, error) {
+	return EVMImpl{}.ExtractGetterStruct(res, gr, contextRes)
+}
+
+func (t TronImpl) ExtractGetterRangeStruct(res *models.RPCResJSON, grTo, grFrom string) (blockRangeResult, error) {
+	return EVMImpl{}.ExtractGetterRangeStruct(res, grTo, grFrom)
+}
+
+// the state methods of the Tron JSON-RPC only accept the latest block and the wallet API methods have no block param,
+// so the getters are never pinned
+func (t TronImpl) PinGetter(param interface{}, gt TronBlock, gr string) (interface{}, error) {
+	return param, nil
+}
+
+// GetterPositions reports that the native methods have no getter params, they are always served at the latest block
+func (t TronImpl) GetterPositions(customHandler string, req *models.RPCReq) ([]int, bool) {
+	return nil, customHandler == "HandleNative"
+}
+
+// Tron nodes have no websocket subscriptions
+func (t TronImpl) ExtractGetterReturnFromNotification(notification *models.RPCNotification) (string, bool, error) {
+	return "", false, nil
+}
+
+func NewTronMethodBuilder() CustomRpcMethodBuilder {
+	return NewGenericConv(TronImpl{})
+}
+
+func (t TronImpl) IsImmutableGetter(gt TronBlock, finalizedHeight uint64) bool {
+	return EVMImpl{}.IsImmutableGetter(gt.Block, finalizedHeight)
+}
+
+func (t TronImpl) BuildContextReq(id string) *models.RPCReq {
+	return nil // the block number is the only data added to the results
+}
+
+func (t TronImpl) GetIndexOfContextReq() string {
+	return ""
+}
+
+// isLatestBlock returns if the block is the latest tag
+func isLatestBlock(block *gethRPC.BlockNumberOrHash) bool {
+	return block != nil && block.BlockNumber != nil && *block.BlockNumber == gethRPC.LatestBlockNumber
+}
+
+// tronHeaderNumber returns the height of a block of the wallet API, false if the result is not one
+func tronHeaderNumber(res *models.RPCResJSON) (uint64, bool) {
+	var block struct {
+		BlockHeader struct {
+			RawData struct {
+				Number *uint64 `json:"number"`
+			} `json:"raw_data"`
+		} `json:"block_header"`
+	}
+	err := json.Unmarshal(res.Result, &block)
+	if err != nil || block.BlockHeader.RawData.Number == nil {
+		return 0, false
+	}
+
+	return *block.BlockHeader.RawData.Number, true
+}
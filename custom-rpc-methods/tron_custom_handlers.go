@@ -0,0 +1,28 @@
+package customrpcmethods
+
+import (
+	gethRPC "github.com/ethereum/go-ethereum/rpc"
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+type tronCustomHandlersHolder struct{}
+
+func (tronCustomHandlersHolder) HandleGetLogsAndBlockRange(req *models.RPCReq) ([]TronBlock, error) {
+	blocks, err := evmCustomHandlersHolder{}.HandleGetLogsAndBlockRange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var gts []TronBlock
+	for _, block := range blocks {
+		gts = append(gts, TronBlock{Block: block})
+	}
+
+	return gts, nil
+}
+
+// HandleNative returns the latest native block for the wallet API methods that are always served at the latest block, e.g. getaccount
+func (tronCustomHandlersHolder) HandleNative(req *models.RPCReq) ([]TronBlock, error) {
+	block := gethRPC.BlockNumberOrHashWithNumber(gethRPC.LatestBlockNumber)
+	return []TronBlock{{Block: &block, Native: true}}, nil
+}
@@ -0,0 +1,66 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// the methods of the wallet API are named after their path, e.g. wallet/getaccount
+var tronWalletMethodRegex = regexp.MustCompile(`^(wallet|walletsolidity)/[a-z0-9]+$`)
+
+// BuildRESTReq returns the request of the wallet API endpoint of the method, its params are the body and must be named
+// the rest of the methods are sent as they are to the JSON-RPC endpoint
+func (t TronImpl) BuildRESTReq(req *models.RPCReq) (*RESTReq, error) {
+	if !tronWalletMethodRegex.MatchString(req.Method) {
+		body, err := json.Marshal(req)
+		if err != nil {
+			return nil, ErrInvalidParams
+		}
+		return &RESTReq{
+			Method:  http.MethodPost,
+			Path:    "/jsonrpc",
+			Body:    body,
+			JSONRPC: true,
+		}, nil
+	}
+
+	body := []byte("{}")
+	if len(req.Params) > 0 && string(req.Params) != "null" {
+		if !isNamedParams(req.Params) || !json.Valid(req.Params) {
+			return nil, ErrInvalidParams
+		}
+		body = req.Params
+	}
+
+	return &RESTReq{
+		Method: http.MethodPost,
+		Path:   "/" + req.Method,
+		Body:   body,
+	}, nil
+}
+
+// ExtractRESTError returns the error of the responses whose status is not successful
+// and of the wallet API responses that have an Error entry, they are returned with a successful status
+func (t TronImpl) ExtractRESTError(statusCode int, body []byte) *models.RPCErr {
+	if statusCode < http.StatusOK || statusCode >= http.StatusMultipleChoices {
+		return restError(statusCode, body)
+	}
+
+	var resMap map[string]json.RawMessage
+	if json.Unmarshal(body, &resMap) != nil {
+		return nil
+	}
+	message, ok := stringOf(resMap["Error"]) // JSON-RPC responses have a lowercase error entry
+	if !ok {
+		return nil
+	}
+
+	return &models.RPCErr{
+		Code:    JSONRPCErrorInternal,
+		Message: message,
+		Data:    body,
+	}
+}
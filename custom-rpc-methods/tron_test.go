@@ -0,0 +1,274 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+func TestTron(t *testing.T) {
+	tests := []testCaseGenericConv{
+		{
+			name: "Call latest block",
+			req: []*models.RPCReq{{
+				Method: "eth_callAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"to":"0x41a614f803b6fd780986a42c78ec9c7f77e6ded13c","data":"0x70a08231"},"latest"]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "eth_call",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`"0x0000000000000000000000000000000000000000000000000000000000000064"`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"number":"0x3e3c5a1"}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockNumberResult{
+					Data:        json.RawMessage(`"0x0000000000000000000000000000000000000000000000000000000000000064"`),
+					BlockNumber: "0x3e3c5a1",
+				}.raw(),
+			},
+			contentsToRewrite: []string{"latest"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Logs range",
+			req: []*models.RPCReq{{
+				Method: "eth_getLogsAndBlockRange",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`[{"fromBlock":"0x3e3c500","toBlock":"0x3e3c5a0"}]`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "eth_getLogs",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`[]`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockRangeResult{
+					Data:          json.RawMessage(`[]`),
+					StartingBlock: "0x3e3c500",
+					EndingBlock:   "0x3e3c5a0",
+				}.raw(),
+			},
+		},
+		{
+			name: "Account at the now block",
+			req: []*models.RPCReq{{
+				Method: "wallet/getaccountAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"address":"TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy","visible":true}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "wallet/getaccount",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"address":"TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy","balance":100}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`{"blockID":"0000000003e3c5a1","block_header":{"raw_data":{"number":65258913,"timestamp":1718000000000}}}`)}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockNumberResult{
+					Data:        json.RawMessage(`{"address":"TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy","balance":100}`),
+					BlockNumber: "65258913",
+				}.raw(),
+			},
+			contentsToRewrite: []string{"wallet/getnowblock"},
+			idsToRewrite:      []string{"22"},
+		},
+		{
+			name: "Transaction info by block num",
+			req: []*models.RPCReq{{
+				Method: "wallet/gettransactioninfobyblocknumAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"num":65258900}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "wallet/gettransactioninfobyblocknum",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 1,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`[]`),
+			}},
+			expectedRes: &models.RPCResJSON{
+				ID: json.RawMessage("21"),
+				Result: blockNumberResult{
+					Data:        json.RawMessage(`[]`),
+					BlockNumber: "65258900",
+				}.raw(),
+			},
+		},
+		{
+			name: "Now block without header",
+			req: []*models.RPCReq{{
+				Method: "wallet/triggerconstantcontractAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"owner_address":"TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy","contract_address":"TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t","function_selector":"balanceOf(address)","visible":true}`),
+			}},
+			expectedReq: &models.RPCReq{
+				Method: "wallet/triggerconstantcontract",
+				ID:     json.RawMessage("21"),
+			},
+			expectedReqLength: 2,
+			res: []*models.RPCResJSON{{
+				ID:     json.RawMessage("21"),
+				Result: json.RawMessage(`{"constant_result":["0064"]}`),
+			}, {ID: json.RawMessage("22"),
+				Result: json.RawMessage(`"0x3e3c5a1"`)}},
+			expectedErr:       ErrInternalBlockNumberMethodNotMap,
+			contentsToRewrite: []string{"wallet/getnowblock"},
+			idsToRewrite:      []string{"22"},
+		},
+	}
+
+	runTests(t, "../supported-chains/tron.json", tests)
+}
+
+func TestTronPinGetterParams(t *testing.T) {
+	tests := []testCasePinGetterParams{
+		{
+			name: "Native method without block param",
+			req: []*models.RPCReq{{
+				Method: "wallet/getaccountAndBlockNumber",
+				ID:     json.RawMessage("21"),
+				Params: json.RawMessage(`{"address":"TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy","visible":true}`),
+			}},
+			getterRes: map[string]*models.RPCResJSON{
+				"wallet/getnowblock": {Result: json.RawMessage(`{"blockID":"0000000003e3c5a1","block_header":{"raw_data":{"number":65258913,"timestamp":1718000000000}}}`)},
+			},
+			expectedParams: []string{`{"address":"TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy","visible":true}`},
+		},
+	}
+
+	runPinTests(t, "../supported-chains/tron.json", tests)
+}
+
+func TestTronBuildRESTReq(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          *models.RPCReq
+		expectedPath string
+		expectedBody string
+		jsonrpc      bool
+		expectedErr  error
+	}{
+		{
+			name:         "Now block",
+			req:          &models.RPCReq{Method: "wallet/getnowblock"},
+			expectedPath: "/wallet/getnowblock",
+			expectedBody: `{}`,
+		},
+		{
+			name:         "Solidity now block",
+			req:          &models.RPCReq{Method: "walletsolidity/getnowblock", Params: json.RawMessage(`null`)},
+			expectedPath: "/walletsolidity/getnowblock",
+			expectedBody: `{}`,
+		},
+		{
+			name:         "Account",
+			req:          &models.RPCReq{Method: "wallet/getaccount", Params: json.RawMessage(`{"address":"TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy","visible":true}`)},
+			expectedPath: "/wallet/getaccount",
+			expectedBody: `{"address":"TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy","visible":true}`,
+		},
+		{
+			name:         "JSON-RPC method",
+			req:          &models.RPCReq{JSONRPC: "2.0", Method: "eth_blockNumber", ID: json.RawMessage("1")},
+			expectedPath: "/jsonrpc",
+			expectedBody: `{"jsonrpc":"2.0","method":"eth_blockNumber","id":1}`,
+			jsonrpc:      true,
+		},
+		{
+			name:        "Positional params",
+			req:         &models.RPCReq{Method: "wallet/getaccount", Params: json.RawMessage(`["TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy"]`)},
+			expectedErr: ErrInvalidParams,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restReq, err := TronImpl{}.BuildRESTReq(tt.req)
+			if err != tt.expectedErr {
+				t.Fatalf("Test case %s: Expected error %v, got %v", tt.name, tt.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if restReq.Method != http.MethodPost {
+				t.Errorf("Test case %s: Expected method %s, got %s", tt.name, http.MethodPost, restReq.Method)
+			}
+			if restReq.Path != tt.expectedPath {
+				t.Errorf("Test case %s: Expected path %s, got %s", tt.name, tt.expectedPath, restReq.Path)
+			}
+			if string(restReq.Body) != tt.expectedBody {
+				t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedBody, restReq.Body)
+			}
+			if restReq.JSONRPC != tt.jsonrpc {
+				t.Errorf("Test case %s: Expected JSON-RPC %v, got %v", tt.name, tt.jsonrpc, restReq.JSONRPC)
+			}
+		})
+	}
+}
+
+func TestTronExtractRESTError(t *testing.T) {
+	body := []byte(`{"Error":"class org.tron.core.exception.BadItemException : address invalid"}`)
+
+	rpcErr := TronImpl{}.ExtractRESTError(http.StatusOK, body)
+	if rpcErr == nil || rpcErr.Message != "class org.tron.core.exception.BadItemException : address invalid" {
+		t.Fatalf("Expected the Error entry as the message, got %v", rpcErr)
+	}
+	if string(rpcErr.Data) != string(body) {
+		t.Errorf("Expected the body as the data, got %s", rpcErr.Data)
+	}
+
+	rpcErr = TronImpl{}.ExtractRESTError(http.StatusOK, []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}`))
+	if rpcErr != nil {
+		t.Errorf("Expected no error on JSON-RPC responses, got %v", rpcErr)
+	}
+
+	rpcErr = TronImpl{}.ExtractRESTError(http.StatusNotFound, []byte("not found"))
+	if rpcErr == nil || rpcErr.Message != http.StatusText(http.StatusNotFound) {
+		t.Errorf("Expected the status text, got %v", rpcErr)
+	}
+}
+
+func TestTronIsImmutableReq(t *testing.T) {
+	tests := []testCaseIsImmutableReq{
+		{
+			name:     "Native height below finalized",
+			req:      &models.RPCReq{Method: "wallet/getblockbynumAndBlockNumber", Params: json.RawMessage(`{"num":90}`)},
+			expected: true,
+		},
+		{
+			name:     "Native height above finalized",
+			req:      &models.RPCReq{Method: "wallet/getblockbynum", Params: json.RawMessage(`{"num":110}`)},
+			expected: false,
+		},
+		{
+			name:     "Logs below finalized",
+			req:      &models.RPCReq{Method: "eth_getLogsAndBlockRange", Params: json.RawMessage(`[{"fromBlock":"0x10","toBlock":"0x20"}]`)},
+			expected: true,
+		},
+		{
+			name:     "Account at the now block",
+			req:      &models.RPCReq{Method: "wallet/getaccountAndBlockNumber", Params: json.RawMessage(`{"address":"TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy"}`)},
+			expected: false,
+		},
+	}
+
+	runIsImmutableTests(t, "../supported-chains/tron.json", 100, tests)
+}
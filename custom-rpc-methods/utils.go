@@ -16,11 +16,53 @@ var (
 		HTTPErrorCode: 400,
 	}
 
+	ErrInvalidRequest = &models.RPCErr{
+		Code:          -32600,
+		Message:       "invalid request",
+		HTTPErrorCode: 400,
+	}
+
+	ErrMethodNotFound = &models.RPCErr{
+		Code:          -32601,
+		Message:       "method not found",
+		HTTPErrorCode: 404,
+	}
+
+	ErrInvalidParams = &models.RPCErr{
+		Code:          -32602,
+		Message:       "invalid params",
+		HTTPErrorCode: 400,
+	}
+
+	ErrAmbiguousMethod = &models.RPCErr{
+		Code:          -32600,
+		Message:       "method is defined by more than one chain type",
+		HTTPErrorCode: 400,
+	}
+
+	ErrMethodNotPinnable = &models.RPCErr{
+		Code:          -32600,
+		Message:       "method can't be pinned, the getter params of its params are not declared",
+		HTTPErrorCode: 400,
+	}
+
 	ErrInternal = &models.RPCErr{
 		Code:          JSONRPCErrorInternal,
 		Message:       "internal error",
 		HTTPErrorCode: 500,
 	}
+
+	ErrRequestTooLarge = &models.RPCErr{
+		Code:          -32600,
+		Message:       "request body is too large",
+		HTTPErrorCode: 413,
+	}
+
+	ErrResponseTooLarge = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 27,
+		Message:       "response body is too large",
+		HTTPErrorCode: 502,
+	}
 )
 
 func generateRandomNumberStringWithRetries(rpcReqs []*models.RPCReq) (string, error) {
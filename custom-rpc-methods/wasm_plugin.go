@@ -0,0 +1,311 @@
+package customrpcmethods
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+const (
+	defaultPluginMemoryLimitMb = 16
+	defaultPluginTimeoutMs     = 100
+
+	// the memory of a 32 bit module has at most 65536 pages of 64KiB
+	maxPluginMemoryLimitMb = 4096
+	wasmPagesPerMb         = (1 << 20) / (64 << 10)
+
+	pluginAllocExport   = "alloc"
+	pluginGettersExport = "getters"
+	pluginWrapExport    = "wrap"
+)
+
+var (
+	ErrInternalPluginFailed = &models.RPCErr{
+		Code:          JSONRPCErrorInternal - 37,
+		Message:       "plugin failed",
+		HTTPErrorCode: 500,
+	}
+
+	loadedPlugins   = map[Plugin]*wasmPlugin{}
+	loadedPluginsMu sync.Mutex
+)
+
+// Plugin is a WASM module that finds the getter params of a method instead of a custom handler
+// and optionally wraps its results, the module is sandboxed with the memory and time limits
+// relative paths are resolved against the directory of the config file by ReadConfigFiles
+type Plugin struct {
+	Path          string `json:"path"`
+	MemoryLimitMb uint32 `json:"memoryLimitMb,omitempty"`
+	TimeoutMs     int64  `json:"timeoutMs,omitempty"`
+}
+
+// wasmPlugin is a compiled plugin, each call runs on a new instance of the module
+// so calls can be concurrent and don't share the memory of the module
+type wasmPlugin struct {
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	timeout  time.Duration
+	wraps    bool
+}
+
+// pluginGetters is the output of the getters export, the getters are params of the chain type
+type pluginGetters struct {
+	Getters []interface{} `json:"getters"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// pluginWrapInput is the input of the wrap export, wrapped is the result with the getter struct of the chain type
+type pluginWrapInput struct {
+	Method  string          `json:"method"`
+	Result  json.RawMessage `json:"result"`
+	Wrapped json.RawMessage `json:"wrapped"`
+	Block   string          `json:"block"`
+}
+
+// pluginWrapOutput is the output of the wrap export
+type pluginWrapOutput struct {
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// loadPlugin returns the compiled plugin of the config, plugins are compiled once and shared by the configs
+func loadPlugin(config Plugin) (*wasmPlugin, error) {
+	loadedPluginsMu.Lock()
+	defer loadedPluginsMu.Unlock()
+
+	plugin, ok := loadedPlugins[config]
+	if ok {
+		return plugin, nil
+	}
+
+	plugin, err := compilePlugin(config)
+	if err != nil {
+		return nil, err
+	}
+	loadedPlugins[config] = plugin
+
+	return plugin, nil
+}
+
+func compilePlugin(config Plugin) (*wasmPlugin, error) {
+	wasm, err := os.ReadFile(config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	memoryLimitMb := config.MemoryLimitMb
+	if memoryLimitMb == 0 {
+		memoryLimitMb = defaultPluginMemoryLimitMb
+	}
+	if memoryLimitMb > maxPluginMemoryLimitMb {
+		return nil, fmt.Errorf("plugin memory limit is %dMb and the max allowed is %dMb", memoryLimitMb, maxPluginMemoryLimitMb)
+	}
+	timeoutMs := config.TimeoutMs
+	if timeoutMs <= 0 {
+		timeoutMs = defaultPluginTimeoutMs
+	}
+
+	ctx := gocontext.Background()
+	// modules are closed when the context of their call is done, so a call can't run past its timeout
+	runtimeConfig := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(memoryLimitMb * wasmPagesPerMb).
+		WithCloseOnContextDone(true)
+	r := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+
+	// plugins built for WASI can be instantiated, but they have no args, env, files nor stdout
+	_, err = wasi_snapshot_preview1.Instantiate(ctx, r)
+	if err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+
+	err = validatePluginExports(compiled)
+	if err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+
+	_, wraps := compiled.ExportedFunctions()[pluginWrapExport]
+
+	return &wasmPlugin{
+		runtime:  r,
+		compiled: compiled,
+		timeout:  time.Duration(timeoutMs) * time.Millisecond,
+		wraps:    wraps,
+	}, nil
+}
+
+// validatePluginExports returns an error if the module doesn't export the memory and the functions of the plugin interface
+func validatePluginExports(compiled wazero.CompiledModule) error {
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		return errors.New("plugin doesn't export its memory")
+	}
+
+	expected := map[string][2][]api.ValueType{
+		pluginAllocExport:   {{api.ValueTypeI32}, {api.ValueTypeI32}},
+		pluginGettersExport: {{api.ValueTypeI32, api.ValueTypeI32}, {api.ValueTypeI64}},
+		pluginWrapExport:    {{api.ValueTypeI32, api.ValueTypeI32}, {api.ValueTypeI64}},
+	}
+	functions := compiled.ExportedFunctions()
+	for name, signature := range expected {
+		function, ok := functions[name]
+		if !ok {
+			if name == pluginWrapExport {
+				continue // the wrap export is optional
+			}
+			return fmt.Errorf("plugin doesn't export the %s function", name)
+		}
+		if !equalValueTypes(function.ParamTypes(), signature[0]) || !equalValueTypes(function.ResultTypes(), signature[1]) {
+			return fmt.Errorf("plugin function %s has an incorrect signature", name)
+		}
+	}
+
+	return nil
+}
+
+func equalValueTypes(a, b []api.ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// call writes the input to the memory given by the alloc export of a new instance of the module
+// and returns the output of the export, exports return the pointer of the output on the high 32 bits and its length on the low ones
+func (p *wasmPlugin) call(export string, input []byte) ([]byte, error) {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), p.timeout)
+	defer cancel()
+
+	// the start function of reactor modules initializes them, commands are not started so they don't exit
+	mod, err := p.runtime.InstantiateModule(ctx, p.compiled, wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return nil, err
+	}
+	defer mod.Close(ctx)
+
+	allocRes, err := mod.ExportedFunction(pluginAllocExport).Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, err
+	}
+	ptr := uint32(allocRes[0])
+	if !mod.Memory().Write(ptr, input) {
+		return nil, fmt.Errorf("plugin allocated %d bytes out of its memory", len(input))
+	}
+
+	res, err := mod.ExportedFunction(export).Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, err
+	}
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	out, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("plugin output of %d bytes is out of its memory", outLen)
+	}
+
+	return append([]byte(nil), out...), nil // the memory is released when the module is closed
+}
+
+// getters returns the getter params of the req found by the plugin
+func (p *wasmPlugin) getters(req *models.RPCReq) ([]interface{}, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := p.call(pluginGettersExport, input)
+	if err != nil {
+		return nil, pluginErr(err.Error())
+	}
+
+	var output pluginGetters
+	err = json.Unmarshal(out, &output)
+	if err != nil {
+		return nil, pluginErr("invalid getters output")
+	}
+	if output.Error != "" {
+		return nil, errors.New(output.Error) // the params of the req are invalid
+	}
+
+	return output.Getters, nil
+}
+
+// wrap returns the result of the method returned by the plugin
+func (p *wasmPlugin) wrap(input pluginWrapInput) (json.RawMessage, *models.RPCErr) {
+	in, err := json.Marshal(input)
+	if err != nil {
+		return nil, pluginErr(err.Error())
+	}
+
+	out, err := p.call(pluginWrapExport, in)
+	if err != nil {
+		return nil, pluginErr(err.Error())
+	}
+
+	var output pluginWrapOutput
+	err = json.Unmarshal(out, &output)
+	if err != nil || (output.Error == "" && len(output.Result) == 0) {
+		return nil, pluginErr("invalid wrap output")
+	}
+	if output.Error != "" {
+		return nil, pluginErr(output.Error)
+	}
+
+	return output.Result, nil
+}
+
+func pluginErr(message string) *models.RPCErr {
+	return &models.RPCErr{
+		Code:          ErrInternalPluginFailed.Code,
+		Message:       fmt.Sprintf("%s: %s", ErrInternalPluginFailed.Message, message),
+		HTTPErrorCode: ErrInternalPluginFailed.HTTPErrorCode,
+	}
+}
+
+// pluginHandler returns the custom handler of the plugin, the getter params it returns are extracted as the ones of the params
+// no getter params means the default getters are used
+func (g *GenericConv[T, R, S, SR]) pluginHandler(plugin *wasmPlugin) func(*models.RPCReq) ([]T, error) {
+	return func(req *models.RPCReq) ([]T, error) {
+		params, err := plugin.getters(req)
+		if err != nil {
+			return nil, err
+		}
+		if len(params) == 0 {
+			return g.returnDefaultGetters(req), nil
+		}
+		if len(params) > 2 {
+			return nil, pluginErr(fmt.Sprintf("%d getters returned and the max allowed is 2", len(params)))
+		}
+
+		var gts []T
+		for _, param := range params {
+			gt, err := g.impl.ExtractGetter(param)
+			if err != nil {
+				return nil, err
+			}
+			gts = append(gts, gt)
+		}
+
+		return gts, nil
+	}
+}
@@ -0,0 +1,262 @@
+package customrpcmethods
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+const testPluginInputOffset = 4096
+
+func uleb128(v uint64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func sleb128(v int64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func wasmVec(items ...[]byte) []byte {
+	b := uleb128(uint64(len(items)))
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+func wasmName(name string) []byte {
+	return append(uleb128(uint64(len(name))), name...)
+}
+
+func wasmSection(id byte, payload []byte) []byte {
+	return append(append([]byte{id}, uleb128(uint64(len(payload)))...), payload...)
+}
+
+// returnOutputBody is the body of an export that returns the output written at the offset
+func returnOutputBody(offset int, output string) []byte {
+	return append(append([]byte{0x00, 0x42}, sleb128(int64(offset)<<32|int64(len(output)))...), 0x0b)
+}
+
+// loopBody is the body of an export that never returns
+var loopBody = []byte{0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x00, 0x0b}
+
+// testPluginModule returns a module with the memory, alloc, getters and optionally wrap exports
+// the outputs are written to the memory at their offsets
+func testPluginModule(minPages uint64, gettersBody, wrapBody []byte, outputs map[int]string) []byte {
+	module := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+
+	module = append(module, wasmSection(1, wasmVec(
+		[]byte{0x60, 0x01, 0x7f, 0x01, 0x7f},       // (i32) -> i32
+		[]byte{0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e}, // (i32, i32) -> i64
+	))...)
+
+	functions := [][]byte{{0x00}, {0x01}}
+	exports := [][]byte{
+		append(wasmName("memory"), 0x02, 0x00),
+		append(wasmName("alloc"), 0x00, 0x00),
+		append(wasmName("getters"), 0x00, 0x01),
+	}
+	allocBody := append(append([]byte{0x00, 0x41}, sleb128(testPluginInputOffset)...), 0x0b)
+	codes := [][]byte{allocBody, gettersBody}
+	if wrapBody != nil {
+		functions = append(functions, []byte{0x01})
+		exports = append(exports, append(wasmName("wrap"), 0x00, 0x02))
+		codes = append(codes, wrapBody)
+	}
+	for i, code := range codes {
+		codes[i] = append(uleb128(uint64(len(code))), code...)
+	}
+
+	var segments [][]byte
+	for offset, output := range outputs {
+		segment := append([]byte{0x00, 0x41}, sleb128(int64(offset))...)
+		segment = append(segment, 0x0b)
+		segments = append(segments, append(segment, wasmName(output)...))
+	}
+
+	module = append(module, wasmSection(3, wasmVec(functions...))...)
+	module = append(module, wasmSection(5, wasmVec(append([]byte{0x00}, uleb128(minPages)...)))...)
+	module = append(module, wasmSection(7, wasmVec(exports...))...)
+	module = append(module, wasmSection(10, wasmVec(codes...))...)
+	module = append(module, wasmSection(11, wasmVec(segments...))...)
+
+	return module
+}
+
+func writeTestPlugin(t *testing.T, module []byte) string {
+	path := filepath.Join(t.TempDir(), "plugin.wasm")
+	err := os.WriteFile(path, module, 0o600)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+
+	return path
+}
+
+func testPluginConfig(plugin Plugin) MethodsConfig {
+	return MethodsConfig{
+		ChainType: ChainTypeEVM,
+		Methods: []Method{{
+			CustomMethod:   "eth_getLogsAndBlockRange",
+			OriginalMethod: "eth_getLogs",
+			Plugin:         &plugin,
+			IsRange:        true,
+		}},
+	}
+}
+
+func TestPluginGetters(t *testing.T) {
+	gettersOutput := `{"getters":["0x1","latest"]}`
+	wrapOutput := `{"result":{"logs":[],"from":"0x1"}}`
+	path := writeTestPlugin(t, testPluginModule(1, returnOutputBody(16, gettersOutput), returnOutputBody(512, wrapOutput), map[int]string{
+		16:  gettersOutput,
+		512: wrapOutput,
+	}))
+	ch := NewCustomMethodHolderFromConfigs(false, []MethodsConfig{testPluginConfig(Plugin{Path: path})})
+
+	reqs := []*models.RPCReq{{JSONRPC: "2.0", Method: "eth_getLogsAndBlockRange", ID: json.RawMessage("1"), Params: json.RawMessage(`[{"fromBlock":"0x1"}]`)}}
+	customMethodsMap, err := ch.GetCustomMethodsMap(reqs)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	gts := customMethodsMap["1"]
+	if len(gts) != 2 || gts[0].EVM.String() != "0x1" || gts[1].EVM.String() != "latest" {
+		t.Fatalf("Expected the getters of the plugin, got %v", gts)
+	}
+
+	changedMethods, _ := ch.ChangeCustomMethods(reqs)
+	if !ch.WrapsResult(json.RawMessage("1"), changedMethods) {
+		t.Errorf("Expected the result to be wrapped by the plugin")
+	}
+	reqs, idsHolder, err := ch.AddGetterMethodsIfNeeded(reqs, customMethodsMap)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+
+	ress := []*models.RPCResJSON{
+		{JSONRPC: "2.0", ID: json.RawMessage("1"), Result: json.RawMessage(`[]`)},
+		{JSONRPC: "2.0", ID: json.RawMessage(idsHolder["latest"]), Result: json.RawMessage(`{"number":"0x10"}`)},
+	}
+	ress, err = ch.ChangeCustomMethodsResponses(ress, changedMethods, idsHolder, customMethodsMap)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	if len(reqs) != 2 || len(ress) != 1 || string(ress[0].Result) != `{"logs":[],"from":"0x1"}` {
+		t.Errorf("Expected the result of the plugin, got %s", ress[0].Result)
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	invalidOutput := `{"error":"filter is missing"}`
+	invalid := writeTestPlugin(t, testPluginModule(1, returnOutputBody(16, invalidOutput), nil, map[int]string{16: invalidOutput}))
+	loop := writeTestPlugin(t, testPluginModule(1, loopBody, nil, nil))
+
+	tests := []struct {
+		name            string
+		plugin          Plugin
+		expectedCode    int
+		expectedMessage string
+	}{
+		{
+			name:            "Invalid params",
+			plugin:          Plugin{Path: invalid},
+			expectedCode:    ErrParseErr.Code,
+			expectedMessage: "filter is missing",
+		},
+		{
+			name:            "Timeout",
+			plugin:          Plugin{Path: loop, TimeoutMs: 10},
+			expectedCode:    ErrInternalPluginFailed.Code,
+			expectedMessage: ErrInternalPluginFailed.Message,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := NewCustomMethodHolderFromConfigs(false, []MethodsConfig{testPluginConfig(tt.plugin)})
+			req := &models.RPCReq{JSONRPC: "2.0", Method: "eth_getLogsAndBlockRange", ID: json.RawMessage("1"), Params: json.RawMessage(`[{}]`)}
+
+			validReqs, errRess, err := ch.FilterInvalidRPCReqs([]*models.RPCReq{req})
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+			if len(validReqs) != 0 || len(errRess) != 1 {
+				t.Fatalf("Test case %s: Expected the req to be invalid, got %d valid and %d errors", tt.name, len(validReqs), len(errRess))
+			}
+			if errRess[0].Error.Code != tt.expectedCode || !strings.Contains(errRess[0].Error.Message, tt.expectedMessage) {
+				t.Errorf("Test case %s: Expected code %d and message %s, got %v", tt.name, tt.expectedCode, tt.expectedMessage, errRess[0].Error)
+			}
+		})
+	}
+}
+
+func TestLoadPluginErrors(t *testing.T) {
+	gettersOutput := `{"getters":[]}`
+	tests := []struct {
+		name   string
+		plugin Plugin
+	}{
+		{
+			name:   "Missing file",
+			plugin: Plugin{Path: filepath.Join(t.TempDir(), "missing.wasm")},
+		},
+		{
+			name:   "Invalid module",
+			plugin: Plugin{Path: writeTestPlugin(t, []byte("not wasm"))},
+		},
+		{
+			name:   "Memory over limit",
+			plugin: Plugin{Path: writeTestPlugin(t, testPluginModule(32, returnOutputBody(16, gettersOutput), nil, map[int]string{16: gettersOutput})), MemoryLimitMb: 1},
+		},
+		{
+			name:   "Memory limit over max",
+			plugin: Plugin{Path: writeTestPlugin(t, testPluginModule(1, returnOutputBody(16, gettersOutput), nil, map[int]string{16: gettersOutput})), MemoryLimitMb: 4097},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadPlugin(tt.plugin)
+			if err == nil {
+				t.Errorf("Test case %s: Expected an error", tt.name)
+			}
+		})
+	}
+}
+
+func TestReadConfigFilesPluginPath(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "evm.json")
+	err := os.WriteFile(file, []byte(`{"chainType":"evm","methods":[{"customMethod":"eth_getLogsAndBlockRange","originalMethod":"eth_getLogs","plugin":{"path":"plugins/get_logs.wasm"}},{"customMethod":"eth_callAndBlock","originalMethod":"eth_call","plugin":{"path":"/plugins/call.wasm"}}]}`), 0o600)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+
+	configs := ReadConfigFiles(file)
+	expectedPaths := []string{filepath.Join(dir, "plugins", "get_logs.wasm"), "/plugins/call.wasm"}
+	for i, expectedPath := range expectedPaths {
+		if configs[0].Methods[i].Plugin.Path != expectedPath {
+			t.Errorf("Expected plugin path %s, got %s", expectedPath, configs[0].Methods[i].Plugin.Path)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package gettercache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/metrics"
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// DefaultMaxEntries bounds the entries of the cache, block hashes come from the client so there is no natural bound
+const DefaultMaxEntries = 1024
+
+type entry struct {
+	res       *models.RPCResJSON
+	expiresAt time.Time
+}
+
+// Cache holds the responses of the getter reqs by the index of their id holder (e.g. latest, finalized or a block hash)
+// so the getter reqs don't need to be sent on every request, a cache must only be used for the upstreams of a single chain
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+	mu         sync.RWMutex
+	entries    map[string]entry
+}
+
+// New returns a cache that keeps the getter responses for the ttl, usually the block time of the chain
+func New(ttl time.Duration, maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[string]entry{},
+	}
+}
+
+// Get returns the getter response of the index if it is fresh
+func (c *Cache) Get(index string) (*models.RPCResJSON, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.entries[index]
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, false
+	}
+
+	return e.res, true
+}
+
+// Set saves the getter response of the index, error responses are not saved
+func (c *Cache) Set(index string, res *models.RPCResJSON) {
+	if res == nil || res.Error != nil || !res.HasResult() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	_, ok := c.entries[index]
+	if !ok && len(c.entries) >= c.maxEntries {
+		for i, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, i)
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			return
+		}
+	}
+
+	c.entries[index] = entry{
+		res:       res,
+		expiresAt: now.Add(c.ttl),
+	}
+}
+
+// UseCached removes the getter reqs that have a fresh response on the cache from the rpc reqs
+// and returns the cached responses with the ids of the removed getter reqs
+func (c *Cache) UseCached(rpcReqs []*models.RPCReq, idsHolder map[string]string) ([]*models.RPCReq, []*models.RPCResJSON) {
+	cachedIDs := make(map[string]bool, len(idsHolder))
+	var cachedRess []*models.RPCResJSON
+	for index, id := range idsHolder {
+		res, ok := c.Get(index)
+		metrics.ObserveGetterCache(ok)
+		if !ok {
+			continue
+		}
+
+		cachedIDs[id] = true
+		cachedRess = append(cachedRess, &models.RPCResJSON{
+			JSONRPC: res.JSONRPC,
+			Result:  res.Result,
+			ID:      json.RawMessage(id),
+		})
+	}
+
+	if len(cachedRess) == 0 {
+		return rpcReqs, nil
+	}
+
+	reqs := make([]*models.RPCReq, 0, len(rpcReqs)-len(cachedRess))
+	for _, req := range rpcReqs {
+		if !cachedIDs[string(req.ID)] {
+			reqs = append(reqs, req)
+		}
+	}
+
+	return reqs, cachedRess
+}
+
+// SaveGetterRess saves the responses of the getter reqs of the ids holder that are on the responses
+func (c *Cache) SaveGetterRess(ress []*models.RPCResJSON, idsHolder map[string]string) {
+	if len(idsHolder) == 0 {
+		return
+	}
+
+	idToIndex := make(map[string]string, len(idsHolder))
+	for index, id := range idsHolder {
+		idToIndex[id] = index
+	}
+
+	for _, res := range ress {
+		index, ok := idToIndex[string(res.ID)]
+		if ok {
+			c.Set(index, res)
+		}
+	}
+}
+
+// StartHeadPoller saves the getter response returned by fetch every interval until the context is done
+// so the most used getter is resolved without waiting for a request to miss the cache
+func (c *Cache) StartHeadPoller(ctx context.Context, interval time.Duration, index string, fetch func(ctx context.Context) (*models.RPCResJSON, error), logger *slog.Logger) {
+	if interval <= 0 {
+		return
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	poll := func() {
+		res, err := fetch(ctx)
+		if err != nil {
+			logger.Warn("Head poll failed", slog.String("index", index), slog.String("error", err.Error()))
+			return
+		}
+		c.Set(index, res)
+	}
+
+	poll()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				poll()
+			}
+		}
+	}()
+}
@@ -0,0 +1,122 @@
+package gettercache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+func newGetterRes(id string, number string) *models.RPCResJSON {
+	return &models.RPCResJSON{
+		JSONRPC: "2.0",
+		Result:  json.RawMessage(fmt.Sprintf(`{"number":"%s"}`, number)),
+		ID:      json.RawMessage(id),
+	}
+}
+
+func TestUseCached(t *testing.T) {
+	cache := New(time.Minute, 0)
+	cache.SaveGetterRess([]*models.RPCResJSON{
+		{JSONRPC: "2.0", Result: json.RawMessage(`"0x1"`), ID: json.RawMessage("1")},
+		newGetterRes("100", "0x21"),
+	}, map[string]string{"latest": "100"})
+
+	reqs := []*models.RPCReq{
+		{Method: "eth_getBalance", ID: json.RawMessage("1")},
+		{Method: "eth_getBlockByNumber", ID: json.RawMessage("200")},
+		{Method: "eth_getBlockByNumber", ID: json.RawMessage("201")},
+	}
+
+	reqs, cachedRess := cache.UseCached(reqs, map[string]string{"latest": "200", "finalized": "201"})
+
+	if len(reqs) != 2 || string(reqs[0].ID) != "1" || string(reqs[1].ID) != "201" {
+		t.Errorf("Expected cached getter req to be removed, got %v", reqs)
+	}
+
+	if len(cachedRess) != 1 {
+		t.Fatalf("Expected 1 cached response, got %d", len(cachedRess))
+	}
+	if string(cachedRess[0].ID) != "200" {
+		t.Errorf("Expected cached response with the id of the getter req, got %s", cachedRess[0].ID)
+	}
+	if string(cachedRess[0].Result) != `{"number":"0x21"}` {
+		t.Errorf("Expected cached result 0x21, got %v", cachedRess[0].Result)
+	}
+}
+
+func TestCacheExpiration(t *testing.T) {
+	cache := New(10*time.Millisecond, 0)
+	cache.Set("latest", newGetterRes("1", "0x21"))
+
+	_, ok := cache.Get("latest")
+	if !ok {
+		t.Fatalf("Expected fresh entry")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, ok = cache.Get("latest")
+	if ok {
+		t.Errorf("Expected expired entry")
+	}
+}
+
+func TestCacheSetErrorsAndMaxEntries(t *testing.T) {
+	cache := New(time.Minute, 2)
+
+	cache.Set("latest", &models.RPCResJSON{Error: &models.RPCErr{Code: -32000, Message: "header not found"}})
+	_, ok := cache.Get("latest")
+	if ok {
+		t.Errorf("Expected error response to not be saved")
+	}
+
+	cache.Set("latest", newGetterRes("1", "0x21"))
+	cache.Set("finalized", newGetterRes("1", "0x20"))
+	cache.Set("safe", newGetterRes("1", "0x1f"))
+
+	_, ok = cache.Get("safe")
+	if ok {
+		t.Errorf("Expected entry over the max entries to not be saved")
+	}
+
+	cache.Set("latest", newGetterRes("1", "0x22"))
+	res, ok := cache.Get("latest")
+	if !ok || string(res.Result) != `{"number":"0x22"}` {
+		t.Errorf("Expected existing entry to be updated, got %v", res)
+	}
+}
+
+func TestStartHeadPoller(t *testing.T) {
+	cache := New(time.Minute, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	polls := make(chan struct{}, 10)
+	number := 0x20
+	cache.StartHeadPoller(ctx, 5*time.Millisecond, "latest", func(ctx context.Context) (*models.RPCResJSON, error) {
+		number++
+		select {
+		case polls <- struct{}{}:
+		default:
+		}
+		return newGetterRes("1", fmt.Sprintf("0x%x", number)), nil
+	}, nil)
+
+	// the first poll is done before returning
+	_, ok := cache.Get("latest")
+	if !ok {
+		t.Fatalf("Expected entry of the first poll")
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-polls:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected head poller to keep polling")
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	customrpcmethods "github.com/stateless-solutions/compatibility-layer/custom-rpc-methods"
 	"github.com/stateless-solutions/compatibility-layer/models"
 	rpccontext "github.com/stateless-solutions/compatibility-layer/rpc-context"
+	"github.com/stateless-solutions/compatibility-layer/upstream"
 )
 
 var urlFlag string
@@ -119,6 +120,18 @@ func TestIntegration(t *testing.T) {
 				Logger:             slog.Default(),
 			}
 
+			// upstreams of chain types with a REST API are called through their transport
+			chainTypes := ch.GetChainTypes()
+			translator, ok := customrpcmethods.ChainTypeToRESTTranslator[chainTypes[0]]
+			if ok && len(chainTypes) == 1 {
+				pool, err := upstream.NewPool([]string{urlFlag}, upstream.PolicyRoundRobin, nil, slog.Default())
+				if err != nil {
+					panic(err)
+				}
+				pool.SetTransport(rpccontext.NewRESTTransport(translator, nil, 0))
+				context.Upstreams = pool
+			}
+
 			// Create a handler using AttestorHandler
 			handler := http.HandlerFunc(context.Handler)
 
@@ -0,0 +1,180 @@
+// Package jcs implements the JSON Canonicalization Scheme of RFC 8785
+// so the hashes of the attestations can be recomputed by verifiers in any language
+package jcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Scheme is the name of the canonicalization stated on the attestations
+const Scheme = "jcs"
+
+var ErrInvalidNumber = errors.New("number can't be represented as an IEEE 754 double")
+
+// Transform returns the canonical form of the JSON data
+// numbers are serialized as ECMAScript doubles as RFC 8785 mandates, integers that are not exact doubles (e.g. 2^53 + 1)
+// return ErrInvalidNumber since their canonical form would be the one of another integer
+func Transform(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf, v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Marshal returns the canonical JSON of v
+func Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return Transform(data)
+}
+
+func write(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		return writeNumber(buf, v)
+	case string:
+		return writeString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := write(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		// keys are sorted by their UTF-16 code units, not by their UTF-8 bytes
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeString(buf, k); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := write(buf, v[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unexpected JSON type %T", v)
+	}
+
+	return nil
+}
+
+// writeNumber writes the number as ECMAScript's Number.prototype.toString does
+func writeNumber(buf *bytes.Buffer, n json.Number) error {
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return ErrInvalidNumber
+	}
+	if !strings.ContainsAny(string(n), ".eE") {
+		i, ok := new(big.Int).SetString(string(n), 10)
+		exact, _ := big.NewFloat(f).Int(nil)
+		if !ok || i.Cmp(exact) != 0 {
+			return ErrInvalidNumber
+		}
+	}
+
+	// -0 is serialized as 0
+	if f == 0 {
+		buf.WriteByte('0')
+		return nil
+	}
+
+	// encoding/json formats floats with the ECMAScript rules
+	data, err := json.Marshal(f)
+	if err != nil {
+		return ErrInvalidNumber
+	}
+	buf.Write(data)
+
+	return nil
+}
+
+// writeString escapes only the quote, the backslash and the control characters, the rest is written as UTF-8
+// invalid UTF-8 was already replaced by the decoder
+func writeString(buf *bytes.Buffer, s string) error {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+
+	return nil
+}
+
+func lessUTF16(a, b string) bool {
+	ua := utf16.Encode([]rune(a))
+	ub := utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+
+	return len(ua) < len(ub)
+}
@@ -0,0 +1,99 @@
+package jcs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "Whitespace And Key Order",
+			input:    `{ "b": [1, 2, {"d": true, "c": null}], "a": "x" }`,
+			expected: `{"a":"x","b":[1,2,{"c":null,"d":true}]}`,
+		},
+		{
+			// sample of RFC 8785 section 3.2.3
+			name:     "UTF-16 Key Order",
+			input:    `{"\u20ac":"Euro Sign","\r":"Carriage Return","\ufb33":"Hebrew Letter Dalet With Dagesh","1":"One","\ud83d\ude00":"Emoji: Grinning Face","\u0080":"Control","\u00f6":"Latin Small Letter O With Diaeresis"}`,
+			expected: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001F600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			name:     "String Escapes",
+			input:    `["\u0001\u001f\b\f\n\r\t\"\\/<>&\u2028\u007f"]`,
+			expected: "[\"\\u0001\\u001f\\b\\f\\n\\r\\t\\\"\\\\/<>&\u2028\u007f\"]",
+		},
+		{
+			// values of RFC 8785 appendix B
+			name:     "Numbers",
+			input:    `[0, -0, 5e-324, -5e-324, 1.7976931348623157e308, 9007199254740992, -9007199254740992, 295147905179352825856, 9.999999999999997e22, 1e23, 0.000001, 9.999999999999997e-7, 333333333.33333329, 1E2, 1.50]`,
+			expected: `[0,0,5e-324,-5e-324,1.7976931348623157e+308,9007199254740992,-9007199254740992,295147905179352830000,9.999999999999997e+22,1e+23,0.000001,9.999999999999997e-7,333333333.3333333,100,1.5]`,
+		},
+		{
+			name:      "Number Out Of Range",
+			input:     `[1e400]`,
+			expectErr: true,
+		},
+		{
+			name:      "Integer Not Exact Double",
+			input:     `{"value":9007199254740993}`,
+			expectErr: true,
+		},
+		{
+			name:     "Exact Integer Above 2^53",
+			input:    `[9007199254740994, -18446744073709551616]`,
+			expected: `[9007199254740994,-18446744073709552000]`,
+		},
+		{
+			name:      "Trailing Data",
+			input:     `{} {}`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Transform([]byte(tt.input))
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Test case %s: Expected error, got %s", tt.name, output)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+
+			if string(output) != tt.expected {
+				t.Errorf("Test case %s: Expected %s, got %s", tt.name, tt.expected, output)
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	output, err := Marshal(map[string]interface{}{"data": "<0x1>", "blockNumber": "0x21"})
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+
+	expected := `{"blockNumber":"0x21","data":"<0x1>"}`
+	if string(output) != expected {
+		t.Errorf("Expected %s, got %s", expected, output)
+	}
+}
+
+func TestTransformDifferentIntegers(t *testing.T) {
+	// both integers are rounded to the same double, so neither can be canonicalized
+	for _, input := range []string{`[12345678901234567890]`, `[12345678901234567891]`} {
+		output, err := Transform([]byte(input))
+		if !errors.Is(err, ErrInvalidNumber) {
+			t.Errorf("Expected error %v for %s, got %s and %v", ErrInvalidNumber, input, output, err)
+		}
+	}
+}
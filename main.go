@@ -7,24 +7,49 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stateless-solutions/compatibility-layer/attestation"
 	customrpcmethods "github.com/stateless-solutions/compatibility-layer/custom-rpc-methods"
 	"github.com/stateless-solutions/compatibility-layer/environment"
+	gettercache "github.com/stateless-solutions/compatibility-layer/getter-cache"
+	"github.com/stateless-solutions/compatibility-layer/metrics"
+	"github.com/stateless-solutions/compatibility-layer/models"
 	rpccontext "github.com/stateless-solutions/compatibility-layer/rpc-context"
+	"github.com/stateless-solutions/compatibility-layer/tracing"
+	"github.com/stateless-solutions/compatibility-layer/upstream"
 )
 
 var (
-	defaultChainURL = environment.GetString("DEFAULT_CHAIN_URL", "")
-	useAttestation  = environment.GetBool("USE_ATTESTATION", false)
-	keyFile         = environment.GetString("KEY_FILE", "")
-	keyFilePassword = environment.GetString("KEY_FILE_PASSWORD", "")
-	identity        = environment.GetString("IDENTITY", "")
-	httpPort        = environment.GetString("HTTP_PORT", "8080")
-	configFiles     = environment.GetString("CONFIG_FILES", "supported-chains/ethereum.json")
-	logLevel        = slog.Level(environment.GetInt64("LOG_LEVEL", int64(slog.LevelInfo)))
-	gatewayMode     = environment.GetBool("GATEWAY_MODE", false)
+	defaultChain      = environment.GetString("DEFAULT_CHAIN_NAME", "default")
+	defaultChainURL   = environment.GetString("DEFAULT_CHAIN_URL", "")
+	defaultChainWSURL = environment.GetString("DEFAULT_CHAIN_WS_URL", "")
+	useAttestation    = environment.GetBool("USE_ATTESTATION", false)
+	attestationVer    = environment.GetInt64("ATTESTATION_VERSION", attestation.Version1)
+	keyFile           = environment.GetString("KEY_FILE", "")
+	keyFilePassword   = environment.GetString("KEY_FILE_PASSWORD", "")
+	identity          = environment.GetString("IDENTITY", "")
+	httpPort          = environment.GetString("HTTP_PORT", "8080")
+	configFiles       = environment.GetString("CONFIG_FILES", "supported-chains/ethereum.json")
+	logLevel          = slog.Level(environment.GetInt64("LOG_LEVEL", int64(slog.LevelInfo)))
+	gatewayMode       = environment.GetBool("GATEWAY_MODE", false)
+	pinnedMode        = environment.GetBool("PINNED_MODE", false)
+	upstreamPolicy    = environment.GetString("UPSTREAM_POLICY", string(upstream.PolicyRoundRobin))
+	healthCheckSecs   = environment.GetInt64("HEALTH_CHECK_INTERVAL", 10)
+	upstreamTimeoutMs = environment.GetInt64("UPSTREAM_TIMEOUT_MS", upstream.DefaultTimeout.Milliseconds())
+	useGetterCache    = environment.GetBool("GETTER_CACHE", false)
+	useHeadPoller     = environment.GetBool("HEAD_POLLER", false)
+	useResponseCache  = environment.GetBool("RESPONSE_CACHE", false)
+	responseCacheSize = environment.GetInt64("RESPONSE_CACHE_SIZE", rpccontext.DefaultResponseCacheEntries)
+	streamResponses   = environment.GetBool("STREAM_RESPONSES", false)
+	maxRequestBody    = environment.GetInt64("MAX_REQUEST_BODY_SIZE", 10<<20)
+	maxResponseSize   = environment.GetInt64("MAX_RESPONSE_SIZE", 128<<20)
+	wsAllowedOrigins  = environment.GetString("WS_ALLOWED_ORIGINS", "")
+	useTracing        = environment.GetBool("USE_TRACING", false)
+	tracingService    = environment.GetString("TRACING_SERVICE_NAME", "compatibility-layer")
 )
 
 func main() {
@@ -32,18 +57,95 @@ func main() {
 		Level: logLevel,
 	}))
 
-	ch := customrpcmethods.NewCustomMethodHolder(gatewayMode, configFiles)
+	if useTracing {
+		// the exporter is configured with the standard OTEL_EXPORTER_OTLP_* env vars
+		exporter, err := tracing.NewOTLPExporter(context.Background())
+		if err != nil {
+			panic(err)
+		}
+		tp := tracing.Setup(exporter, tracingService)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := tp.Shutdown(ctx); err != nil {
+				logger.Error("Tracer provider shutdown failed", slog.String("error", err.Error()))
+			}
+		}()
+	}
+
+	configs := customrpcmethods.ReadConfigFiles(configFiles)
+	ch := customrpcmethods.NewCustomMethodHolderFromConfigs(gatewayMode, configs)
+
+	healthCtx, stopHealthChecks := context.WithCancel(context.Background())
+	defer stopHealthChecks()
+
+	// the store is shared by the chains since the chain is part of the keys
+	var responseStore rpccontext.ResponseStore
+	if useResponseCache {
+		responseStore = rpccontext.NewLRUStore(int(responseCacheSize))
+	}
+
+	var upstreams *upstream.Pool
+	var getterCache *gettercache.Cache
+	var responseCache *rpccontext.ResponseCache
+	if defaultChainURL != "" {
+		// health checks and the getter cache need to know the chain type of the upstreams
+		var healthChecker upstream.HealthChecker
+		chainTypes := ch.GetChainTypes()
+		if len(chainTypes) == 1 {
+			healthChecker = customrpcmethods.ChainTypeToPublicData[chainTypes[0]]
+		}
+
+		var err error
+		metrics.RegisterUpstreams(strings.Split(defaultChainURL, ","))
+		upstreams, err = upstream.NewPool(strings.Split(defaultChainURL, ","), upstream.Policy(upstreamPolicy), healthChecker, logger)
+		if err != nil {
+			panic(err)
+		}
+		upstreams.SetTimeout(time.Duration(upstreamTimeoutMs) * time.Millisecond)
+		if len(chainTypes) == 1 {
+			setRESTTransport(upstreams, chainTypes[0])
+		}
+		upstreams.StartHealthChecks(healthCtx, time.Duration(healthCheckSecs)*time.Second)
+
+		if useGetterCache && len(chainTypes) == 1 {
+			getterCache = newGetterCache(healthCtx, ch, chainTypes[0], minBlockTime(configs), upstreams, logger)
+		}
+
+		if responseStore != nil && len(chainTypes) == 1 {
+			responseCache = newResponseCache(healthCtx, defaultChain, chainTypes[0], minBlockTime(configs), responseStore, upstreams, logger)
+		}
+	}
+
+	chainRoutes := buildChainRoutes(healthCtx, configs, upstreams, getterCache, responseCache, responseStore, logger)
 
 	rpcContext := &rpccontext.RPCContext{
 		Identity:           identity,
-		DefaultChainURL:    defaultChainURL,
+		DefaultChain:       defaultChain,
+		DefaultChainWSURL:  defaultChainWSURL,
+		Upstreams:          upstreams,
+		ChainRoutes:        chainRoutes,
 		HTTPPort:           httpPort,
 		CustomMethodHolder: ch,
+		GetterCache:        getterCache,
+		ResponseCache:      responseCache,
+		PinnedMode:         pinnedMode,
+		StreamResponses:    streamResponses,
+		MaxRequestBodySize: maxRequestBody,
+		MaxResponseSize:    maxResponseSize,
 		Logger:             logger,
 	}
 
+	if wsAllowedOrigins != "" {
+		rpcContext.WSAllowedOrigins = strings.Split(wsAllowedOrigins, ",")
+	}
+
 	if useAttestation {
+		if attestationVer != attestation.Version1 && attestationVer != attestation.Version2 {
+			panic(fmt.Sprintf("ATTESTATION_VERSION %d is not supported", attestationVer))
+		}
 		rpcContext.EnableAttestation(keyFile, keyFilePassword, identity)
+		rpcContext.AttestationVersion = int(attestationVer)
 	}
 
 	srv := &http.Server{
@@ -53,6 +155,11 @@ func main() {
 
 	// Start the server on the specified port
 	http.HandleFunc("/rpc", rpcContext.Handler)
+	http.HandleFunc("/ws", rpcContext.WSHandler)
+	http.HandleFunc("/rpc/{chainName}", rpcContext.ChainHandler)
+	http.HandleFunc("/ws/{chainName}", rpcContext.ChainWSHandler)
+
+	http.Handle("/metrics", promhttp.Handler())
 
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -83,3 +190,121 @@ func main() {
 
 	logger.Info("Server exiting")
 }
+
+// buildChainRoutes returns a route for each chain name of the configs with the methods and upstreams of its config
+// configs without upstreams use the default ones and their caches, it is logged when there are more configs
+// since the default upstreams must serve all of them
+func buildChainRoutes(ctx context.Context, configs []customrpcmethods.MethodsConfig, defaultUpstreams *upstream.Pool, defaultGetterCache *gettercache.Cache, defaultResponseCache *rpccontext.ResponseCache, responseStore rpccontext.ResponseStore, logger *slog.Logger) map[string]*rpccontext.ChainRoute {
+	chainRoutes := make(map[string]*rpccontext.ChainRoute)
+	for _, config := range configs {
+		route := &rpccontext.ChainRoute{
+			CustomMethodHolder: customrpcmethods.NewCustomMethodHolderFromConfigs(gatewayMode, []customrpcmethods.MethodsConfig{config}),
+			Upstreams:          defaultUpstreams,
+			GetterCache:        defaultGetterCache,
+			ResponseCache:      defaultResponseCache,
+			ChainWSURL:         defaultChainWSURL,
+		}
+
+		if len(config.Upstreams) == 0 && len(configs) > 1 && len(config.ChainNames) > 0 {
+			logger.Warn("Chain routes without upstreams use the ones of DEFAULT_CHAIN_URL", slog.String("chainNames", strings.Join(config.ChainNames, ",")))
+		}
+
+		if len(config.Upstreams) > 0 {
+			policy := config.UpstreamPolicy
+			if policy == "" {
+				policy = upstreamPolicy
+			}
+
+			metrics.RegisterUpstreams(config.Upstreams)
+			pool, err := upstream.NewPool(config.Upstreams, upstream.Policy(policy), customrpcmethods.ChainTypeToPublicData[config.ChainType], logger)
+			if err != nil {
+				panic(err)
+			}
+			pool.SetTimeout(time.Duration(upstreamTimeoutMs) * time.Millisecond)
+			setRESTTransport(pool, config.ChainType)
+			pool.StartHealthChecks(ctx, time.Duration(healthCheckSecs)*time.Second)
+			route.Upstreams = pool
+			route.GetterCache = nil
+			route.ResponseCache = nil
+
+			if useGetterCache {
+				route.GetterCache = newGetterCache(ctx, route.CustomMethodHolder, config.ChainType, config.GetBlockTime(), pool, logger)
+			}
+			if responseStore != nil && len(config.ChainNames) > 0 {
+				route.ResponseCache = newResponseCache(ctx, config.ChainNames[0], config.ChainType, config.GetBlockTime(), responseStore, pool, logger)
+			}
+		}
+
+		if config.WSUpstream != "" {
+			route.ChainWSURL = config.WSUpstream
+		}
+
+		for _, chainName := range config.ChainNames {
+			_, ok := chainRoutes[chainName]
+			if ok {
+				panic(fmt.Sprintf("chain name %s is repeated on the config files", chainName))
+			}
+			chainRoutes[chainName] = route
+		}
+	}
+
+	return chainRoutes
+}
+
+// setRESTTransport makes the pool translate the rpc reqs to the REST API of the upstreams if the chain type has one
+func setRESTTransport(pool *upstream.Pool, chainType customrpcmethods.ChainType) {
+	translator, ok := customrpcmethods.ChainTypeToRESTTranslator[chainType]
+	if ok {
+		pool.SetTransport(rpccontext.NewRESTTransport(translator, nil, maxResponseSize))
+	}
+}
+
+// newGetterCache returns a getter cache of the upstreams of the pool that keeps the getters for a block time
+// the head poller is started if it is enabled
+func newGetterCache(ctx context.Context, ch *customrpcmethods.CustomMethodHolder, chainType customrpcmethods.ChainType, blockTime time.Duration, pool *upstream.Pool, logger *slog.Logger) *gettercache.Cache {
+	cache := gettercache.New(blockTime, gettercache.DefaultMaxEntries)
+
+	if useHeadPoller {
+		req, index, err := ch.BuildDefaultGetterReq(chainType, "1")
+		if err != nil {
+			panic(err)
+		}
+
+		// polled twice per block time so the default getter doesn't expire between polls
+		cache.StartHeadPoller(ctx, blockTime/2, index, func(ctx context.Context) (*models.RPCResJSON, error) {
+			return pool.Call(ctx, req)
+		}, logger)
+	}
+
+	return cache
+}
+
+// newResponseCache returns a response cache of the upstreams of the pool on the store
+// the finalized height is polled every block time, until it is known only block hashes are immutable
+func newResponseCache(ctx context.Context, chain string, chainType customrpcmethods.ChainType, blockTime time.Duration, store rpccontext.ResponseStore, pool *upstream.Pool, logger *slog.Logger) *rpccontext.ResponseCache {
+	cache := rpccontext.NewResponseCache(chain, store)
+	publicData := customrpcmethods.ChainTypeToPublicData[chainType]
+	req := publicData.GetFinalizedHeightReq()
+
+	cache.StartFinalizedPoller(ctx, blockTime, func(ctx context.Context) (uint64, error) {
+		res, err := pool.Call(ctx, req)
+		if err != nil {
+			return 0, err
+		}
+		return publicData.ExtractFinalizedHeight(res)
+	}, logger)
+
+	return cache
+}
+
+// minBlockTime returns the lowest block time of the configs
+func minBlockTime(configs []customrpcmethods.MethodsConfig) time.Duration {
+	var blockTime time.Duration
+	for _, config := range configs {
+		if blockTime == 0 || config.GetBlockTime() < blockTime {
+			blockTime = config.GetBlockTime()
+		}
+	}
+
+	return blockTime
+}
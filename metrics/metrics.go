@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	namespace = "compatibility_layer"
+
+	// OtherMethod is the label of the methods that are not on the config files, it avoids unbounded labels from client input
+	OtherMethod = "other"
+	// OtherUpstream is the label of the upstreams that are not configured, e.g. the ones of the Stateless-Chain-URL header
+	OtherUpstream = "other"
+
+	StatusSuccess = "success"
+	StatusError   = "error"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_requests_total",
+		Help:      "Number of rpc requests by method and status.",
+	}, []string{"method", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "Duration of the http requests that contained the rpc method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	stageFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stage_failures_total",
+		Help:      "Number of failed http requests by the stage of the handler that failed.",
+	}, []string{"stage"})
+
+	upstreamResponsesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_responses_total",
+		Help:      "Number of upstream responses by upstream host and status code, transport errors have the error status code.",
+	}, []string{"upstream", "status_code"})
+
+	upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Duration of the requests to the upstreams by upstream host.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"upstream"})
+
+	getterRequestsInjectedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "getter_requests_injected_total",
+		Help:      "Number of getter requests added to the requests of custom methods.",
+	})
+
+	getterCacheRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "getter_cache_requests_total",
+		Help:      "Number of lookups of getter resolutions on the cache by result.",
+	}, []string{"result"})
+
+	responseCacheRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "response_cache_requests_total",
+		Help:      "Number of lookups of immutable responses on the cache by result.",
+	}, []string{"result"})
+
+	// configuredUpstreams are the hosts of the configured upstreams, the only ones used as labels
+	configuredUpstreams sync.Map
+
+	attestationDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "attestation_signing_duration_seconds",
+		Help:      "Duration of the signing of the attestations.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 12),
+	})
+)
+
+// ObserveRequest records the status and duration of the http request for each of its rpc methods
+func ObserveRequest(methods []string, status string, duration time.Duration) {
+	for _, method := range methods {
+		requestsTotal.WithLabelValues(method, status).Inc()
+		requestDuration.WithLabelValues(method).Observe(duration.Seconds())
+	}
+}
+
+// ObserveStageFailure records the stage of the handler where the request failed
+func ObserveStageFailure(stage string) {
+	stageFailuresTotal.WithLabelValues(stage).Inc()
+}
+
+// upstreamHost returns the host of the upstream URL, the URL itself if it has no host
+func upstreamHost(upstreamURL string) string {
+	parsedURL, err := url.Parse(upstreamURL)
+	if err == nil && parsedURL.Host != "" {
+		return parsedURL.Host
+	}
+
+	return upstreamURL
+}
+
+// RegisterUpstreams sets the upstreams whose host is used as label, the rest are labeled as OtherUpstream
+func RegisterUpstreams(upstreamURLs []string) {
+	for _, upstreamURL := range upstreamURLs {
+		configuredUpstreams.Store(upstreamHost(upstreamURL), true)
+	}
+}
+
+// ObserveUpstreamResponse records the status code and duration of an upstream request
+// only the host of the upstream is used as label to not leak keys that are part of the URL path
+// and upstreams that are not configured share a label to avoid unbounded labels from client input
+func ObserveUpstreamResponse(upstreamURL, statusCode string, duration time.Duration) {
+	host := upstreamHost(upstreamURL)
+	if _, ok := configuredUpstreams.Load(host); !ok {
+		host = OtherUpstream
+	}
+
+	upstreamResponsesTotal.WithLabelValues(host, statusCode).Inc()
+	upstreamDuration.WithLabelValues(host).Observe(duration.Seconds())
+}
+
+// ObserveGetterRequestsInjected records the number of getter requests added to a request
+func ObserveGetterRequestsInjected(count int) {
+	getterRequestsInjectedTotal.Add(float64(count))
+}
+
+// ObserveGetterCache records if the resolution of a getter was found on the cache
+func ObserveGetterCache(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	getterCacheRequestsTotal.WithLabelValues(result).Inc()
+}
+
+// ObserveResponseCache records if the response of an immutable request was found on the cache
+func ObserveResponseCache(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	responseCacheRequestsTotal.WithLabelValues(result).Inc()
+}
+
+// ObserveAttestation records the duration of the signing of an attestation
+func ObserveAttestation(duration time.Duration) {
+	attestationDuration.Observe(duration.Seconds())
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveRequest(t *testing.T) {
+	ObserveRequest([]string{"eth_getBalance", "eth_getBalance", OtherMethod}, StatusSuccess, time.Millisecond)
+
+	if count := testutil.ToFloat64(requestsTotal.WithLabelValues("eth_getBalance", StatusSuccess)); count != 2 {
+		t.Errorf("Expected 2 eth_getBalance requests, got %v", count)
+	}
+	if count := testutil.ToFloat64(requestsTotal.WithLabelValues(OtherMethod, StatusSuccess)); count != 1 {
+		t.Errorf("Expected 1 other request, got %v", count)
+	}
+}
+
+func TestObserveUpstreamResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		upstreamURL  string
+		statusCode   string
+		expectedHost string
+	}{
+		{
+			name:         "Key on path",
+			upstreamURL:  "https://eth.node.co/v2/secret-key",
+			statusCode:   "200",
+			expectedHost: "eth.node.co",
+		},
+		{
+			name:         "Host with port",
+			upstreamURL:  "http://localhost:8545",
+			statusCode:   "error",
+			expectedHost: "localhost:8545",
+		},
+		{
+			name:         "Upstream not configured",
+			upstreamURL:  "https://client.node.co/v2/key",
+			statusCode:   "200",
+			expectedHost: OtherUpstream,
+		},
+	}
+
+	RegisterUpstreams([]string{"https://eth.node.co/v2/secret-key", "http://localhost:8545"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ObserveUpstreamResponse(tt.upstreamURL, tt.statusCode, time.Millisecond)
+
+			if count := testutil.ToFloat64(upstreamResponsesTotal.WithLabelValues(tt.expectedHost, tt.statusCode)); count != 1 {
+				t.Errorf("Test case %s: Expected 1 response for host %s, got %v", tt.name, tt.expectedHost, count)
+			}
+		})
+	}
+}
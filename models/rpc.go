@@ -22,10 +22,10 @@ func (req RPCReq) LogAttrs() []slog.Attr {
 }
 
 type RPCErr struct {
-	Code          int    `json:"code"`
-	Message       string `json:"message"`
-	Data          string `json:"data,omitempty"`
-	HTTPErrorCode int    `json:"-"`
+	Code          int             `json:"code"`
+	Message       string          `json:"message"`
+	Data          json.RawMessage `json:"data,omitempty"` // kept raw since nodes return objects too, e.g. the context slot of Solana errors
+	HTTPErrorCode int             `json:"-"`
 }
 
 func (r *RPCErr) Error() string {
@@ -34,15 +34,22 @@ func (r *RPCErr) Error() string {
 
 type RPCResJSON struct {
 	JSONRPC string          `json:"jsonrpc"`
-	Result  interface{}     `json:"result,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
 	Error   *RPCErr         `json:"error,omitempty"`
 	ID      json.RawMessage `json:"id"`
+	// block number or slot the result of a custom method was resolved at, it is not part of the response
+	Block string `json:"-"`
+}
+
+// HasResult returns if the response has a non null result
+func (res *RPCResJSON) HasResult() bool {
+	return len(res.Result) > 0 && string(res.Result) != "null"
 }
 
 func (res RPCResJSON) LogAttrs() []slog.Attr {
 	attrs := []slog.Attr{
 		slog.String("jsonrpc", res.JSONRPC),
-		slog.Any("result", res.Result),
+		slog.String("result", string(res.Result)),
 		slog.String("id", string(res.ID)),
 	}
 
@@ -56,18 +63,34 @@ func (res RPCResJSON) LogAttrs() []slog.Attr {
 type Attestation struct {
 	SignatureFormat string `json:"signatureFormat,omitempty"`
 	HashAlgo        string `json:"hashAlgo,omitempty"`
-	Identiy         string `json:"identity,omitempty"`
-	MsgHash         string `json:"msg"`
-	Signature       string `json:"signature"`
+	// canonicalization of the JSON that is hashed, jcs (RFC 8785) or none for streamed results that are hashed as they are sent
+	Canonicalization string `json:"canonicalization,omitempty"`
+	Identiy          string `json:"identity,omitempty"`
+	MsgHash          string `json:"msg"`
+	Signature        string `json:"signature"`
+	// version 2 attestations sign a payload that binds the result to its request, they are not set on version 1
+	Version    int    `json:"version,omitempty"`
+	Chain      string `json:"chain,omitempty"`
+	Block      string `json:"block,omitempty"`
+	Timestamp  int64  `json:"timestamp,omitempty"`
+	Nonce      string `json:"nonce,omitempty"`
+	ResultHash string `json:"resultHash,omitempty"`
 }
 
 func (a Attestation) LogAttrs() []slog.Attr {
 	return []slog.Attr{
 		slog.String("signatureFormat", a.SignatureFormat),
 		slog.String("hashAlgo", a.HashAlgo),
+		slog.String("canonicalization", a.Canonicalization),
 		slog.String("identity", a.Identiy),
 		slog.String("msg", a.MsgHash),
 		slog.String("signature", a.Signature),
+		slog.Int("version", a.Version),
+		slog.String("chain", a.Chain),
+		slog.String("block", a.Block),
+		slog.Int64("timestamp", a.Timestamp),
+		slog.String("nonce", a.Nonce),
+		slog.String("resultHash", a.ResultHash),
 	}
 }
 
@@ -75,14 +98,14 @@ type RPCResJSONAttested struct {
 	JSONRPC     string          `json:"jsonrpc,omitempty"`
 	ID          json.RawMessage `json:"id,omitempty"`
 	Error       *RPCErr         `json:"error,omitempty"`
-	Result      interface{}     `json:"result,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
 	Attestation *Attestation    `json:"attestation,omitempty"`
 }
 
 func (res RPCResJSONAttested) LogAttrs() []slog.Attr {
 	attrs := []slog.Attr{
 		slog.String("jsonrpc", res.JSONRPC),
-		slog.Any("result", res.Result),
+		slog.String("result", string(res.Result)),
 		slog.String("id", string(res.ID)),
 	}
 
@@ -98,3 +121,32 @@ func (res RPCResJSONAttested) LogAttrs() []slog.Attr {
 
 	return attrs
 }
+
+type RPCNotificationParams struct {
+	Subscription json.RawMessage `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
+}
+
+type RPCNotification struct {
+	JSONRPC string                `json:"jsonrpc"`
+	Method  string                `json:"method"`
+	Params  RPCNotificationParams `json:"params"`
+	// block number or slot the result was resolved at, it is not part of the notification
+	Block string `json:"-"`
+}
+
+func (n RPCNotification) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("jsonrpc", n.JSONRPC),
+		slog.String("method", n.Method),
+		slog.String("subscription", string(n.Params.Subscription)),
+		slog.String("result", string(n.Params.Result)),
+	}
+}
+
+type RPCNotificationAttested struct {
+	JSONRPC     string                `json:"jsonrpc"`
+	Method      string                `json:"method"`
+	Params      RPCNotificationParams `json:"params"`
+	Attestation *Attestation          `json:"attestation,omitempty"`
+}
@@ -0,0 +1,122 @@
+package rpccontext
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	customrpcmethods "github.com/stateless-solutions/compatibility-layer/custom-rpc-methods"
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+var nullID = json.RawMessage("null")
+
+// newRPCErr returns an error with the code and http status code of the base error and a specific message
+func newRPCErr(base *models.RPCErr, message string) *models.RPCErr {
+	return &models.RPCErr{
+		Code:          base.Code,
+		Message:       message,
+		HTTPErrorCode: base.HTTPErrorCode,
+	}
+}
+
+// toRPCErr returns the rpc error wrapped on err, other errors get the code of the fallback with the message of err
+func toRPCErr(err error, fallback *models.RPCErr) *models.RPCErr {
+	var rpcErr *models.RPCErr
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	return newRPCErr(fallback, err.Error())
+}
+
+// itemID returns the id of an invalid item of a batch, null if it can't be read
+func itemID(item json.RawMessage) json.RawMessage {
+	var withID struct {
+		ID json.RawMessage `json:"id"`
+	}
+	err := json.Unmarshal(item, &withID)
+	if err != nil || len(withID.ID) == 0 {
+		return nullID
+	}
+
+	return withID.ID
+}
+
+// errorRess returns a response with the error for each of the client reqs of the handler
+// items of a slice that were already invalid keep their own error and cached items keep their result
+func errorRess(rh *reqHandler, rpcErr *models.RPCErr) []*models.RPCResJSON {
+	var ress []*models.RPCResJSON
+	if rh != nil {
+		for _, id := range rh.ReqIDs {
+			ress = append(ress, &models.RPCResJSON{
+				JSONRPC: "2.0",
+				Error:   rpcErr,
+				ID:      id,
+			})
+		}
+		ress = append(ress, rh.CachedRess...)
+		ress = append(ress, rh.ErrRess...)
+	}
+
+	if len(ress) == 0 {
+		ress = []*models.RPCResJSON{{
+			JSONRPC: "2.0",
+			Error:   rpcErr,
+			ID:      nullID,
+		}}
+	}
+
+	return ress
+}
+
+// marshalRess marshals the responses as a slice or a single response, attested if attestation is enabled
+func (c *RPCContext) marshalRess(rh *reqHandler, ress []*models.RPCResJSON, isSlice bool) ([]byte, error) {
+	if c.UseAttestation {
+		attestedRess, err := c.attestRess(rh, ress)
+		if err != nil {
+			return nil, err
+		}
+		if isSlice {
+			return json.Marshal(attestedRess)
+		}
+		return json.Marshal(attestedRess[0])
+	}
+
+	if isSlice {
+		return json.Marshal(ress)
+	}
+	return json.Marshal(ress[0])
+}
+
+// marshalRPCError returns the body of the error as the response of the reqs of the handler
+func (c *RPCContext) marshalRPCError(rh *reqHandler, rpcErr *models.RPCErr) ([]byte, error) {
+	ress := errorRess(rh, rpcErr)
+	isSlice := rh != nil && rh.IsSlice
+
+	return c.marshalRess(rh, ress, isSlice)
+}
+
+// writeRPCError writes the error as a JSON-RPC response for each of the reqs of the handler
+// the handler can be nil if the reqs are not known yet, the response then has a null id
+func (c *RPCContext) writeRPCError(w http.ResponseWriter, rh *reqHandler, rpcErr *models.RPCErr) {
+	body, err := c.marshalRPCError(rh, rpcErr)
+	if err != nil {
+		c.Logger.Error("Marshal error response failed", slog.String("error", err.Error()))
+		http.Error(w, rpcErr.Message, http.StatusInternalServerError)
+		return
+	}
+
+	statusCode := rpcErr.HTTPErrorCode
+	if statusCode == 0 {
+		statusCode = customrpcmethods.ErrInternal.HTTPErrorCode
+	}
+
+	// the headers of the upstream response may have been copied already
+	w.Header().Del("Content-Encoding")
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
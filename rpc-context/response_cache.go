@@ -0,0 +1,279 @@
+package rpccontext
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/metrics"
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// DefaultResponseCacheEntries bounds the entries of the in-memory response store
+const DefaultResponseCacheEntries = 4096
+
+// ResponseStore saves the results of immutable requests by their key
+// it can be implemented by external stores to share the responses between instances
+type ResponseStore interface {
+	// Get returns the result of the key and if it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set saves the result of the key
+	Set(ctx context.Context, key string, value []byte) error
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// LRUStore is an in-memory response store that evicts the least recently used results
+type LRUStore struct {
+	maxEntries int
+	mu         sync.Mutex
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+// NewLRUStore returns an in-memory response store that keeps up to max entries
+func NewLRUStore(maxEntries int) *LRUStore {
+	if maxEntries <= 0 {
+		maxEntries = DefaultResponseCacheEntries
+	}
+
+	return &LRUStore{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+func (s *LRUStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	s.order.MoveToFront(elem)
+
+	return elem.Value.(*lruEntry).value, true, nil
+}
+
+func (s *LRUStore) Set(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[key]
+	if ok {
+		elem.Value.(*lruEntry).value = value
+		s.order.MoveToFront(elem)
+		return nil
+	}
+
+	s.entries[key] = s.order.PushFront(&lruEntry{key: key, value: value})
+	if s.order.Len() > s.maxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruEntry).key)
+	}
+
+	return nil
+}
+
+// ResponseCache holds the results of requests that can't change, the ones of cacheable methods
+// pinned to a block hash or to a block number at or below the finalized height of the chain
+type ResponseCache struct {
+	Chain           string
+	Store           ResponseStore
+	finalizedHeight atomic.Uint64
+}
+
+// NewResponseCache returns a response cache of the chain, the chain is part of the keys so a store can be shared
+func NewResponseCache(chain string, store ResponseStore) *ResponseCache {
+	return &ResponseCache{
+		Chain: chain,
+		Store: store,
+	}
+}
+
+// FinalizedHeight returns the last finalized height known, 0 if it is unknown
+func (rc *ResponseCache) FinalizedHeight() uint64 {
+	return rc.finalizedHeight.Load()
+}
+
+// SetFinalizedHeight saves the finalized height, lower heights are ignored since finality doesn't go back
+func (rc *ResponseCache) SetFinalizedHeight(height uint64) {
+	for {
+		current := rc.finalizedHeight.Load()
+		if height <= current || rc.finalizedHeight.CompareAndSwap(current, height) {
+			return
+		}
+	}
+}
+
+// StartFinalizedPoller saves the finalized height returned by fetch every interval until the context is done
+func (rc *ResponseCache) StartFinalizedPoller(ctx context.Context, interval time.Duration, fetch func(ctx context.Context) (uint64, error), logger *slog.Logger) {
+	if interval <= 0 {
+		return
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	poll := func() {
+		height, err := fetch(ctx)
+		if err != nil {
+			logger.Warn("Finalized height poll failed", slog.String("chain", rc.Chain), slog.String("error", err.Error()))
+			return
+		}
+		rc.SetFinalizedHeight(height)
+	}
+
+	poll()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				poll()
+			}
+		}
+	}()
+}
+
+// Key returns the key of the request on the store, made of the chain, the method and the canonicalized params
+func (rc *ResponseCache) Key(rpcReq *models.RPCReq) (string, error) {
+	params, err := canonicalParams(rpcReq.Params)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(rc.Chain))
+	h.Write([]byte{0})
+	h.Write([]byte(rpcReq.Method))
+	h.Write([]byte{0})
+	h.Write(params)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// canonicalParams returns the params without insignificant whitespace and with sorted object keys
+// numbers are kept as they were sent, unlike JCS, so different big integers don't share a key
+func canonicalParams(params json.RawMessage) ([]byte, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// useCachedRess removes the immutable reqs that have a cached result from the rpc reqs and saves their responses
+// the keys of the immutable reqs that were not found are saved to store their results
+func (c *RPCContext) useCachedRess(ctx context.Context, rh *reqHandler) {
+	finalizedHeight := rh.ResponseCache.FinalizedHeight()
+	rh.CacheKeys = map[string]string{}
+
+	rpcReqs := make([]*models.RPCReq, 0, len(rh.RPCReqs))
+	for _, req := range rh.RPCReqs {
+		if !rh.CustomMethodHolder.IsImmutableReq(req, finalizedHeight) {
+			rpcReqs = append(rpcReqs, req)
+			continue
+		}
+
+		key, err := rh.ResponseCache.Key(req)
+		if err != nil {
+			rpcReqs = append(rpcReqs, req)
+			continue
+		}
+
+		value, ok, err := rh.ResponseCache.Store.Get(ctx, key)
+		if err != nil {
+			c.Logger.Warn("Response cache get failed", slog.String("error", err.Error()))
+		}
+		var cached cachedResult
+		if ok {
+			cached, ok = decodeCachedResult(value)
+		}
+		metrics.ObserveResponseCache(ok)
+		if !ok {
+			rh.CacheKeys[string(req.ID)] = key
+			rpcReqs = append(rpcReqs, req)
+			continue
+		}
+
+		rh.CachedRess = append(rh.CachedRess, &models.RPCResJSON{
+			JSONRPC: "2.0",
+			Result:  cached.Result,
+			ID:      req.ID,
+			Block:   cached.Block,
+		})
+	}
+
+	rh.RPCReqs = rpcReqs
+	rh.setReqIDs()
+}
+
+// saveCachedRess stores the results of the immutable reqs that were forwarded, error responses are not stored
+func (c *RPCContext) saveCachedRess(ctx context.Context, rh *reqHandler) {
+	for _, res := range rh.RPCRess {
+		key, ok := rh.CacheKeys[string(res.ID)]
+		if !ok || res.Error != nil || !res.HasResult() {
+			continue
+		}
+
+		err := rh.ResponseCache.Store.Set(ctx, key, encodeCachedResult(cachedResult{Result: res.Result, Block: res.Block}))
+		if err != nil {
+			c.Logger.Warn("Response cache set failed", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// cachedResult is the value of the store, the block the result was resolved at is kept to bind the attestations
+type cachedResult struct {
+	Block  string          `json:"block"`
+	Result json.RawMessage `json:"result"`
+}
+
+// encodeCachedResult returns the value of the store, it is built by hand so the result keeps the bytes of the upstream
+func encodeCachedResult(cached cachedResult) []byte {
+	block, _ := json.Marshal(cached.Block)
+
+	value := make([]byte, 0, len(block)+len(cached.Result)+20)
+	value = append(value, `{"block":`...)
+	value = append(value, block...)
+	value = append(value, `,"result":`...)
+	value = append(value, cached.Result...)
+
+	return append(value, '}')
+}
+
+// decodeCachedResult returns the cached result of the value, values without a result are ignored
+func decodeCachedResult(value []byte) (cachedResult, bool) {
+	var cached cachedResult
+	err := json.Unmarshal(value, &cached)
+	if err != nil || len(cached.Result) == 0 {
+		return cachedResult{}, false
+	}
+
+	return cached, true
+}
@@ -0,0 +1,151 @@
+package rpccontext
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+func TestLRUStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLRUStore(2)
+
+	store.Set(ctx, "a", []byte("1"))
+	store.Set(ctx, "b", []byte("2"))
+
+	// a is used so b is the least recently used
+	_, ok, _ := store.Get(ctx, "a")
+	if !ok {
+		t.Fatalf("Expected entry a")
+	}
+	store.Set(ctx, "c", []byte("3"))
+
+	_, ok, _ = store.Get(ctx, "b")
+	if ok {
+		t.Errorf("Expected entry b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		_, ok, _ = store.Get(ctx, key)
+		if !ok {
+			t.Errorf("Expected entry %s", key)
+		}
+	}
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	cache := NewResponseCache("ethereum", NewLRUStore(0))
+
+	tests := []struct {
+		name      string
+		reqA      *models.RPCReq
+		reqB      *models.RPCReq
+		sameKey   bool
+		expectErr bool
+	}{
+		{
+			name:    "Whitespace And Key Order",
+			reqA:    &models.RPCReq{Method: "eth_getLogs", Params: json.RawMessage(`[{"fromBlock":"0x1","toBlock":"0x2"}]`)},
+			reqB:    &models.RPCReq{Method: "eth_getLogs", Params: json.RawMessage(`[ { "toBlock": "0x2", "fromBlock": "0x1" } ]`)},
+			sameKey: true,
+		},
+		{
+			name:    "Different Method",
+			reqA:    &models.RPCReq{Method: "eth_getBalance", Params: json.RawMessage(`["0x1","0x10"]`)},
+			reqB:    &models.RPCReq{Method: "eth_getBalanceAndBlockNumber", Params: json.RawMessage(`["0x1","0x10"]`)},
+			sameKey: false,
+		},
+		{
+			name:    "Different Params",
+			reqA:    &models.RPCReq{Method: "eth_getBalance", Params: json.RawMessage(`["0x1","0x10"]`)},
+			reqB:    &models.RPCReq{Method: "eth_getBalance", Params: json.RawMessage(`["0x1","0x11"]`)},
+			sameKey: false,
+		},
+		{
+			name:      "Invalid Params",
+			reqA:      &models.RPCReq{Method: "eth_getBalance", Params: json.RawMessage(`["0x1",`)},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyA, err := cache.Key(tt.reqA)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Test case %s: Expected error, got nil", tt.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+
+			keyB, err := cache.Key(tt.reqB)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+
+			if (keyA == keyB) != tt.sameKey {
+				t.Errorf("Test case %s: Expected same key %v, got keys %s and %s", tt.name, tt.sameKey, keyA, keyB)
+			}
+		})
+	}
+
+	otherChain := NewResponseCache("arbitrum", cache.Store)
+	req := &models.RPCReq{Method: "eth_getBalance", Params: json.RawMessage(`["0x1","0x10"]`)}
+	keyA, _ := cache.Key(req)
+	keyB, _ := otherChain.Key(req)
+	if keyA == keyB {
+		t.Errorf("Expected different keys for different chains")
+	}
+}
+
+func TestSetFinalizedHeight(t *testing.T) {
+	cache := NewResponseCache("ethereum", NewLRUStore(0))
+
+	cache.SetFinalizedHeight(10)
+	cache.SetFinalizedHeight(5)
+	if height := cache.FinalizedHeight(); height != 10 {
+		t.Errorf("Expected finalized height 10, got %d", height)
+	}
+}
+
+func TestCachedResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         []byte
+		expectedFound bool
+		expected      cachedResult
+	}{
+		{
+			name:          "Result With Block",
+			value:         encodeCachedResult(cachedResult{Result: json.RawMessage(`{"b": 18446744073709551617, "a":"<"}`), Block: "0x21"}),
+			expectedFound: true,
+			expected:      cachedResult{Result: json.RawMessage(`{"b": 18446744073709551617, "a":"<"}`), Block: "0x21"},
+		},
+		{
+			name:          "Result Without Block",
+			value:         encodeCachedResult(cachedResult{Result: json.RawMessage(`"0x1"`)}),
+			expectedFound: true,
+			expected:      cachedResult{Result: json.RawMessage(`"0x1"`)},
+		},
+		{
+			name:  "Raw Result",
+			value: []byte(`"0x1"`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cached, ok := decodeCachedResult(tt.value)
+			if ok != tt.expectedFound {
+				t.Fatalf("Test case %s: Expected found %v, got %v", tt.name, tt.expectedFound, ok)
+			}
+			if string(cached.Result) != string(tt.expected.Result) || cached.Block != tt.expected.Block {
+				t.Errorf("Test case %s: Expected %+v, got %+v", tt.name, tt.expected, cached)
+			}
+		})
+	}
+}
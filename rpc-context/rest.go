@@ -0,0 +1,250 @@
+package rpccontext
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	customrpcmethods "github.com/stateless-solutions/compatibility-layer/custom-rpc-methods"
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// RESTTransport is the transport of the upstreams that serve a REST API instead of JSON-RPC
+// the rpc reqs of the JSON-RPC requests are sent as REST requests to the URL of the upstream
+// and their responses are returned as a JSON-RPC response, so the requests, health checks and pollers are the same
+// rpc reqs that the translator keeps as JSON-RPC are sent one by one to their path
+type RESTTransport struct {
+	translator      customrpcmethods.RESTTranslator
+	base            http.RoundTripper
+	maxResponseSize int64 // max bytes of each REST response, 0 means no limit
+}
+
+// maxRESTCalls is the max number of REST requests of the same JSON-RPC request that are sent at the same time
+const maxRESTCalls = 16
+
+// restResult is the response of the REST request of an rpc req
+type restResult struct {
+	res        *models.RPCResJSON
+	statusCode int
+	header     http.Header
+	body       []byte // only kept on server errors, they are returned as they are
+	err        error
+}
+
+// NewRESTTransport returns a transport that translates the rpc reqs with the translator
+// the REST requests are sent with the base transport, the default one if it is nil
+// and their responses are read up to the max response size, 0 means no limit
+func NewRESTTransport(translator customrpcmethods.RESTTranslator, base http.RoundTripper, maxResponseSize int64) *RESTTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &RESTTransport{
+		translator:      translator,
+		base:            base,
+		maxResponseSize: maxResponseSize,
+	}
+}
+
+// RoundTrip sends the rpc reqs of the body of the request concurrently, up to maxRESTCalls at the same time
+// a server error or a failure of any of them fails the whole request so the pool fails over to the next upstream
+func (t *RESTTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil {
+		return nil, fmt.Errorf("request to %s has no body", req.URL.Host)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	rpcReqs, errRess, isSlice, err := parseRPCReqBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]restResult, len(rpcReqs))
+	indexes := make(chan int, len(rpcReqs))
+	for i := range rpcReqs {
+		indexes <- i
+	}
+	close(indexes)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(len(rpcReqs), maxRESTCalls); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = t.call(req, rpcReqs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	var header http.Header
+	ress := make([]*models.RPCResJSON, 0, len(rpcReqs)+len(errRess))
+	for _, result := range results {
+		if result.err != nil {
+			return nil, result.err
+		}
+		if result.statusCode >= http.StatusInternalServerError {
+			return newRESTResponse(req, result.statusCode, result.header, result.body), nil
+		}
+		if header == nil {
+			header = result.header
+		}
+		ress = append(ress, result.res)
+	}
+	ress = append(ress, errRess...)
+
+	var resBody []byte
+	if isSlice {
+		resBody, err = json.Marshal(ress)
+	} else {
+		resBody, err = json.Marshal(ress[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newRESTResponse(req, http.StatusOK, header, resBody), nil
+}
+
+// call sends the REST request of the rpc req, reqs that can't be translated are answered with the error
+func (t *RESTTransport) call(req *http.Request, rpcReq *models.RPCReq) restResult {
+	res := &models.RPCResJSON{
+		JSONRPC: "2.0",
+		ID:      rpcReq.ID,
+	}
+
+	restReq, err := t.translator.BuildRESTReq(rpcReq)
+	if err != nil {
+		res.Error = toRPCErr(err, customrpcmethods.ErrInvalidParams)
+		return restResult{res: res, statusCode: http.StatusOK}
+	}
+
+	newReq, err := newRESTRequest(req, restReq)
+	if err != nil {
+		return restResult{err: err}
+	}
+
+	resp, err := t.base.RoundTrip(newReq)
+	if err != nil {
+		return restResult{err: err}
+	}
+	defer resp.Body.Close()
+
+	result := restResult{
+		res:        res,
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+	}
+
+	respBody, err := readBody(resp.Body, t.maxResponseSize)
+	if errors.Is(err, errBodyTooLarge) {
+		res.Error = customrpcmethods.ErrResponseTooLarge // the upstream is healthy, so it is not failed over
+		result.statusCode = http.StatusOK
+		return result
+	}
+	if err != nil {
+		return restResult{err: err}
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		result.body = respBody
+		return result
+	}
+	if rpcErr := t.translator.ExtractRESTError(resp.StatusCode, respBody); rpcErr != nil {
+		res.Error = rpcErr
+		return result
+	}
+
+	switch {
+	case restReq.JSONRPC:
+		var rpcRes models.RPCResJSON
+		if json.Unmarshal(respBody, &rpcRes) != nil {
+			res.Error = newRPCErr(customrpcmethods.ErrInternal, "invalid response format")
+			break
+		}
+		res.Result = rpcRes.Result
+		res.Error = rpcRes.Error
+	case !json.Valid(respBody):
+		res.Error = newRPCErr(customrpcmethods.ErrInternal, "invalid response format")
+	default:
+		res.Result = respBody
+	}
+
+	return result
+}
+
+// newRESTRequest returns the REST request on the URL of the JSON-RPC request with its headers
+// the path of the REST request is added to the path of the URL and its query to the query of the URL
+func newRESTRequest(req *http.Request, restReq *customrpcmethods.RESTReq) (*http.Request, error) {
+	ref, err := url.Parse(strings.TrimSuffix(req.URL.EscapedPath(), "/") + restReq.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	u := *req.URL
+	u.Path = ref.Path
+	u.RawPath = ref.RawPath
+	query := req.URL.Query()
+	for key, values := range restReq.Query {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+
+	var body io.Reader
+	if restReq.Body != nil {
+		body = bytes.NewReader(restReq.Body)
+	}
+	newReq, err := http.NewRequestWithContext(req.Context(), restReq.Method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range req.Header {
+		newReq.Header[k] = v
+	}
+	// the responses are decompressed by the base transport, the JSON-RPC response is not compressed
+	newReq.Header.Del("Accept-Encoding")
+	newReq.Header.Del("Content-Length")
+	newReq.Header.Set("Accept", "application/json")
+	if body == nil {
+		newReq.Header.Del("Content-Type")
+	} else {
+		newReq.Header.Set("Content-Type", "application/json")
+	}
+
+	return newReq, nil
+}
+
+// newRESTResponse returns the response of the JSON-RPC request with the body and the headers of a REST response
+func newRESTResponse(req *http.Request, statusCode int, header http.Header, body []byte) *http.Response {
+	resHeader := http.Header{}
+	for k, v := range header {
+		resHeader[k] = v
+	}
+	resHeader.Del("Content-Encoding")
+	resHeader.Set("Content-Type", "application/json")
+	resHeader.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resHeader,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,343 @@
+package rpccontext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	customrpcmethods "github.com/stateless-solutions/compatibility-layer/custom-rpc-methods"
+	"github.com/stateless-solutions/compatibility-layer/models"
+	"github.com/stateless-solutions/compatibility-layer/upstream"
+)
+
+// mockAptos serves the Aptos REST endpoints of the tests, accounts are answered with the ledger version of their query
+func mockAptos(t *testing.T, ledgerVersion string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"chain_id":1,"epoch":"10","ledger_version":"` + ledgerVersion + `","block_height":"900"}`))
+	})
+	mux.HandleFunc("GET /v1/accounts/{address}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("address") != "0x1" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Account not found","error_code":"account_not_found","vm_error_code":null}`))
+			return
+		}
+		w.Write([]byte(`{"sequence_number":"0","ledger_version_of_query":"` + r.URL.Query().Get("ledger_version") + `"}`))
+	})
+	mux.HandleFunc("POST /v1/view", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]json.RawMessage
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil || string(body["function"]) != `"0x1::coin::balance"` {
+			t.Errorf("Unexpected view body: %v %v", body, err)
+		}
+		w.Write([]byte(`["100"]`))
+	})
+	mux.HandleFunc("GET /v1/transactions/by_version/{txn_version}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"message":"unavailable"}`))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+// mockTron serves the wallet API and the JSON-RPC endpoint of the tests
+func mockTron(t *testing.T, height int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /wallet/getnowblock", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"blockID":"0000000003e3c5a1","block_header":{"raw_data":{"number":%d}}}`, height)
+	})
+	mux.HandleFunc("POST /wallet/getaccount", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]json.RawMessage
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Errorf("Unexpected account body: %v", err)
+		}
+		if string(body["address"]) != `"TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"` {
+			w.Write([]byte(`{"Error":"address invalid"}`))
+			return
+		}
+		w.Write([]byte(`{"balance":100}`))
+	})
+	mux.HandleFunc("POST /jsonrpc", func(w http.ResponseWriter, r *http.Request) {
+		var req models.RPCReq
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			t.Errorf("Unexpected JSON-RPC body: %v", err)
+		}
+		switch req.Method {
+		case "eth_getBlockByNumber":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"number":"0x%x"}}`, req.ID, height)
+		case "eth_getBalance":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x64"}`, req.ID)
+		default:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32601,"message":"method not found"}}`, req.ID)
+		}
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func newRESTPool(t *testing.T, url string, chainType customrpcmethods.ChainType) *upstream.Pool {
+	pool, err := upstream.NewPool([]string{url}, upstream.PolicyRoundRobin, nil, slog.Default())
+	if err != nil {
+		t.Fatalf("Error creating pool: %v", err)
+	}
+	pool.SetTransport(NewRESTTransport(customrpcmethods.ChainTypeToRESTTranslator[chainType], nil, 0))
+
+	return pool
+}
+
+func TestAptosHandler(t *testing.T) {
+	mockServer := mockAptos(t, "2150")
+	defer mockServer.Close()
+
+	tests := []struct {
+		name         string
+		reqBody      string
+		pinnedMode   bool
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Latest version",
+			reqBody:      `{"jsonrpc":"2.0","method":"get_accountAndLedgerVersion","id":1,"params":{"address":"0x1"}}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"jsonrpc":"2.0","result":{"data":{"sequence_number":"0","ledger_version_of_query":""},"ledgerVersion":"2150"},"id":1}`,
+		},
+		{
+			name:         "Pinned latest version",
+			reqBody:      `{"jsonrpc":"2.0","method":"get_accountAndLedgerVersion","id":1,"params":{"address":"0x1"}}`,
+			pinnedMode:   true,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"jsonrpc":"2.0","result":{"data":{"sequence_number":"0","ledger_version_of_query":"2150"},"ledgerVersion":"2150"},"id":1}`,
+		},
+		{
+			name:         "Batch with view and not found account",
+			reqBody:      `[{"jsonrpc":"2.0","method":"viewAndLedgerVersion","id":1,"params":{"function":"0x1::coin::balance","type_arguments":[],"arguments":["0x1"],"ledger_version":"2000"}},{"jsonrpc":"2.0","method":"get_account","id":2,"params":{"address":"0x2"}}]`,
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"jsonrpc":"2.0","result":{"data":["100"],"ledgerVersion":"2000"},"id":1},{"jsonrpc":"2.0","error":{"code":-32000,"message":"Account not found","data":{"message":"Account not found","error_code":"account_not_found","vm_error_code":null}},"id":2}]`,
+		},
+		{
+			name:         "Unknown method",
+			reqBody:      `{"jsonrpc":"2.0","method":"get_events","id":1}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found"},"id":1}`,
+		},
+		{
+			name:         "Server error",
+			reqBody:      `{"jsonrpc":"2.0","method":"get_transaction_by_version","id":1,"params":{"txn_version":"5"}}`,
+			expectedCode: http.StatusOK, // bodies of responses that are not ok are written as they are
+			expectedBody: `{"message":"unavailable"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/", bytes.NewBufferString(tt.reqBody))
+			if err != nil {
+				t.Fatalf("Test case %s: Error creating mock request: %v", tt.name, err)
+			}
+			rec := httptest.NewRecorder()
+
+			context := &RPCContext{
+				Upstreams:          newRESTPool(t, mockServer.URL, customrpcmethods.ChainTypeAptos),
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/aptos.json"),
+				PinnedMode:         tt.pinnedMode,
+				Logger:             slog.Default(),
+			}
+			http.HandlerFunc(context.Handler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("Test case %s: Expected status code %d, got %d", tt.name, tt.expectedCode, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedBody, rec.Body)
+			}
+		})
+	}
+}
+
+func TestTronHandler(t *testing.T) {
+	mockServer := mockTron(t, 65258913)
+	defer mockServer.Close()
+
+	tests := []struct {
+		name         string
+		reqBody      string
+		expectedBody string
+	}{
+		{
+			name:         "Native account",
+			reqBody:      `{"jsonrpc":"2.0","method":"wallet/getaccountAndBlockNumber","id":1,"params":{"address":"TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"}}`,
+			expectedBody: `{"jsonrpc":"2.0","result":{"data":{"balance":100},"blockNumber":"65258913"},"id":1}`,
+		},
+		{
+			name:         "JSON-RPC balance",
+			reqBody:      `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":["0x41a614f803b6fd780986a42c78ec9c7f77e6ded13c","latest"]}`,
+			expectedBody: `{"jsonrpc":"2.0","result":{"data":"0x64","blockNumber":"0x3e3c5a1"},"id":1}`,
+		},
+		{
+			name:         "Batch with wallet error and JSON-RPC error",
+			reqBody:      `[{"jsonrpc":"2.0","method":"wallet/getaccount","id":1,"params":{"address":"T1"}},{"jsonrpc":"2.0","method":"eth_unknown","id":2}]`,
+			expectedBody: `[{"jsonrpc":"2.0","error":{"code":-32000,"message":"address invalid","data":{"Error":"address invalid"}},"id":1},{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not found"},"id":2}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/", bytes.NewBufferString(tt.reqBody))
+			if err != nil {
+				t.Fatalf("Test case %s: Error creating mock request: %v", tt.name, err)
+			}
+			rec := httptest.NewRecorder()
+
+			context := &RPCContext{
+				Upstreams:          newRESTPool(t, mockServer.URL, customrpcmethods.ChainTypeTron),
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/tron.json"),
+				Logger:             slog.Default(),
+			}
+			http.HandlerFunc(context.Handler).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Test case %s: Expected status code %d, got %d", tt.name, http.StatusOK, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedBody, rec.Body)
+			}
+		})
+	}
+}
+
+func TestRESTTransportHealthCheck(t *testing.T) {
+	mockServer := mockAptos(t, "2150")
+	defer mockServer.Close()
+
+	pool := newRESTPool(t, mockServer.URL, customrpcmethods.ChainTypeAptos)
+	publicData := customrpcmethods.ChainTypeToPublicData[customrpcmethods.ChainTypeAptos]
+
+	res, err := pool.Call(context.Background(), publicData.GetHealthCheckReq())
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	height, err := publicData.ExtractHeightFromHealthCheck(res)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	if height != 2150 {
+		t.Errorf("Expected height 2150, got %d", height)
+	}
+}
+
+func TestRESTTransportURLPath(t *testing.T) {
+	var gotURL string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		w.Write([]byte(`{"sequence_number":"0"}`))
+	}))
+	defer mockServer.Close()
+
+	transport := NewRESTTransport(customrpcmethods.AptosImpl{}, nil, 0)
+	body := `{"jsonrpc":"2.0","method":"get_account","id":1,"params":{"address":"0x1","ledger_version":"5"}}`
+	req, err := http.NewRequest("POST", mockServer.URL+"/node/?api_key=abc", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Error creating request: %v", err)
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if gotURL != "/node/v1/accounts/0x1?api_key=abc&ledger_version=5" {
+		t.Errorf("Expected the REST path after the path of the upstream, got %s", gotURL)
+	}
+
+	var res models.RPCResJSON
+	respBody, _ := io.ReadAll(resp.Body)
+	err = json.Unmarshal(respBody, &res)
+	if err != nil {
+		t.Fatalf("Error decoding response %s: %v", respBody, err)
+	}
+	if string(res.Result) != `{"sequence_number":"0"}` || string(res.ID) != "1" {
+		t.Errorf("Expected the REST body as the result, got %s", respBody)
+	}
+}
+
+func TestRESTTransportLimits(t *testing.T) {
+	var inFlight, maxInFlight int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			observed := atomic.LoadInt32(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte(`{"chain_id":1,"ledger_version":"1000","block_height":"10"}`))
+	}))
+	defer mockServer.Close()
+
+	var reqs []string
+	for i := 0; i < 3*maxRESTCalls; i++ {
+		reqs = append(reqs, fmt.Sprintf(`{"jsonrpc":"2.0","method":"get_ledger_info","id":%d}`, i))
+	}
+	body := "[" + strings.Join(reqs, ",") + "]"
+
+	tests := []struct {
+		name            string
+		maxResponseSize int64
+		expectedErr     *models.RPCErr
+	}{
+		{
+			name: "Calls under the limit",
+		},
+		{
+			name:            "Response too large",
+			maxResponseSize: 16,
+			expectedErr:     customrpcmethods.ErrResponseTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := NewRESTTransport(customrpcmethods.AptosImpl{}, nil, tt.maxResponseSize)
+			req, err := http.NewRequest("POST", mockServer.URL, bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatalf("Test case %s: Error creating request: %v", tt.name, err)
+			}
+
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+			defer resp.Body.Close()
+
+			var ress []models.RPCResJSON
+			err = json.NewDecoder(resp.Body).Decode(&ress)
+			if err != nil || len(ress) != len(reqs) {
+				t.Fatalf("Test case %s: Expected %d responses, got %d and error %v", tt.name, len(reqs), len(ress), err)
+			}
+			for _, res := range ress {
+				if (tt.expectedErr == nil && res.Error != nil) || (tt.expectedErr != nil && (res.Error == nil || res.Error.Code != tt.expectedErr.Code)) {
+					t.Errorf("Test case %s: Expected error %v, got %v", tt.name, tt.expectedErr, res.Error)
+				}
+			}
+		})
+	}
+
+	if maxInFlight > maxRESTCalls {
+		t.Errorf("Expected at most %d REST requests at the same time, got %d", maxRESTCalls, maxInFlight)
+	}
+}
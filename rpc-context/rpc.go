@@ -3,6 +3,7 @@ package rpccontext
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,34 +12,89 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/stateless-solutions/compatibility-layer/attestation"
 	customrpcmethods "github.com/stateless-solutions/compatibility-layer/custom-rpc-methods"
+	gettercache "github.com/stateless-solutions/compatibility-layer/getter-cache"
+	"github.com/stateless-solutions/compatibility-layer/metrics"
 	"github.com/stateless-solutions/compatibility-layer/models"
+	"github.com/stateless-solutions/compatibility-layer/tracing"
+	"github.com/stateless-solutions/compatibility-layer/upstream"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/ssh"
 )
 
+var errAllReqsInvalid = errors.New("all the reqs are invalid")
+
+const (
+	// nonceHeader is the header of the optional nonce of the client bound to the version 2 attestations
+	nonceHeader    = "Stateless-Attestation-Nonce"
+	maxNonceLength = 128
+)
+
 type RPCContext struct {
 	Identity           string
+	DefaultChain       string // chain identifier of the default route, bound to the attestations
 	DefaultChainURL    string
+	DefaultChainWSURL  string
+	Upstreams          *upstream.Pool
+	ChainRoutes        map[string]*ChainRoute
 	HTTPPort           string
 	CustomMethodHolder *customrpcmethods.CustomMethodHolder
+	GetterCache        *gettercache.Cache
+	ResponseCache      *ResponseCache
 	UseAttestation     bool
+	AttestationVersion int // version 1 is used if it is not set
+	PinnedMode         bool
+	StreamResponses    bool     // results of single reqs are streamed to the client instead of being buffered
+	MaxRequestBodySize int64    // max bytes of the client request bodies, 0 means no limit
+	MaxResponseSize    int64    // max bytes of the upstream responses that are buffered, 0 means no limit
+	WSAllowedOrigins   []string // origins of the websocket clients that are allowed, any origin is allowed if it is empty
 	SigningKey         ssh.Signer
 	Logger             *slog.Logger
 }
 
+// ChainRoute holds the custom methods and upstreams of a named chain
+type ChainRoute struct {
+	CustomMethodHolder *customrpcmethods.CustomMethodHolder
+	Upstreams          *upstream.Pool
+	GetterCache        *gettercache.Cache
+	ResponseCache      *ResponseCache
+	ChainWSURL         string
+}
+
 type reqHandler struct {
-	ChainURL         string
-	IsSlice          bool
-	IsGzip           bool
-	HTTPResponse     *http.Response
-	RPCReqs          []*models.RPCReq
-	RPCRess          []*models.RPCResJSON
-	RPCRessAttested  []*models.RPCResJSONAttested
-	CustomMethodsMap map[string][]customrpcmethods.GetterTypesHolder
-	ChangedMethods   map[string]string
-	IDsHolder        map[string]string
+	CustomMethodHolder *customrpcmethods.CustomMethodHolder
+	Chain              string // chain identifier bound to the attestations
+	Nonce              string // nonce of the client bound to the attestations
+	ChainURL           string
+	Pool               *upstream.Pool
+	Upstream           *upstream.Upstream
+	GetterCache        *gettercache.Cache
+	ResponseCache      *ResponseCache
+	IsSlice            bool
+	IsGzip             bool
+	Streamed           bool // the response was already streamed to the client
+	HTTPResponse       *http.Response
+	RPCReqs            []*models.RPCReq
+	RPCRess            []*models.RPCResJSON
+	GetterRess         []*models.RPCResJSON
+	RPCRessAttested    []*models.RPCResJSONAttested
+	ReqIDs             []json.RawMessage         // ids of the client reqs, used to answer with errors
+	ClientReqs         map[string]*models.RPCReq // reqs as the client sent them by their ids, used to bind the attestations
+	ErrRess            []*models.RPCResJSON      // responses of the invalid items of a slice, they are not forwarded
+	CachedRess         []*models.RPCResJSON      // responses of the immutable reqs found on the response cache, they are not forwarded
+	CacheKeys          map[string]string         // response cache keys of the immutable reqs that are forwarded by their ids
+	CustomMethodsMap   map[string][]customrpcmethods.GetterTypesHolder
+	ChangedMethods     map[string]string
+	IDsHolder          map[string]string
+	ContextID          string // id of the context req answered with the context of the upstream instead of being forwarded
+	MetricMethods      []string
 }
 
 func (rh *reqHandler) LogAttrs() []slog.Attr {
@@ -75,144 +131,346 @@ func (rh *reqHandler) LogAttrs() []slog.Attr {
 
 func (c *RPCContext) parseRPCReq(w http.ResponseWriter, r *http.Request, rh *reqHandler) error {
 	// Read the request body
-	body, err := io.ReadAll(r.Body)
+	body, err := readBody(r.Body, c.MaxRequestBodySize)
+	if errors.Is(err, errBodyTooLarge) {
+		c.writeRPCError(w, rh, customrpcmethods.ErrRequestTooLarge)
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
 	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusInternalServerError)
+		c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "failed to read request body"))
 		return fmt.Errorf("failed to read request body: %w", err)
 	}
 	defer r.Body.Close()
 
-	// Parse the request body
+	err = rh.parseBody(body)
+	if err != nil {
+		c.writeRPCError(w, rh, toRPCErr(err, customrpcmethods.ErrParseErr))
+		return fmt.Errorf("failed to unmarshal request: %w", err)
+	}
+
+	// the error responses of the invalid items are returned if there is nothing to forward
+	if len(rh.RPCReqs) == 0 {
+		c.writeRPCError(w, rh, customrpcmethods.ErrInvalidRequest)
+		return errAllReqsInvalid
+	}
+
+	return nil
+}
+
+// parseBody parses the rpc reqs of the body and saves the ids of the client reqs
+func (rh *reqHandler) parseBody(body []byte) error {
+	var err error
+	rh.RPCReqs, rh.ErrRess, rh.IsSlice, err = parseRPCReqBody(body)
+	if err != nil {
+		return err
+	}
+
+	// reqs are modified in place, so the method and params are copied before
+	rh.ClientReqs = make(map[string]*models.RPCReq, len(rh.RPCReqs))
+	for _, req := range rh.RPCReqs {
+		rh.ClientReqs[string(req.ID)] = &models.RPCReq{
+			Method: req.Method,
+			Params: req.Params,
+			ID:     req.ID,
+		}
+	}
+
+	rh.setReqIDs()
+
+	return nil
+}
+
+// setReqIDs saves the ids of the client reqs that will be forwarded
+func (rh *reqHandler) setReqIDs() {
+	rh.ReqIDs = nil
+	for _, req := range rh.RPCReqs {
+		rh.ReqIDs = append(rh.ReqIDs, req.ID)
+	}
+}
+
+// parseRPCReqBody parses the body as a single RPCReq or a slice of RPCReq
+// invalid items of a slice get an error response instead of failing the whole slice
+func parseRPCReqBody(body []byte) ([]*models.RPCReq, []*models.RPCResJSON, bool, error) {
 	var rpcReq *models.RPCReq
+	err := json.Unmarshal(body, &rpcReq)
+	if err == nil && rpcReq != nil {
+		return []*models.RPCReq{rpcReq}, nil, false, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, nil, false, customrpcmethods.ErrParseErr
+	}
+	if len(items) == 0 {
+		return nil, nil, false, customrpcmethods.ErrInvalidRequest
+	}
+
 	var rpcReqs []*models.RPCReq
+	var errRess []*models.RPCResJSON
+	for _, item := range items {
+		var rpcReq *models.RPCReq
+		err := json.Unmarshal(item, &rpcReq)
+		if err != nil || rpcReq == nil || rpcReq.Method == "" {
+			errRess = append(errRess, &models.RPCResJSON{
+				JSONRPC: "2.0",
+				Error:   customrpcmethods.ErrInvalidRequest,
+				ID:      itemID(item),
+			})
+			continue
+		}
+		rpcReqs = append(rpcReqs, rpcReq)
+	}
 
-	// Check if the body is a single RPCReq or a slice of RPCReq
-	err = json.Unmarshal(body, &rpcReq)
-	if err != nil {
-		if err := json.Unmarshal(body, &rpcReqs); err != nil {
-			http.Error(w, "Invalid request format", http.StatusBadRequest)
-			return fmt.Errorf("failed to unmarshal request: %w", err)
+	return rpcReqs, errRess, true, nil
+}
+
+// reqsSpanAttrs returns the methods and batch size of the rpc reqs as span attributes
+func reqsSpanAttrs(rpcReqs []*models.RPCReq) []attribute.KeyValue {
+	methods := make([]string, 0, len(rpcReqs))
+	for _, req := range rpcReqs {
+		methods = append(methods, req.Method)
+	}
+
+	return []attribute.KeyValue{
+		attribute.StringSlice("rpc.methods", methods),
+		attribute.Int("rpc.batch_size", len(rpcReqs)),
+	}
+}
+
+// setMetricMethods saves the methods of the request to be used as metric labels
+// methods that are not on the config files are grouped to avoid unbounded labels
+func (rh *reqHandler) setMetricMethods() {
+	for _, req := range rh.RPCReqs {
+		_, isCustom := rh.CustomMethodHolder.CustomMethodToChainType[req.Method]
+		_, isOriginal := rh.CustomMethodHolder.OriginalMethodToChainType[req.Method]
+		if isCustom || isOriginal {
+			rh.MetricMethods = append(rh.MetricMethods, req.Method)
+		} else {
+			rh.MetricMethods = append(rh.MetricMethods, metrics.OtherMethod)
 		}
-		rh.IsSlice = true
-		rh.RPCReqs = rpcReqs
-	} else {
-		rh.IsSlice = false
-		rh.RPCReqs = []*models.RPCReq{rpcReq}
+	}
+}
+
+func (c *RPCContext) modifyReq(ctx context.Context, w http.ResponseWriter, rh *reqHandler) error {
+	err := c.modifyRPCReqs(ctx, rh)
+	if err != nil {
+		c.writeRPCError(w, rh, toRPCErr(err, customrpcmethods.ErrInvalidRequest))
+		return err
 	}
 
 	return nil
 }
 
-func (c *RPCContext) modifyReq(w http.ResponseWriter, rh *reqHandler) error {
+func (c *RPCContext) modifyRPCReqs(ctx context.Context, rh *reqHandler) error {
 	var err error
-	rh.RPCReqs, err = c.CustomMethodHolder.HandleGatewayMode(rh.RPCReqs)
+	rh.RPCReqs, err = rh.CustomMethodHolder.HandleGatewayMode(rh.RPCReqs)
+	if err != nil {
+		return err
+	}
+
+	// invalid reqs are answered with their own error so they don't fail the rest of the slice
+	var errRess []*models.RPCResJSON
+	rh.RPCReqs, errRess, err = rh.CustomMethodHolder.FilterInvalidRPCReqs(rh.RPCReqs)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
 		return err
 	}
-	customMethodsMap, err := c.CustomMethodHolder.GetCustomMethodsMap(rh.RPCReqs)
+	rh.ErrRess = append(rh.ErrRess, errRess...)
+	rh.setReqIDs()
+	if len(rh.RPCReqs) == 0 {
+		return errAllReqsInvalid
+	}
+
+	// immutable reqs answered before are not forwarded again
+	if rh.ResponseCache != nil {
+		c.useCachedRess(ctx, rh)
+		if len(rh.RPCReqs) == 0 {
+			return nil
+		}
+	}
+
+	customMethodsMap, err := rh.CustomMethodHolder.GetCustomMethodsMap(rh.RPCReqs)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
 		return err
 	}
 	rh.CustomMethodsMap = customMethodsMap
-	rh.ChangedMethods, err = c.CustomMethodHolder.ChangeCustomMethods(rh.RPCReqs)
+	rh.ChangedMethods, err = rh.CustomMethodHolder.ChangeCustomMethods(rh.RPCReqs)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
 		return err
 	}
 
-	rh.RPCReqs, rh.IDsHolder, err = c.CustomMethodHolder.AddGetterMethodsIfNeeded(rh.RPCReqs, customMethodsMap)
+	rh.RPCReqs, rh.IDsHolder, err = rh.CustomMethodHolder.AddGetterMethodsIfNeeded(rh.RPCReqs, customMethodsMap)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
 		return err
 	}
 
+	rh.removeContextReq()
+
+	// getters resolved recently are not sent again, their cached responses are used as the getter responses
+	if rh.GetterCache != nil {
+		rh.RPCReqs, rh.GetterRess = rh.GetterCache.UseCached(rh.RPCReqs, rh.IDsHolder)
+	}
+	injected := len(rh.IDsHolder) - len(rh.GetterRess)
+	if rh.ContextID != "" {
+		injected--
+	}
+	metrics.ObserveGetterRequestsInjected(injected)
+
 	return nil
 }
 
-func (c *RPCContext) doRPCCall(w http.ResponseWriter, r *http.Request, rh *reqHandler) error {
+// removeContextReq removes the context req from the rpc reqs if the pool fetches the context of its upstreams with the health checks
+// the context of the upstream that answers the request is used as its response
+func (rh *reqHandler) removeContextReq() {
+	if rh.Pool == nil {
+		return
+	}
+	contextReq := rh.Pool.ContextReq()
+	if contextReq == nil {
+		return
+	}
+
+	ids := make(map[string]bool, len(rh.IDsHolder))
+	for _, id := range rh.IDsHolder {
+		ids[id] = true
+	}
+
+	for i, req := range rh.RPCReqs {
+		if req.Method == contextReq.Method && ids[string(req.ID)] {
+			rh.ContextID = string(req.ID)
+			rh.RPCReqs = append(rh.RPCReqs[:i:i], rh.RPCReqs[i+1:]...)
+			return
+		}
+	}
+}
+
+// addUpstreamContext adds the context of the upstream that answered the request to the getter responses
+// as the response of the removed context req, it is left out of the results if it is not known yet
+func (rh *reqHandler) addUpstreamContext() {
+	if rh.ContextID == "" || rh.Upstream == nil {
+		return
+	}
+
+	contextRes := rh.Upstream.Context()
+	if contextRes != nil {
+		rh.GetterRess = append(rh.GetterRess, &models.RPCResJSON{
+			JSONRPC: contextRes.JSONRPC,
+			Result:  contextRes.Result,
+			ID:      json.RawMessage(rh.ContextID),
+		})
+	}
+	rh.ContextID = ""
+}
+
+// forwardRPCReqs forwards the rpc reqs to the upstreams and returns the response with its decompressed body
+func (c *RPCContext) forwardRPCReqs(w http.ResponseWriter, r *http.Request, rh *reqHandler, rpcReqs []*models.RPCReq) (*http.Response, io.Reader, error) {
 	// Marshal the modified request body
 	var modifiedBody []byte
 	var err error
-	modifiedBody, err = json.Marshal(rh.RPCReqs)
+	modifiedBody, err = json.Marshal(rpcReqs)
 	if err != nil {
-		http.Error(w, "Failed to marshal modified request", http.StatusInternalServerError)
-		return fmt.Errorf("failed to marshal modified request: %w", err)
+		c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "failed to marshal modified request"))
+		return nil, nil, fmt.Errorf("failed to marshal modified request: %w", err)
 	}
 
 	// Create a new request to forward to the second server
-	req, err := http.NewRequest("POST", rh.ChainURL, bytes.NewBuffer(modifiedBody))
-	if err != nil {
-		http.Error(w, "Failed to create request", http.StatusInternalServerError)
-		return fmt.Errorf("failed to create request: %w", err)
-	}
+	newReq := func(chainURL string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(r.Context(), "POST", chainURL, bytes.NewBuffer(modifiedBody))
+		if err != nil {
+			return nil, err
+		}
+
+		// Copy the original headers
+		for k, v := range r.Header {
+			req.Header[k] = v
+		}
 
-	// Copy the original headers
-	for k, v := range r.Header {
-		req.Header[k] = v
+		return req, nil
 	}
 
-	// Forward the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// Forward the request, the same upstream is preferred for all the calls of the request
+	resp, usedUpstream, err := rh.Pool.Do(rh.Upstream, newReq)
 	if err != nil {
-		http.Error(w, "Failed to forward request", http.StatusInternalServerError)
-		return fmt.Errorf("failed to forward request: %w", err)
+		c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "failed to forward request"))
+		return nil, nil, fmt.Errorf("failed to forward request: %w", err)
 	}
-	defer resp.Body.Close()
 
 	rh.HTTPResponse = resp
+	rh.Upstream = usedUpstream
+	rh.ChainURL = usedUpstream.URL
 
 	// Check if the response is gzipped and decompress if necessary
-	var respBody []byte
 	if resp.Header.Get("Content-Encoding") == "gzip" {
 		rh.IsGzip = true
 		gzr, err := gzip.NewReader(resp.Body)
 		if err != nil {
-			http.Error(w, "Failed to create gzip reader", http.StatusInternalServerError)
-			return fmt.Errorf("failed to create gzip reader: %w", err)
+			resp.Body.Close()
+			c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "failed to create gzip reader"))
+			return nil, nil, fmt.Errorf("failed to create gzip reader: %w", err)
 		}
-		defer gzr.Close()
-		respBody, err = io.ReadAll(gzr)
-		if err != nil {
-			http.Error(w, "Failed to read gzipped response body", http.StatusInternalServerError)
-			return fmt.Errorf("failed to read gzipped response body: %w", err)
+		return resp, gzr, nil
+	}
+
+	return resp, resp.Body, nil
+}
+
+// readResBody reads the upstream response body up to the max response size
+func (c *RPCContext) readResBody(w http.ResponseWriter, rh *reqHandler, body io.Reader) ([]byte, error) {
+	respBody, err := readBody(body, c.MaxResponseSize)
+	if errors.Is(err, errBodyTooLarge) {
+		c.writeRPCError(w, rh, customrpcmethods.ErrResponseTooLarge)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if err != nil {
+		c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "failed to read response body"))
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return respBody, nil
+}
+
+// writeNotOKRes writes the response of the upstream as it is when its status is not 200
+func (c *RPCContext) writeNotOKRes(w http.ResponseWriter, rh *reqHandler, resp *http.Response, respBody []byte) error {
+	// Copy the headers from the response
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+
+	// Send the modified response back to the original client
+	w.Header().Set("Content-Type", "application/json")
+
+	if rh.IsGzip {
+		var buf bytes.Buffer
+		gzw := gzip.NewWriter(&buf)
+		if _, err := gzw.Write(respBody); err != nil {
+			c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "failed to compress response body"))
+			return fmt.Errorf("failed to compress response body: %w", err)
 		}
-	} else {
-		respBody, err = io.ReadAll(resp.Body)
-		if err != nil {
-			http.Error(w, "Failed to read response body", http.StatusInternalServerError)
-			return fmt.Errorf("failed to read response body: %w", err)
+		if err := gzw.Close(); err != nil {
+			c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "failed to close gzip writer"))
+			return fmt.Errorf("failed to close gzip writer: %w", err)
 		}
+		w.Write(buf.Bytes())
+	} else {
+		w.Write(respBody)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		// Copy the headers from the response
-		for k, v := range resp.Header {
-			w.Header()[k] = v
-		}
+	return errors.New("Response was not 200 ok")
+}
 
-		// Send the modified response back to the original client
-		w.Header().Set("Content-Type", "application/json")
-
-		if rh.IsGzip {
-			var buf bytes.Buffer
-			gzw := gzip.NewWriter(&buf)
-			if _, err := gzw.Write(respBody); err != nil {
-				http.Error(w, "Failed to compress response body", http.StatusInternalServerError)
-				return fmt.Errorf("failed to compress response body: %w", err)
-			}
-			if err := gzw.Close(); err != nil {
-				http.Error(w, "Failed to close gzip writer", http.StatusInternalServerError)
-				return fmt.Errorf("failed to close gzip writer: %w", err)
-			}
-			w.Write(buf.Bytes())
-		} else {
-			w.Write(respBody)
-		}
+func (c *RPCContext) postRPCReqs(w http.ResponseWriter, r *http.Request, rh *reqHandler, rpcReqs []*models.RPCReq) ([]*models.RPCResJSON, error) {
+	resp, body, err := c.forwardRPCReqs(w, r, rh, rpcReqs)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := c.readResBody(w, rh, body)
+	if err != nil {
+		return nil, err
+	}
 
-		return errors.New("Response was not 200 ok")
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.writeNotOKRes(w, rh, resp, respBody)
 	}
 
 	// Parse the response body
@@ -223,29 +481,135 @@ func (c *RPCContext) doRPCCall(w http.ResponseWriter, r *http.Request, rh *reqHa
 	err = json.Unmarshal(respBody, &rpcRes)
 	if err != nil {
 		if err := json.Unmarshal(respBody, &rpcRess); err != nil {
-			http.Error(w, "Invalid response format", http.StatusBadRequest)
-			return fmt.Errorf("failed to unmarshal response body: %w", err)
+			c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "invalid response format"))
+			return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
 		}
-		rh.RPCRess = rpcRess
-	} else {
-		rh.RPCRess = []*models.RPCResJSON{rpcRes}
+		setJSONRPCVersion(rpcRess)
+		return rpcRess, nil
+	}
+
+	rpcRess = []*models.RPCResJSON{rpcRes}
+	setJSONRPCVersion(rpcRess)
+
+	return rpcRess, nil
+}
+
+// setJSONRPCVersion sets the version of the responses without one
+// nodes with JSON-RPC 1.0 responses as bitcoind before v28 don't send it
+func setJSONRPCVersion(rpcRess []*models.RPCResJSON) {
+	for _, res := range rpcRess {
+		if res != nil && res.JSONRPC == "" {
+			res.JSONRPC = "2.0"
+		}
+	}
+}
+
+// pinReq resolves the getters before forwarding the request so the getter params
+// can be rewritten to the concrete values that will be returned with the response
+func (c *RPCContext) pinReq(w http.ResponseWriter, r *http.Request, rh *reqHandler) error {
+	rpcReqs, getterReqs := customrpcmethods.SplitGetterReqs(rh.RPCReqs, rh.IDsHolder)
+	if len(getterReqs) == 0 && len(rh.GetterRess) == 0 {
+		return nil
+	}
+
+	// cached getter responses are pinned the same way, so the reported getter is the one the request is executed at
+	err := c.postGetterReqs(w, r, rh, getterReqs)
+	if err != nil {
+		return err
+	}
+
+	err = rh.CustomMethodHolder.PinGetterParams(rpcReqs, rh.GetterRess, rh.ChangedMethods, rh.IDsHolder, rh.CustomMethodsMap)
+	if err != nil {
+		c.writeRPCError(w, rh, toRPCErr(err, customrpcmethods.ErrInvalidRequest))
+		return err
+	}
+
+	rh.RPCReqs = rpcReqs
+
+	return nil
+}
+
+// postGetterReqs posts the getter reqs before the reqs they belong to, their responses are added to the getter responses of the handler
+func (c *RPCContext) postGetterReqs(w http.ResponseWriter, r *http.Request, rh *reqHandler, getterReqs []*models.RPCReq) error {
+	if len(getterReqs) == 0 {
+		return nil
+	}
+
+	postedRess, err := c.postRPCReqs(w, r, rh, getterReqs)
+	if err != nil {
+		return err
+	}
+	if rh.GetterCache != nil {
+		rh.GetterCache.SaveGetterRess(postedRess, rh.IDsHolder)
+	}
+	rh.GetterRess = append(rh.GetterRess, postedRess...)
+
+	return nil
+}
+
+func (c *RPCContext) doRPCCall(w http.ResponseWriter, r *http.Request, rh *reqHandler) error {
+	// all the reqs were answered by the response cache
+	if len(rh.RPCReqs) == 0 {
+		return nil
+	}
+
+	if c.canStream(rh) {
+		// the getters are resolved first, so only the single req is streamed
+		rpcReqs, getterReqs := customrpcmethods.SplitGetterReqs(rh.RPCReqs, rh.IDsHolder)
+		err := c.postGetterReqs(w, r, rh, getterReqs)
+		if err != nil {
+			return err
+		}
+		rh.RPCReqs = rpcReqs
+
+		return c.streamRPCCall(w, r, rh)
+	}
+
+	rpcRess, err := c.postRPCReqs(w, r, rh, rh.RPCReqs)
+	if err != nil {
+		return err
+	}
+	rh.addUpstreamContext()
+
+	if rh.GetterCache != nil {
+		rh.GetterCache.SaveGetterRess(rpcRess, rh.IDsHolder)
+	}
+
+	// getter responses of pinned mode and of the cache are added back to be used when modifying the responses
+	rh.RPCRess = append(rpcRess, rh.GetterRess...)
+
+	return nil
+}
+
+func (c *RPCContext) modifyRes(ctx context.Context, w http.ResponseWriter, rh *reqHandler) error {
+	err := c.modifyRPCRess(ctx, rh)
+	if err != nil {
+		c.writeRPCError(w, rh, toRPCErr(err, customrpcmethods.ErrInternal))
+		return err
 	}
 
 	return nil
 }
 
-func (c *RPCContext) modifyRes(w http.ResponseWriter, rh *reqHandler) error {
+func (c *RPCContext) modifyRPCRess(ctx context.Context, rh *reqHandler) error {
 	var err error
-	rh.RPCRess, err = c.CustomMethodHolder.ChangeCustomMethodsResponses(rh.RPCRess, rh.ChangedMethods, rh.IDsHolder, rh.CustomMethodsMap)
+	rh.RPCRess, err = rh.CustomMethodHolder.ChangeCustomMethodsResponses(rh.RPCRess, rh.ChangedMethods, rh.IDsHolder, rh.CustomMethodsMap)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
 		return err
 	}
 
+	// results are stored as they are returned to the client
+	if rh.ResponseCache != nil {
+		c.saveCachedRess(ctx, rh)
+	}
+
+	// cached and invalid items of the slice are answered with the forwarded ones
+	rh.RPCRess = append(rh.RPCRess, rh.CachedRess...)
+	rh.RPCRess = append(rh.RPCRess, rh.ErrRess...)
+
 	if c.UseAttestation {
-		rh.RPCRessAttested, err = attestation.AttestRess(rh.RPCRess, c.Identity, c.SigningKey)
+		rh.RPCRessAttested, err = c.attestRess(rh, rh.RPCRess)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
 			return err
 		}
 	}
@@ -253,6 +617,40 @@ func (c *RPCContext) modifyRes(w http.ResponseWriter, rh *reqHandler) error {
 	return nil
 }
 
+// attestRess attests the responses with the attestation version of the context
+func (c *RPCContext) attestRess(rh *reqHandler, ress []*models.RPCResJSON) ([]*models.RPCResJSONAttested, error) {
+	if c.AttestationVersion != attestation.Version2 {
+		return attestation.AttestRess(ress, c.Identity, c.SigningKey)
+	}
+
+	bindings := make([]attestation.Binding, 0, len(ress))
+	for _, res := range ress {
+		bindings = append(bindings, rh.binding(res))
+	}
+
+	return attestation.AttestRessV2(ress, bindings, c.Identity, c.SigningKey)
+}
+
+// binding returns what the attestation of the response is bound to
+// responses without a client req (e.g. unparsable items) are bound to an empty method
+func (rh *reqHandler) binding(res *models.RPCResJSON) attestation.Binding {
+	binding := attestation.Binding{}
+	if rh == nil {
+		return binding
+	}
+
+	binding.Chain = rh.Chain
+	binding.Nonce = rh.Nonce
+	req, ok := rh.ClientReqs[string(res.ID)]
+	if ok {
+		binding.Method = req.Method
+		binding.Params = req.Params
+	}
+	binding.Block = res.Block
+
+	return binding
+}
+
 func (c *RPCContext) marshalBody(rh *reqHandler) ([]byte, error) {
 	var modifiedRespBody []byte
 	var err error
@@ -277,13 +675,15 @@ func (c *RPCContext) returnRes(w http.ResponseWriter, rh *reqHandler) error {
 	// Marshal the modified response body
 	modifiedRespBody, err := c.marshalBody(rh)
 	if err != nil {
-		http.Error(w, "Failed to marshal modified response", http.StatusInternalServerError)
+		c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "failed to marshal modified response"))
 		return fmt.Errorf("failed to marshal modified response: %w", err)
 	}
 
-	// Copy the headers from the response
-	for k, v := range rh.HTTPResponse.Header {
-		w.Header()[k] = v
+	// Copy the headers from the response, there is none if all the reqs were answered by the response cache
+	if rh.HTTPResponse != nil {
+		for k, v := range rh.HTTPResponse.Header {
+			w.Header()[k] = v
+		}
 	}
 
 	// Send the modified response back to the original client
@@ -293,11 +693,11 @@ func (c *RPCContext) returnRes(w http.ResponseWriter, rh *reqHandler) error {
 		var buf bytes.Buffer
 		gzw := gzip.NewWriter(&buf)
 		if _, err := gzw.Write(modifiedRespBody); err != nil {
-			http.Error(w, "Failed to compress response body", http.StatusInternalServerError)
+			c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "failed to compress response body"))
 			return fmt.Errorf("failed to compress response body: %w", err)
 		}
 		if err := gzw.Close(); err != nil {
-			http.Error(w, "Failed to close gzip writer", http.StatusInternalServerError)
+			c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "failed to close gzip writer"))
 			return fmt.Errorf("failed to close gzip writer: %w", err)
 		}
 		w.Header().Set("Content-Length", strconv.Itoa(len(buf.Bytes())))
@@ -310,24 +710,56 @@ func (c *RPCContext) returnRes(w http.ResponseWriter, rh *reqHandler) error {
 	return nil
 }
 
-func (c *RPCContext) newReqHandler(w http.ResponseWriter, r *http.Request) (*reqHandler, error) {
+func (c *RPCContext) newReqHandler(w http.ResponseWriter, r *http.Request, route *ChainRoute) (*reqHandler, error) {
 	rh := &reqHandler{
-		ChainURL: c.DefaultChainURL,
+		CustomMethodHolder: route.CustomMethodHolder,
+		Chain:              c.chainOf(r),
+		Pool:               route.Upstreams,
+		GetterCache:        route.GetterCache,
+		ResponseCache:      route.ResponseCache,
+	}
+
+	nonce, err := nonceOf(r)
+	if err != nil {
+		c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInvalidRequest, err.Error()))
+		return nil, err
 	}
+	rh.Nonce = nonce
 
+	chainURL := ""
 	headerChainURL := r.Header.Get("Stateless-Chain-URL")
 	if headerChainURL != "" {
 		// Validate the URL
 		_, err := url.ParseRequestURI(headerChainURL)
 		if err != nil {
-			http.Error(w, "Invalid Chain URL", http.StatusBadRequest)
+			c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInvalidRequest, "invalid chain URL"))
 			return nil, errors.New("invalid chain URL")
 		}
-		rh.ChainURL = headerChainURL
+		chainURL = headerChainURL
+		rh.Chain = chainURLHost(headerChainURL)
+		// the caches only hold responses of the chain of the route
+		rh.GetterCache = nil
+		rh.ResponseCache = nil
+	} else if rh.Pool == nil {
+		chainURL = c.DefaultChainURL
+	}
+
+	if chainURL != "" {
+		pool, err := upstream.NewPool([]string{chainURL}, upstream.PolicyRoundRobin, nil, c.Logger)
+		if err != nil {
+			c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInvalidRequest, "invalid chain URL"))
+			return nil, err
+		}
+		// the chain URL serves the same API as the upstreams of the route
+		if rh.Pool != nil {
+			pool.SetTransport(rh.Pool.Transport())
+		}
+		rh.Pool = pool
+		rh.ChainURL = chainURL
 	}
 
-	if rh.ChainURL == "" {
-		http.Error(w, "Chain URL is not set", http.StatusBadRequest)
+	if rh.Pool == nil {
+		c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInvalidRequest, "chain URL is not set"))
 		return nil, errors.New("chain URL is not set")
 	}
 
@@ -369,48 +801,182 @@ func (c *RPCContext) logDebug(msg string, err error, rh *reqHandler) {
 	c.Logger.Debug(msg, attrsToLog...)
 }
 
+// chainOf returns the chain identifier of the request, the chain name of the path or the default chain
+func (c *RPCContext) chainOf(r *http.Request) string {
+	chainName := r.PathValue("chainName")
+	if chainName != "" {
+		return chainName
+	}
+
+	return c.DefaultChain
+}
+
+// chainURLHost returns the host of a chain URL sent by the client, the path can hold api keys so it is not used
+func chainURLHost(chainURL string) string {
+	u, err := url.Parse(chainURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Host
+}
+
+// nonceOf returns the nonce of the client of the request, empty if it wasn't sent
+func nonceOf(r *http.Request) (string, error) {
+	nonce := r.Header.Get(nonceHeader)
+	if len(nonce) > maxNonceLength {
+		return "", fmt.Errorf("attestation nonce is longer than %d bytes", maxNonceLength)
+	}
+
+	return nonce, nil
+}
+
+func (c *RPCContext) defaultRoute() *ChainRoute {
+	return &ChainRoute{
+		CustomMethodHolder: c.CustomMethodHolder,
+		Upstreams:          c.Upstreams,
+		GetterCache:        c.GetterCache,
+		ResponseCache:      c.ResponseCache,
+		ChainWSURL:         c.DefaultChainWSURL,
+	}
+}
+
+// getChainRoute returns the route of the chain name of the path
+func (c *RPCContext) getChainRoute(w http.ResponseWriter, r *http.Request) (*ChainRoute, bool) {
+	chainName := r.PathValue("chainName")
+	route, ok := c.ChainRoutes[chainName]
+	if !ok {
+		c.writeRPCError(w, nil, &models.RPCErr{
+			Code:          customrpcmethods.ErrInvalidRequest.Code,
+			Message:       "chain not found",
+			HTTPErrorCode: http.StatusNotFound,
+		})
+		c.Logger.Error("Chain route not found", slog.String("chainName", chainName))
+		return nil, false
+	}
+
+	return route, true
+}
+
+// Handler handles the requests with the default custom methods and upstreams
 func (c *RPCContext) Handler(w http.ResponseWriter, r *http.Request) {
-	rh, err := c.newReqHandler(w, r)
+	c.handle(w, r, c.defaultRoute())
+}
+
+// ChainHandler handles the requests of the chain name of the path with the custom methods and upstreams of its config
+func (c *RPCContext) ChainHandler(w http.ResponseWriter, r *http.Request) {
+	route, ok := c.getChainRoute(w, r)
+	if !ok {
+		return
+	}
+
+	c.handle(w, r, route)
+}
+
+func (c *RPCContext) handle(w http.ResponseWriter, r *http.Request, route *ChainRoute) {
+	start := time.Now()
+
+	// the trace of the caller is continued if the request has a trace context
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracing.Tracer().Start(ctx, "handle", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	status := metrics.StatusError
+	defer func() {
+		if status != metrics.StatusSuccess {
+			span.SetStatus(codes.Error, "request failed")
+		}
+	}()
+
+	rh, err := c.newReqHandler(w, r, route)
 	if err != nil {
+		metrics.ObserveStageFailure("create")
+		span.RecordError(err)
 		c.Logger.Error("Create request handler failed", slog.String("error", err.Error()))
 		c.Logger.Debug("Create request handler failed", slog.String("error", err.Error()))
 		return
 	}
 
+	_, stageSpan := tracing.Tracer().Start(ctx, "parseRPCReq")
 	err = c.parseRPCReq(w, r, rh)
+	tracing.EndSpan(stageSpan, err)
 	if err != nil {
+		metrics.ObserveStageFailure("parse")
 		c.Logger.Error("Parse request failed", slog.String("error", err.Error()))
 		c.logDebug("Parse request failed", err, rh)
 		return
 	}
 
-	err = c.modifyReq(w, rh)
+	span.SetAttributes(reqsSpanAttrs(rh.RPCReqs)...)
+	span.SetAttributes(attribute.Bool("rpc.is_batch", rh.IsSlice))
+
+	rh.setMetricMethods()
+	defer func() {
+		metrics.ObserveRequest(rh.MetricMethods, status, time.Since(start))
+	}()
+
+	_, stageSpan = tracing.Tracer().Start(ctx, "modifyReq")
+	err = c.modifyReq(ctx, w, rh)
+	tracing.EndSpan(stageSpan, err)
 	if err != nil {
+		metrics.ObserveStageFailure("modify")
 		c.Logger.Error("Modify request failed", slog.String("error", err.Error()))
 		c.logDebug("Modify request failed", err, rh)
 		return
 	}
 
-	err = c.doRPCCall(w, r, rh)
+	if c.PinnedMode {
+		var stageCtx context.Context
+		stageCtx, stageSpan = tracing.Tracer().Start(ctx, "pinReq")
+		err = c.pinReq(w, r.WithContext(stageCtx), rh)
+		tracing.EndSpan(stageSpan, err)
+		if err != nil {
+			metrics.ObserveStageFailure("pin")
+			c.Logger.Error("Pin request failed", slog.String("error", err.Error()))
+			c.logDebug("Pin request failed", err, rh)
+			return
+		}
+	}
+
+	// the forwarded reqs include the getter methods that were added
+	stageCtx, stageSpan := tracing.Tracer().Start(ctx, "doRPCCall", trace.WithAttributes(reqsSpanAttrs(rh.RPCReqs)...))
+	err = c.doRPCCall(w, r.WithContext(stageCtx), rh)
+	tracing.EndSpan(stageSpan, err)
 	if err != nil {
+		metrics.ObserveStageFailure("call")
 		c.Logger.Error("Do RPC call failed", slog.String("error", err.Error()))
 		c.logDebug("Do RPC call failed", err, rh)
 		return
 	}
 
-	err = c.modifyRes(w, rh)
+	// streamed responses were already modified and returned
+	if rh.Streamed {
+		status = metrics.StatusSuccess
+		c.Logger.Info("Request succeeded")
+		return
+	}
+
+	_, stageSpan = tracing.Tracer().Start(ctx, "modifyRes", trace.WithAttributes(attribute.Bool("attestation", c.UseAttestation)))
+	err = c.modifyRes(ctx, w, rh)
+	tracing.EndSpan(stageSpan, err)
 	if err != nil {
+		metrics.ObserveStageFailure("modifyRes")
 		c.Logger.Error("Modify response failed", slog.String("error", err.Error()))
 		c.logDebug("Modify response failed", err, rh)
 		return
 	}
 
+	_, stageSpan = tracing.Tracer().Start(ctx, "returnRes")
 	err = c.returnRes(w, rh)
+	tracing.EndSpan(stageSpan, err)
 	if err != nil {
+		metrics.ObserveStageFailure("return")
 		c.Logger.Error("Return response failed", slog.String("error", err.Error()))
 		c.logDebug("Return response failed", err, rh)
 		return
 	}
 
+	status = metrics.StatusSuccess
 	c.Logger.Info("Request succeeded")
 }
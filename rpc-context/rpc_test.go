@@ -2,13 +2,28 @@ package rpccontext
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/stateless-solutions/compatibility-layer/attestation"
 	customrpcmethods "github.com/stateless-solutions/compatibility-layer/custom-rpc-methods"
+	gettercache "github.com/stateless-solutions/compatibility-layer/getter-cache"
+	"github.com/stateless-solutions/compatibility-layer/models"
+	"github.com/stateless-solutions/compatibility-layer/tracing"
+	"github.com/stateless-solutions/compatibility-layer/upstream"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestAttestorHandler(t *testing.T) {
@@ -33,7 +48,7 @@ func TestAttestorHandler(t *testing.T) {
 			useAttestation: true,
 			reqBody:        `{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "latest"]}`,
 			expectedCode:   http.StatusOK,
-			expectedBody:   `{"jsonrpc":"2.0","id":"1","result":"success","attestation":{"signatureFormat":"ssh-rsa","hashAlgo":"sha256","identity":"mock_identity","msg":"68e7a69974a641064a6a5ae8b1a00997939a325ec585a49e9fe82b386a21726a","signature":"8e71bb7db5b3b719e12a36219c05308dff130740f2714ed3bbf04f69cb6e95a691792cc7492c14c13c74b76cdbc939169b1f6ba53ff4f82b8c89875d9f49b8db6d83ef4924f18931e975bd27de9e6e734ed5c930330f14c2f36e6002b577a37de27adf57a4b17bcee8816d757c989f5119807c4cd85212712eecc042dc6e917a"}}`,
+			expectedBody:   `{"jsonrpc":"2.0","id":"1","result":"success","attestation":{"signatureFormat":"ssh-rsa","hashAlgo":"sha256","canonicalization":"jcs","identity":"mock_identity","msg":"68e7a69974a641064a6a5ae8b1a00997939a325ec585a49e9fe82b386a21726a","signature":"8e71bb7db5b3b719e12a36219c05308dff130740f2714ed3bbf04f69cb6e95a691792cc7492c14c13c74b76cdbc939169b1f6ba53ff4f82b8c89875d9f49b8db6d83ef4924f18931e975bd27de9e6e734ed5c930330f14c2f36e6002b577a37de27adf57a4b17bcee8816d757c989f5119807c4cd85212712eecc042dc6e917a"}}`,
 		},
 		{
 			name: "Success Case One Request No Attestation",
@@ -57,7 +72,18 @@ func TestAttestorHandler(t *testing.T) {
 			useAttestation:      true,
 			reqBody:             `{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "latest"]}`,
 			expectedCode:        http.StatusOK,
-			expectedBody:        `{"jsonrpc":"2.0","id":"1","result":"success","attestation":{"signatureFormat":"ssh-rsa","hashAlgo":"sha256","identity":"mock_identity","msg":"68e7a69974a641064a6a5ae8b1a00997939a325ec585a49e9fe82b386a21726a","signature":"8e71bb7db5b3b719e12a36219c05308dff130740f2714ed3bbf04f69cb6e95a691792cc7492c14c13c74b76cdbc939169b1f6ba53ff4f82b8c89875d9f49b8db6d83ef4924f18931e975bd27de9e6e734ed5c930330f14c2f36e6002b577a37de27adf57a4b17bcee8816d757c989f5119807c4cd85212712eecc042dc6e917a"}}`,
+			expectedBody:        `{"jsonrpc":"2.0","id":"1","result":"success","attestation":{"signatureFormat":"ssh-rsa","hashAlgo":"sha256","canonicalization":"jcs","identity":"mock_identity","msg":"68e7a69974a641064a6a5ae8b1a00997939a325ec585a49e9fe82b386a21726a","signature":"8e71bb7db5b3b719e12a36219c05308dff130740f2714ed3bbf04f69cb6e95a691792cc7492c14c13c74b76cdbc939169b1f6ba53ff4f82b8c89875d9f49b8db6d83ef4924f18931e975bd27de9e6e734ed5c930330f14c2f36e6002b577a37de27adf57a4b17bcee8816d757c989f5119807c4cd85212712eecc042dc6e917a"}}`,
+		},
+		{
+			name: "Success Case Result Kept As Returned",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"jsonrpc":"2.0","result": {"value":18446744073709551617,"amount":1.50}, "id": "1"}`))
+			}),
+			keyFile:      "test-data/.mock_key.pem",
+			reqBody:      `{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "latest"]}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"jsonrpc":"2.0","result":{"value":18446744073709551617,"amount":1.50},"id":"1"}`,
 		},
 		{
 			name: "Success Case Batch Request",
@@ -69,7 +95,19 @@ func TestAttestorHandler(t *testing.T) {
 			useAttestation: true,
 			reqBody:        `[{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "latest"]},{"jsonrpc":"2.0","method":"eth_getBalance","id":2,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "latest"]}]`,
 			expectedCode:   http.StatusOK,
-			expectedBody:   `[{"jsonrpc":"2.0","id":"1","result":"success","attestation":{"signatureFormat":"ssh-rsa","hashAlgo":"sha256","identity":"mock_identity","msg":"68e7a69974a641064a6a5ae8b1a00997939a325ec585a49e9fe82b386a21726a","signature":"8e71bb7db5b3b719e12a36219c05308dff130740f2714ed3bbf04f69cb6e95a691792cc7492c14c13c74b76cdbc939169b1f6ba53ff4f82b8c89875d9f49b8db6d83ef4924f18931e975bd27de9e6e734ed5c930330f14c2f36e6002b577a37de27adf57a4b17bcee8816d757c989f5119807c4cd85212712eecc042dc6e917a"}},{"jsonrpc":"2.0","id":"2","result":"success","attestation":{"msg":"68e7a69974a641064a6a5ae8b1a00997939a325ec585a49e9fe82b386a21726a","signature":"8e71bb7db5b3b719e12a36219c05308dff130740f2714ed3bbf04f69cb6e95a691792cc7492c14c13c74b76cdbc939169b1f6ba53ff4f82b8c89875d9f49b8db6d83ef4924f18931e975bd27de9e6e734ed5c930330f14c2f36e6002b577a37de27adf57a4b17bcee8816d757c989f5119807c4cd85212712eecc042dc6e917a"}}]`,
+			expectedBody:   `[{"jsonrpc":"2.0","id":"1","result":"success","attestation":{"signatureFormat":"ssh-rsa","hashAlgo":"sha256","canonicalization":"jcs","identity":"mock_identity","msg":"68e7a69974a641064a6a5ae8b1a00997939a325ec585a49e9fe82b386a21726a","signature":"8e71bb7db5b3b719e12a36219c05308dff130740f2714ed3bbf04f69cb6e95a691792cc7492c14c13c74b76cdbc939169b1f6ba53ff4f82b8c89875d9f49b8db6d83ef4924f18931e975bd27de9e6e734ed5c930330f14c2f36e6002b577a37de27adf57a4b17bcee8816d757c989f5119807c4cd85212712eecc042dc6e917a"}},{"jsonrpc":"2.0","id":"2","result":"success","attestation":{"msg":"68e7a69974a641064a6a5ae8b1a00997939a325ec585a49e9fe82b386a21726a","signature":"8e71bb7db5b3b719e12a36219c05308dff130740f2714ed3bbf04f69cb6e95a691792cc7492c14c13c74b76cdbc939169b1f6ba53ff4f82b8c89875d9f49b8db6d83ef4924f18931e975bd27de9e6e734ed5c930330f14c2f36e6002b577a37de27adf57a4b17bcee8816d757c989f5119807c4cd85212712eecc042dc6e917a"}}]`,
+		},
+		{
+			name: "Success Case Batch Integers Not Canonicalized",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`[{"jsonrpc":"2.0","result":{"value":12345678901234567890},"id":"1"},{"jsonrpc":"2.0","result":{"value":12345678901234567891},"id":"2"}]`))
+			}),
+			keyFile:        "test-data/.mock_key.pem",
+			useAttestation: true,
+			reqBody:        `[{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "latest"]},{"jsonrpc":"2.0","method":"eth_getBalance","id":2,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "latest"]}]`,
+			expectedCode:   http.StatusOK,
+			expectedBody:   `[{"jsonrpc":"2.0","id":"1","result":{"value":12345678901234567890},"attestation":{"signatureFormat":"ssh-rsa","hashAlgo":"sha256","canonicalization":"none","identity":"mock_identity","msg":"f12a383509aae55ef78dbc30e86450c296aef2cc9659e7c031b651a34609af38","signature":"a582e01b340ca3a99ec106578ce45e2cccdc6d3f75a6c47faf7bc1fdd55a09dfd823142a8d5c1b26bb66a6cb0f9ef380d255434a03316bd0d859590c455096f9b02e0c9cd82e219a61b374604d27b33dc8bf36042f2a0487e1b7000cd1d1f3a596456d9822421b135427ad4d1a50dd5ecba94c91c519127073c95d39000638ca"}},{"jsonrpc":"2.0","id":"2","result":{"value":12345678901234567891},"attestation":{"signatureFormat":"ssh-rsa","hashAlgo":"sha256","canonicalization":"none","identity":"mock_identity","msg":"f3cbe53dd1478ffbc16d1cdcf9daa4dba4333d5d9d96652659ef3721ed1a294a","signature":"2fc3108559790ac5c10581458ac1d0180fdc2909ba6ef467ee8cb65b1232f9e0d9643ad6f20c75244b2abc4ea46ddc39651aa8c98ee66678956bb8ce5d336f9bcec74f817ade597553132bddda0ba402c4b3df8d9a1f26e29f4dd38bf043a2ce2f9bdd59d6c7950860b263853ca013f95e17c408f39cf4ed665fc85736986f08"}}]`,
 		},
 		{
 			name: "Failure Case Invalid Req Body",
@@ -81,7 +119,7 @@ func TestAttestorHandler(t *testing.T) {
 			useAttestation: true,
 			reqBody:        `{"jsonrpc": 1}`,
 			expectedCode:   http.StatusBadRequest,
-			expectedBody:   "Invalid request format\n",
+			expectedBody:   `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"},"attestation":{"signatureFormat":"ssh-rsa","hashAlgo":"sha256","canonicalization":"jcs","identity":"mock_identity","msg":"15242f857373adb862d0011aa167d7fe053de5aa744288b9a75877959550a548","signature":"6bfc60b04673048f6cc75cb1a51f56554b6c7f92a96377f96269f8d1d832a1b8a6ed19a4d194cc54fc5951ef1d887c61b5ed418d98a4c2dd5c08b5b6df8e870807795ca24143b0773e5d07a2c292d16af2c8925e7c5378cd7713d7f88701fde0aa10bfd59872ec09572f80bed8c7532d02a34447365fffcf622ae0a178b29fbb"}}`,
 		},
 		{
 			name: "Failure Case Invalid Res Body",
@@ -92,8 +130,46 @@ func TestAttestorHandler(t *testing.T) {
 			keyFile:        "test-data/.mock_key.pem",
 			useAttestation: true,
 			reqBody:        `{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "latest"]}`,
-			expectedCode:   http.StatusBadRequest,
-			expectedBody:   "Invalid response format\n",
+			expectedCode:   http.StatusInternalServerError,
+			expectedBody:   `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"invalid response format"},"attestation":{"signatureFormat":"ssh-rsa","hashAlgo":"sha256","canonicalization":"jcs","identity":"mock_identity","msg":"2ec9e6d11e344cbcda75e1fb359baa330a6cc79cda27d5a3e2df3865c62c4d7c","signature":"3ce097f46dc9781f008235aae7ce4f41137db3be8939023667ed3fb13df93080887568618d3d477378550fc96d10e43fba78ea09cfe02914fb0e3bc0156079583c0d16cc130f73bbea6506cacf76143fed81342cf8ce66015662ce90e280c0f7a5c687250e80b5fa9a932c795d39146b1d5907447472352737235c3f9b5df73b"}}`,
+		},
+		{
+			name: "Failure Case Invalid Items In Batch",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`[{"jsonrpc":"2.0","result":"0x1","id":1}]`))
+			}),
+			reqBody:      `[{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "latest"]},{"jsonrpc":"2.0","id":2},5]`,
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"jsonrpc":"2.0","result":"0x1","id":1},{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":2},{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":null}]`,
+		},
+		{
+			name: "Failure Case All Items In Batch Invalid",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}),
+			reqBody:      `[{"jsonrpc":"2.0","id":1}]`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `[{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":1}]`,
+		},
+		{
+			name: "Failure Case Invalid Getter In Batch",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`[{"jsonrpc":"2.0","result":"0x1","id":1}]`))
+			}),
+			reqBody:      `[{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "latest"]},{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":2,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", 5]}]`,
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"jsonrpc":"2.0","result":"0x1","id":1},{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error"},"id":2}]`,
+		},
+		{
+			name: "Failure Case Invalid Getter",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}),
+			reqBody:      `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "earliestt"]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error"},"id":1}`,
 		},
 	}
 
@@ -152,3 +228,939 @@ func TestAttestorHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestPinnedModeHandler(t *testing.T) {
+	var forwardedReqs [][]*models.RPCReq
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []*models.RPCReq
+		json.NewDecoder(r.Body).Decode(&reqs)
+		forwardedReqs = append(forwardedReqs, reqs)
+
+		w.WriteHeader(http.StatusOK)
+		if reqs[0].Method == "eth_getBlockByNumber" {
+			w.Write([]byte(fmt.Sprintf(`[{"jsonrpc":"2.0","result":{"number":"0x21"},"id":%s}]`, reqs[0].ID)))
+			return
+		}
+		w.Write([]byte(`[{"jsonrpc":"2.0","result":"0x1","id":1}]`))
+	})
+
+	mockServer := httptest.NewServer(handler)
+	defer mockServer.Close()
+
+	reqBody := `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`
+	req, err := http.NewRequest("POST", "/", bytes.NewBufferString(reqBody))
+	if err != nil {
+		t.Fatalf("Error creating mock request: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+
+	context := &RPCContext{
+		DefaultChainURL:    mockServer.URL,
+		CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+		PinnedMode:         true,
+		Logger:             slog.Default(),
+	}
+
+	http.HandlerFunc(context.Handler).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	if len(forwardedReqs) != 2 {
+		t.Fatalf("Expected 2 forwarded requests, got %d", len(forwardedReqs))
+	}
+
+	if len(forwardedReqs[0]) != 1 || forwardedReqs[0][0].Method != "eth_getBlockByNumber" {
+		t.Errorf("Expected getter request to be resolved first, got %v", forwardedReqs[0])
+	}
+
+	expectedParams := `["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","0x21"]`
+	if len(forwardedReqs[1]) != 1 || string(forwardedReqs[1][0].Params) != expectedParams {
+		t.Errorf("Expected pinned params %s, got %s", expectedParams, forwardedReqs[1][0].Params)
+	}
+
+	expectedBody := `{"jsonrpc":"2.0","result":{"data":"0x1","blockNumber":"0x21"},"id":1}`
+	if rec.Body.String() != expectedBody {
+		t.Errorf("Expected body %s, got %s", expectedBody, rec.Body)
+	}
+}
+
+func TestChainHandler(t *testing.T) {
+	evmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"jsonrpc":"2.0","result":"0x1","id":1},{"jsonrpc":"2.0","result":{"number":"0x21"},"id":2}]`))
+	}))
+	defer evmServer.Close()
+
+	solanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"jsonrpc":"2.0","result":211,"id":1}]`))
+	}))
+	defer solanaServer.Close()
+
+	evmPool, _ := upstream.NewPool([]string{evmServer.URL}, upstream.PolicyRoundRobin, nil, nil)
+	solanaPool, _ := upstream.NewPool([]string{solanaServer.URL}, upstream.PolicyRoundRobin, nil, nil)
+
+	tests := []struct {
+		name         string
+		path         string
+		reqBody      string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "EVM route",
+			path:         "/rpc/ethereum",
+			reqBody:      `{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"jsonrpc":"2.0","result":"0x1","id":1}`,
+		},
+		{
+			name:         "Solana route",
+			path:         "/rpc/solana",
+			reqBody:      `{"jsonrpc":"2.0","method":"getBlockHeight","id":1}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"jsonrpc":"2.0","result":211,"id":1}`,
+		},
+		{
+			name:         "Unknown route",
+			path:         "/rpc/bitcoin",
+			reqBody:      `{"jsonrpc":"2.0","method":"getblockcount","id":1}`,
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"chain not found"},"id":null}`,
+		},
+	}
+
+	context := &RPCContext{
+		ChainRoutes: map[string]*ChainRoute{
+			"ethereum": {
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+				Upstreams:          evmPool,
+			},
+			"solana": {
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/solana.json"),
+				Upstreams:          solanaPool,
+			},
+		},
+		Logger: slog.Default(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rpc/{chainName}", context.ChainHandler)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", tt.path, bytes.NewBufferString(tt.reqBody))
+			if err != nil {
+				t.Fatalf("Error creating mock request: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Errorf("Test case %s: Expected status code %d, got %d", tt.name, tt.expectedCode, rec.Code)
+			}
+
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedBody, rec.Body)
+			}
+		})
+	}
+}
+
+func TestUpstreamContextHandler(t *testing.T) {
+	var batchMethods []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		results := map[string]string{
+			"getSlot":        "300",
+			"getVersion":     `{"solana-core":"2.1.0","feature-set":1}`,
+			"getBlockHeight": "211",
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		var req *models.RPCReq
+		if json.Unmarshal(body, &req) == nil {
+			w.Write([]byte(fmt.Sprintf(`{"jsonrpc":"2.0","result":%s,"id":%s}`, results[req.Method], req.ID)))
+			return
+		}
+
+		var reqs []*models.RPCReq
+		json.Unmarshal(body, &reqs)
+		var ress []string
+		for _, req := range reqs {
+			batchMethods = append(batchMethods, req.Method)
+			ress = append(ress, fmt.Sprintf(`{"jsonrpc":"2.0","result":%s,"id":%s}`, results[req.Method], req.ID))
+		}
+		w.Write([]byte("[" + strings.Join(ress, ",") + "]"))
+	}))
+	defer mockServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool, _ := upstream.NewPool([]string{mockServer.URL}, upstream.PolicyRoundRobin, customrpcmethods.ChainTypeToPublicData[customrpcmethods.ChainTypeSolana], nil)
+	pool.StartHealthChecks(ctx, time.Hour)
+
+	tests := []struct {
+		name            string
+		streamResponses bool
+		expectedBody    string
+		expectedBatches string
+	}{
+		{
+			name:            "Buffered",
+			expectedBody:    `{"jsonrpc":"2.0","result":{"value":211,"context":{"apiVersion":"2.1.0","slot":300}},"id":1}`,
+			expectedBatches: "getBlockHeight,getSlot",
+		},
+		{
+			name:            "Streamed",
+			streamResponses: true,
+			expectedBody:    `{"jsonrpc":"2.0","id":1,"result":{"value":211,"context":{"apiVersion":"2.1.0","slot":300}}}`,
+			expectedBatches: "getSlot,getBlockHeight",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batchMethods = nil
+			req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","method":"getBlockHeightAndContext","id":1}`))
+			if err != nil {
+				t.Fatalf("Test case %s: Error creating mock request: %v", tt.name, err)
+			}
+			rec := httptest.NewRecorder()
+
+			context := &RPCContext{
+				Upstreams:          pool,
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/solana.json"),
+				PinnedMode:         tt.streamResponses, // the getters are resolved first so the result can be streamed
+				StreamResponses:    tt.streamResponses,
+				Logger:             slog.Default(),
+			}
+			http.HandlerFunc(context.Handler).ServeHTTP(rec, req)
+
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedBody, rec.Body)
+			}
+			if strings.Join(batchMethods, ",") != tt.expectedBatches {
+				t.Errorf("Test case %s: Expected the version of the upstream to be cached, got the batch %v", tt.name, batchMethods)
+			}
+		})
+	}
+}
+
+func TestHandlerTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.Setup(exporter, "test")
+	defer func() {
+		tp.Shutdown(context.Background())
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	}()
+
+	var traceparent string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+
+		var reqs []*models.RPCReq
+		json.NewDecoder(r.Body).Decode(&reqs)
+		var ress []*models.RPCResJSON
+		for _, req := range reqs {
+			result := json.RawMessage(`"0x1"`)
+			if req.Method == "eth_getBlockByNumber" {
+				result = json.RawMessage(`{"number":"0x21"}`)
+			}
+			ress = append(ress, &models.RPCResJSON{JSONRPC: "2.0", Result: result, ID: req.ID})
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(ress)
+	}))
+	defer mockServer.Close()
+
+	reqBody := `[{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]},{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":2,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}]`
+	req, err := http.NewRequest("POST", "/", bytes.NewBufferString(reqBody))
+	if err != nil {
+		t.Fatalf("Error creating mock request: %v", err)
+	}
+
+	rpcContext := &RPCContext{
+		DefaultChainURL:    mockServer.URL,
+		CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+		Logger:             slog.Default(),
+	}
+
+	rec := httptest.NewRecorder()
+	http.HandlerFunc(rpcContext.Handler).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	tp.ForceFlush(context.Background())
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+
+	for _, name := range []string{"handle", "parseRPCReq", "modifyReq", "doRPCCall", "upstream", "modifyRes", "returnRes"} {
+		_, ok := spans[name]
+		if !ok {
+			t.Errorf("Expected span %s, got %v", name, spans)
+		}
+	}
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, attr := range spans["handle"].Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	if attrs["rpc.batch_size"].AsInt64() != 2 {
+		t.Errorf("Expected batch size 2, got %v", attrs["rpc.batch_size"])
+	}
+	expectedMethods := []string{"eth_getBalance", "eth_getBalanceAndBlockNumber"}
+	if fmt.Sprint(attrs["rpc.methods"].AsStringSlice()) != fmt.Sprint(expectedMethods) {
+		t.Errorf("Expected methods %v, got %v", expectedMethods, attrs["rpc.methods"].AsStringSlice())
+	}
+
+	upstreamSpan := spans["upstream"]
+	if upstreamSpan.Parent.SpanID() != spans["doRPCCall"].SpanContext.SpanID() {
+		t.Errorf("Expected upstream span to be child of doRPCCall span")
+	}
+	expectedTraceparent := fmt.Sprintf("00-%s-%s-01", upstreamSpan.SpanContext.TraceID(), upstreamSpan.SpanContext.SpanID())
+	if traceparent != expectedTraceparent {
+		t.Errorf("Expected traceparent %s, got %s", expectedTraceparent, traceparent)
+	}
+}
+
+func TestGetterCacheHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		pinnedMode           bool
+		expectedForwarded    []int // number of reqs forwarded on each upstream call
+		expectedSecondParams string
+	}{
+		{
+			name:                 "Regular mode",
+			expectedForwarded:    []int{2, 1},
+			expectedSecondParams: `["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]`,
+		},
+		{
+			name:                 "Pinned mode",
+			pinnedMode:           true,
+			expectedForwarded:    []int{1, 1, 1},
+			expectedSecondParams: `["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","0x21"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var forwardedReqs [][]*models.RPCReq
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var reqs []*models.RPCReq
+				json.NewDecoder(r.Body).Decode(&reqs)
+				forwardedReqs = append(forwardedReqs, reqs)
+
+				var ress []*models.RPCResJSON
+				for _, req := range reqs {
+					result := json.RawMessage(`"0x1"`)
+					if req.Method == "eth_getBlockByNumber" {
+						result = json.RawMessage(`{"number":"0x21"}`)
+					}
+					ress = append(ress, &models.RPCResJSON{JSONRPC: "2.0", Result: result, ID: req.ID})
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(ress)
+			}))
+			defer mockServer.Close()
+
+			rpcContext := &RPCContext{
+				DefaultChainURL:    mockServer.URL,
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+				GetterCache:        gettercache.New(time.Minute, 0),
+				PinnedMode:         tt.pinnedMode,
+				Logger:             slog.Default(),
+			}
+
+			reqBody := `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`
+			expectedBody := `{"jsonrpc":"2.0","result":{"data":"0x1","blockNumber":"0x21"},"id":1}`
+			for i := 0; i < 2; i++ {
+				req, err := http.NewRequest("POST", "/", bytes.NewBufferString(reqBody))
+				if err != nil {
+					t.Fatalf("Test case %s: Error creating mock request: %v", tt.name, err)
+				}
+
+				rec := httptest.NewRecorder()
+				http.HandlerFunc(rpcContext.Handler).ServeHTTP(rec, req)
+
+				if rec.Body.String() != expectedBody {
+					t.Errorf("Test case %s: Expected body %s, got %s", tt.name, expectedBody, rec.Body)
+				}
+			}
+
+			if len(forwardedReqs) != len(tt.expectedForwarded) {
+				t.Fatalf("Test case %s: Expected %d upstream calls, got %d", tt.name, len(tt.expectedForwarded), len(forwardedReqs))
+			}
+			for i, expected := range tt.expectedForwarded {
+				if len(forwardedReqs[i]) != expected {
+					t.Errorf("Test case %s: Expected %d reqs on upstream call %d, got %d", tt.name, expected, i, len(forwardedReqs[i]))
+				}
+			}
+
+			lastReqs := forwardedReqs[len(forwardedReqs)-1]
+			if string(lastReqs[0].Params) != tt.expectedSecondParams {
+				t.Errorf("Test case %s: Expected params %s, got %s", tt.name, tt.expectedSecondParams, lastReqs[0].Params)
+			}
+		})
+	}
+}
+
+func TestResponseCacheHandler(t *testing.T) {
+	const blockHash = "0x2f1c5b3b1e2a3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5"
+
+	tests := []struct {
+		name              string
+		reqBody           string
+		expectedCalls     []int // number of upstream calls on each request
+		expectedSecondRes string
+	}{
+		{
+			name:              "Single Immutable Req",
+			reqBody:           `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","` + blockHash + `"]}`,
+			expectedCalls:     []int{1, 0},
+			expectedSecondRes: `{"jsonrpc":"2.0","result":{"data":"0x1","blockNumber":"0x21"},"id":1}`,
+		},
+		{
+			name:              "Batch With Mutable Req",
+			reqBody:           `[{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","0x10"]},{"jsonrpc":"2.0","method":"eth_getBalance","id":2,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}]`,
+			expectedCalls:     []int{1, 1},
+			expectedSecondRes: `[{"jsonrpc":"2.0","result":"0x1","id":2},{"jsonrpc":"2.0","result":"0x1","id":1}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				var reqs []*models.RPCReq
+				json.NewDecoder(r.Body).Decode(&reqs)
+
+				var ress []*models.RPCResJSON
+				for _, req := range reqs {
+					result := json.RawMessage(`"0x1"`)
+					if req.Method == "eth_getBlockByHash" {
+						result = json.RawMessage(`{"number":"0x21"}`)
+					}
+					ress = append(ress, &models.RPCResJSON{JSONRPC: "2.0", Result: result, ID: req.ID})
+				}
+
+				w.WriteHeader(http.StatusOK)
+				json.NewEncoder(w).Encode(ress)
+			}))
+			defer mockServer.Close()
+
+			responseCache := NewResponseCache("ethereum", NewLRUStore(0))
+			responseCache.SetFinalizedHeight(0x20)
+			rpcContext := &RPCContext{
+				DefaultChainURL:    mockServer.URL,
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+				ResponseCache:      responseCache,
+				Logger:             slog.Default(),
+			}
+
+			for i, expectedCalls := range tt.expectedCalls {
+				calls = 0
+				req, err := http.NewRequest("POST", "/", bytes.NewBufferString(tt.reqBody))
+				if err != nil {
+					t.Fatalf("Test case %s: Error creating mock request: %v", tt.name, err)
+				}
+
+				rec := httptest.NewRecorder()
+				http.HandlerFunc(rpcContext.Handler).ServeHTTP(rec, req)
+
+				if rec.Code != http.StatusOK {
+					t.Errorf("Test case %s: Expected status code %d, got %d", tt.name, http.StatusOK, rec.Code)
+				}
+				if calls != expectedCalls {
+					t.Errorf("Test case %s: Expected %d upstream calls on request %d, got %d", tt.name, expectedCalls, i, calls)
+				}
+				if i == len(tt.expectedCalls)-1 && rec.Body.String() != tt.expectedSecondRes {
+					t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedSecondRes, rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseCacheAttestationV2Handler(t *testing.T) {
+	const blockHash = "0x2f1c5b3b1e2a3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5"
+
+	calls := 0
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var reqs []*models.RPCReq
+		json.NewDecoder(r.Body).Decode(&reqs)
+
+		var ress []*models.RPCResJSON
+		for _, req := range reqs {
+			result := json.RawMessage(`"0x1"`)
+			if req.Method == "eth_getBlockByHash" {
+				result = json.RawMessage(`{"number":"0x21"}`)
+			}
+			ress = append(ress, &models.RPCResJSON{JSONRPC: "2.0", Result: result, ID: req.ID})
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(ress)
+	}))
+	defer mockServer.Close()
+
+	responseCache := NewResponseCache("ethereum", NewLRUStore(0))
+	responseCache.SetFinalizedHeight(0x20)
+	rpcContext := &RPCContext{
+		DefaultChain:       "ethereum",
+		DefaultChainURL:    mockServer.URL,
+		CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+		ResponseCache:      responseCache,
+		Logger:             slog.Default(),
+	}
+	rpcContext.EnableAttestation("test-data/.mock_key.pem", "", "mock_identity")
+	rpcContext.AttestationVersion = attestation.Version2
+
+	signer, err := attestation.GetSigningKeyFromKeyFile("test-data/.mock_key.pem")
+	if err != nil {
+		t.Fatalf("Error reading key file: %v", err)
+	}
+
+	params := json.RawMessage(`["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","` + blockHash + `"]`)
+	binding := attestation.Binding{Chain: "ethereum", Method: "eth_getBalanceAndBlockNumber", Params: params}
+	for i, expectedCalls := range []int{1, 0} {
+		calls = 0
+		req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":`+string(params)+`}`))
+		if err != nil {
+			t.Fatalf("Error creating mock request: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		http.HandlerFunc(rpcContext.Handler).ServeHTTP(rec, req)
+
+		if calls != expectedCalls {
+			t.Errorf("Expected %d upstream calls on request %d, got %d", expectedCalls, i, calls)
+		}
+
+		var res struct {
+			Result      json.RawMessage     `json:"result"`
+			Attestation *models.Attestation `json:"attestation"`
+		}
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Fatalf("Error decoding response %d: %v", i, err)
+		}
+		if res.Attestation == nil || res.Attestation.Block != "0x21" {
+			t.Fatalf("Expected the attestation of request %d at block 0x21, got %+v", i, res.Attestation)
+		}
+		err = attestation.VerifyV2(res.Result, res.Attestation, binding, signer.PublicKey())
+		if err != nil {
+			t.Errorf("Expected a valid attestation on request %d, got %v", i, err)
+		}
+	}
+}
+
+func TestAttestationV2Handler(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []*models.RPCReq
+		json.NewDecoder(r.Body).Decode(&reqs)
+
+		var ress []*models.RPCResJSON
+		for _, req := range reqs {
+			result := json.RawMessage(`"0x1"`)
+			if req.Method == "eth_getBlockByNumber" {
+				result = json.RawMessage(`{"number":"0x21"}`)
+			}
+			ress = append(ress, &models.RPCResJSON{JSONRPC: "2.0", Result: result, ID: req.ID})
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(ress)
+	}))
+	defer mockServer.Close()
+
+	rpcContext := &RPCContext{
+		DefaultChain:       "ethereum",
+		DefaultChainURL:    mockServer.URL,
+		CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+		Logger:             slog.Default(),
+	}
+	rpcContext.EnableAttestation("test-data/.mock_key.pem", "", "mock_identity")
+	rpcContext.AttestationVersion = attestation.Version2
+
+	params := json.RawMessage(`["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4", "latest"]`)
+	reqBody := `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":` + string(params) + `}`
+
+	tests := []struct {
+		name          string
+		nonce         string
+		binding       attestation.Binding
+		expectedCode  int
+		expectedValid bool
+	}{
+		{
+			name:          "Request Binding",
+			nonce:         "client-nonce",
+			binding:       attestation.Binding{Chain: "ethereum", Method: "eth_getBalanceAndBlockNumber", Params: params, Nonce: "client-nonce"},
+			expectedCode:  http.StatusOK,
+			expectedValid: true,
+		},
+		{
+			name:          "Other Method",
+			nonce:         "client-nonce",
+			binding:       attestation.Binding{Chain: "ethereum", Method: "eth_getCodeAndBlockNumber", Params: params, Nonce: "client-nonce"},
+			expectedCode:  http.StatusOK,
+			expectedValid: false,
+		},
+		{
+			name:          "Other Chain",
+			nonce:         "client-nonce",
+			binding:       attestation.Binding{Chain: "arbitrum", Method: "eth_getBalanceAndBlockNumber", Params: params, Nonce: "client-nonce"},
+			expectedCode:  http.StatusOK,
+			expectedValid: false,
+		},
+		{
+			name:          "Other Nonce",
+			nonce:         "client-nonce",
+			binding:       attestation.Binding{Chain: "ethereum", Method: "eth_getBalanceAndBlockNumber", Params: params, Nonce: "other-nonce"},
+			expectedCode:  http.StatusOK,
+			expectedValid: false,
+		},
+		{
+			name:         "Nonce Too Long",
+			nonce:        string(bytes.Repeat([]byte("n"), maxNonceLength+1)),
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	signer, err := attestation.GetSigningKeyFromKeyFile("test-data/.mock_key.pem")
+	if err != nil {
+		t.Fatalf("Error reading key file: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/", bytes.NewBufferString(reqBody))
+			if err != nil {
+				t.Fatalf("Test case %s: Error creating mock request: %v", tt.name, err)
+			}
+			req.Header.Set(nonceHeader, tt.nonce)
+
+			rec := httptest.NewRecorder()
+			http.HandlerFunc(rpcContext.Handler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("Test case %s: Expected status code %d, got %d", tt.name, tt.expectedCode, rec.Code)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var res struct {
+				Result      json.RawMessage     `json:"result"`
+				Attestation *models.Attestation `json:"attestation"`
+			}
+			err = json.Unmarshal(rec.Body.Bytes(), &res)
+			if err != nil {
+				t.Fatalf("Test case %s: Error decoding response: %v", tt.name, err)
+			}
+
+			if res.Attestation.Version != attestation.Version2 || res.Attestation.Block != "0x21" || res.Attestation.Nonce != tt.nonce {
+				t.Errorf("Test case %s: Expected version 2 attestation at block 0x21, got %+v", tt.name, res.Attestation)
+			}
+
+			err = attestation.VerifyV2(res.Result, res.Attestation, tt.binding, signer.PublicKey())
+			if (err == nil) != tt.expectedValid {
+				t.Errorf("Test case %s: Expected valid %v, got error %v", tt.name, tt.expectedValid, err)
+			}
+		})
+	}
+}
+
+func TestStreamHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		upstreamBody string
+		reqBody      string
+		pinnedMode   bool
+		expectedBody string
+	}{
+		{
+			name:         "Regular Method",
+			upstreamBody: `[{"jsonrpc":"2.0","id":1,"result":{"b": 18446744073709551617, "a":"}\"]"}}]`,
+			reqBody:      `{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","0x21"]}`,
+			expectedBody: `{"jsonrpc":"2.0","id":1,"result":{"b": 18446744073709551617, "a":"}\"]"}}`,
+		},
+		{
+			name:         "Custom Method",
+			upstreamBody: `[{"jsonrpc":"2.0","id":1,"result":"0x1"}]`,
+			reqBody:      `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`,
+			pinnedMode:   true,
+			expectedBody: `{"jsonrpc":"2.0","id":1,"result":{"data":"0x1","blockNumber":"0x21"}}`,
+		},
+		{
+			name:         "Error Response",
+			upstreamBody: `[{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"header not found"}}]`,
+			reqBody:      `{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","0x21"]}`,
+			expectedBody: `{"jsonrpc":"2.0","error":{"code":-32000,"message":"header not found"},"id":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var reqs []*models.RPCReq
+				json.NewDecoder(r.Body).Decode(&reqs)
+
+				w.WriteHeader(http.StatusOK)
+				if reqs[0].Method == "eth_getBlockByNumber" {
+					w.Write([]byte(fmt.Sprintf(`[{"jsonrpc":"2.0","result":{"number":"0x21"},"id":%s}]`, reqs[0].ID)))
+					return
+				}
+				w.Write([]byte(tt.upstreamBody))
+			}))
+			defer mockServer.Close()
+
+			req, err := http.NewRequest("POST", "/", bytes.NewBufferString(tt.reqBody))
+			if err != nil {
+				t.Fatalf("Test case %s: Error creating mock request: %v", tt.name, err)
+			}
+			rec := httptest.NewRecorder()
+
+			context := &RPCContext{
+				DefaultChainURL:    mockServer.URL,
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+				PinnedMode:         tt.pinnedMode,
+				StreamResponses:    true,
+				MaxResponseSize:    64,
+				Logger:             slog.Default(),
+			}
+			http.HandlerFunc(context.Handler).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Test case %s: Expected status code %d, got %d", tt.name, http.StatusOK, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedBody, rec.Body)
+			}
+		})
+	}
+}
+
+func TestStreamAttestationHandler(t *testing.T) {
+	result := `{"logs": [1, 2.50, 18446744073709551617]}`
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":` + result + `}]`))
+	}))
+	defer mockServer.Close()
+
+	rpcContext := &RPCContext{
+		DefaultChain:       "ethereum",
+		DefaultChainURL:    mockServer.URL,
+		CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+		StreamResponses:    true,
+		Logger:             slog.Default(),
+	}
+	rpcContext.EnableAttestation("test-data/.mock_key.pem", "", "mock_identity")
+	rpcContext.AttestationVersion = attestation.Version2
+
+	params := json.RawMessage(`[{"blockHash":"0x3f07a9c83155594c000642e7d60e8a8a00038d03e9849171a05ed0e2d47acbb3"}]`)
+	req, err := http.NewRequest("POST", "/", bytes.NewBufferString(`{"jsonrpc":"2.0","method":"eth_getLogs","id":1,"params":`+string(params)+`}`))
+	if err != nil {
+		t.Fatalf("Error creating mock request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	http.HandlerFunc(rpcContext.Handler).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var res struct {
+		Result      json.RawMessage     `json:"result"`
+		Attestation *models.Attestation `json:"attestation"`
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("Error decoding response: %v", err)
+	}
+	if string(res.Result) != result {
+		t.Errorf("Expected result %s, got %s", result, res.Result)
+	}
+	if res.Attestation.Canonicalization != attestation.RawScheme {
+		t.Errorf("Expected canonicalization %s, got %s", attestation.RawScheme, res.Attestation.Canonicalization)
+	}
+
+	signer, err := attestation.GetSigningKeyFromKeyFile("test-data/.mock_key.pem")
+	if err != nil {
+		t.Fatalf("Error reading key file: %v", err)
+	}
+	binding := attestation.Binding{Chain: "ethereum", Method: "eth_getLogs", Params: params}
+	err = attestation.VerifyV2(res.Result, res.Attestation, binding, signer.PublicKey())
+	if err != nil {
+		t.Errorf("Expected valid attestation, got error %v", err)
+	}
+}
+
+func TestMaxBodySizes(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":"0x0000000000000000000000000000000000000000000000000000000000000001"}]`))
+	}))
+	defer mockServer.Close()
+
+	reqBody := `{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`
+
+	tests := []struct {
+		name               string
+		maxRequestBodySize int64
+		maxResponseSize    int64
+		expectedCode       int
+		expectedBody       string
+	}{
+		{
+			name:               "Request Too Large",
+			maxRequestBodySize: 16,
+			expectedCode:       http.StatusRequestEntityTooLarge,
+			expectedBody:       `{"jsonrpc":"2.0","error":{"code":-32600,"message":"request body is too large"},"id":null}`,
+		},
+		{
+			name:            "Response Too Large",
+			maxResponseSize: 64,
+			expectedCode:    http.StatusBadGateway,
+			expectedBody:    `{"jsonrpc":"2.0","error":{"code":-32027,"message":"response body is too large"},"id":1}`,
+		},
+		{
+			name:               "Under The Limits",
+			maxRequestBodySize: 1024,
+			maxResponseSize:    1024,
+			expectedCode:       http.StatusOK,
+			expectedBody:       `{"jsonrpc":"2.0","result":"0x0000000000000000000000000000000000000000000000000000000000000001","id":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/", bytes.NewBufferString(reqBody))
+			if err != nil {
+				t.Fatalf("Test case %s: Error creating mock request: %v", tt.name, err)
+			}
+			rec := httptest.NewRecorder()
+
+			context := &RPCContext{
+				DefaultChainURL:    mockServer.URL,
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+				MaxRequestBodySize: tt.maxRequestBodySize,
+				MaxResponseSize:    tt.maxResponseSize,
+				Logger:             slog.Default(),
+			}
+			http.HandlerFunc(context.Handler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Errorf("Test case %s: Expected status code %d, got %d", tt.name, tt.expectedCode, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedBody, rec.Body)
+			}
+		})
+	}
+}
+
+// mockBitcoind answers the batches as bitcoind, with a null error on the successful responses
+func mockBitcoind(t *testing.T, bestBlockHash string, height int, results map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []*models.RPCReq
+		err := json.NewDecoder(r.Body).Decode(&reqs)
+		if err != nil {
+			t.Errorf("Error decoding bitcoind batch: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var ress []string
+		for _, req := range reqs {
+			var result string
+			switch req.Method {
+			case "getblockcount":
+				result = strconv.Itoa(height)
+			case "getblockchaininfo":
+				result = fmt.Sprintf(`{"chain":"main","blocks":%d,"bestblockhash":"%s"}`, height, bestBlockHash)
+			default:
+				var ok bool
+				result, ok = results[req.Method]
+				if !ok {
+					ress = append(ress, fmt.Sprintf(`{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":%s}`, req.ID))
+					continue
+				}
+			}
+			ress = append(ress, fmt.Sprintf(`{"result":%s,"error":null,"id":%s}`, result, req.ID))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("[" + strings.Join(ress, ",") + "]"))
+	}))
+}
+
+func TestBitcoinHandler(t *testing.T) {
+	bestBlockHash := "000000000000000000026d2c3c1f9e5f2c9f1f3b0a1c6a5b2d6e4a4b7e1f0c11"
+	blockHash := "00000000000000000001c0a8a2b3f6e2d5e7c9b1a3f4d6e8c0b2a4f6d8e0c2a4"
+	mockServer := mockBitcoind(t, bestBlockHash, 850000, map[string]string{
+		"gettxout":       `{"bestblock":"` + bestBlockHash + `","confirmations":1,"value":0.5}`,
+		"getblockheader": `{"hash":"` + blockHash + `","confirmations":3,"height":849998}`,
+	})
+	defer mockServer.Close()
+
+	tests := []struct {
+		name         string
+		reqBody      string
+		expectedBody string
+	}{
+		{
+			name:         "Best block",
+			reqBody:      `{"jsonrpc":"2.0","method":"gettxoutAndBlockHeight","id":1,"params":["d5ada064c6417ca25c4308bd158c34b77e1c0eca2a73cda16c737e7424afba2f",0]}`,
+			expectedBody: `{"jsonrpc":"2.0","result":{"data":{"bestblock":"` + bestBlockHash + `","confirmations":1,"value":0.5},"blockHeight":850000,"blockHash":"` + bestBlockHash + `"},"id":1}`,
+		},
+		{
+			name:         "Block hash",
+			reqBody:      `{"jsonrpc":"2.0","method":"getblockheaderAndBlockHeight","id":1,"params":["` + blockHash + `",true]}`,
+			expectedBody: `{"jsonrpc":"2.0","result":{"data":{"hash":"` + blockHash + `","confirmations":3,"height":849998},"blockHeight":849998,"blockHash":"` + blockHash + `"},"id":1}`,
+		},
+		{
+			name:         "Batch",
+			reqBody:      `[{"jsonrpc":"2.0","method":"getblockchaininfoAndBlockHeight","id":1},{"jsonrpc":"2.0","method":"getblockcount","id":2}]`,
+			expectedBody: `[{"jsonrpc":"2.0","result":{"data":{"chain":"main","blocks":850000,"bestblockhash":"` + bestBlockHash + `"},"blockHeight":850000,"blockHash":"` + bestBlockHash + `"},"id":1},{"jsonrpc":"2.0","result":850000,"id":2}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/", bytes.NewBufferString(tt.reqBody))
+			if err != nil {
+				t.Fatalf("Test case %s: Error creating mock request: %v", tt.name, err)
+			}
+			rec := httptest.NewRecorder()
+
+			context := &RPCContext{
+				DefaultChainURL:    mockServer.URL,
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/bitcoin.json"),
+				Logger:             slog.Default(),
+			}
+			http.HandlerFunc(context.Handler).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Test case %s: Expected status code %d, got %d", tt.name, http.StatusOK, rec.Code)
+			}
+			if rec.Body.String() != tt.expectedBody {
+				t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedBody, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package rpccontext
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+
+	"github.com/stateless-solutions/compatibility-layer/attestation"
+	customrpcmethods "github.com/stateless-solutions/compatibility-layer/custom-rpc-methods"
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+var (
+	errBodyTooLarge     = errors.New("body is too large")
+	errInvalidStreamRes = errors.New("invalid response format")
+)
+
+// readBody reads the body up to the max size, 0 means no limit
+func readBody(body io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return io.ReadAll(body)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errBodyTooLarge
+	}
+
+	return data, nil
+}
+
+// limitedBuffer is a buffer that fails the writes over its max size, 0 means no limit
+type limitedBuffer struct {
+	bytes.Buffer
+	maxSize int64
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.maxSize > 0 && int64(b.Len()+len(p)) > b.maxSize {
+		return 0, errBodyTooLarge
+	}
+
+	return b.Buffer.Write(p)
+}
+
+// jsonScanner reads the JSON of an upstream response copying the raw values without decoding them
+type jsonScanner struct {
+	r *bufio.Reader
+}
+
+func newJSONScanner(r io.Reader) *jsonScanner {
+	return &jsonScanner{r: bufio.NewReaderSize(r, 32*1024)}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// next returns the next byte that is not whitespace
+func (s *jsonScanner) next() (byte, error) {
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !isSpace(b) {
+			return b, nil
+		}
+	}
+}
+
+// copyValue copies the raw bytes of the next value to w, the value is only scanned for its end
+func (s *jsonScanner) copyValue(w io.Writer) error {
+	first, err := s.next()
+	if err != nil {
+		return err
+	}
+	err = s.r.UnreadByte()
+	if err != nil {
+		return err
+	}
+	scalar := first != '{' && first != '[' && first != '"'
+
+	depth := 0
+	inString := false
+	escaped := false
+	copied := 0
+	for {
+		n := s.r.Buffered()
+		if n == 0 {
+			n = 1
+		}
+		chunk, err := s.r.Peek(n)
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		end := -1
+		for i, b := range chunk {
+			if inString {
+				switch {
+				case escaped:
+					escaped = false
+				case b == '\\':
+					escaped = true
+				case b == '"':
+					inString = false
+					if depth == 0 {
+						end = i + 1
+					}
+				}
+			} else if scalar {
+				if b == ',' || b == '}' || b == ']' || isSpace(b) {
+					end = i
+				}
+			} else {
+				switch b {
+				case '"':
+					inString = true
+				case '{', '[':
+					depth++
+				case '}', ']':
+					depth--
+					if depth == 0 {
+						end = i + 1
+					}
+				}
+			}
+			if end >= 0 {
+				break
+			}
+		}
+
+		if end < 0 {
+			end = len(chunk)
+		}
+		copied += end
+		if copied == 0 {
+			return errInvalidStreamRes
+		}
+		if _, err := w.Write(chunk[:end]); err != nil {
+			return err
+		}
+		if _, err := s.r.Discard(end); err != nil {
+			return err
+		}
+		if end < len(chunk) || (!scalar && depth == 0 && !inString) {
+			return nil
+		}
+	}
+}
+
+// readKey returns the next key of an object, false if the object is closed
+func (s *jsonScanner) readKey(first bool) (string, bool, error) {
+	b, err := s.next()
+	if err != nil {
+		return "", false, err
+	}
+	if b == '}' {
+		return "", false, nil
+	}
+	if !first {
+		if b != ',' {
+			return "", false, errInvalidStreamRes
+		}
+		b, err = s.next()
+		if err != nil {
+			return "", false, err
+		}
+	}
+	if b != '"' {
+		return "", false, errInvalidStreamRes
+	}
+	err = s.r.UnreadByte()
+	if err != nil {
+		return "", false, err
+	}
+
+	var raw bytes.Buffer
+	err = s.copyValue(&raw)
+	if err != nil {
+		return "", false, err
+	}
+	var key string
+	err = json.Unmarshal(raw.Bytes(), &key)
+	if err != nil {
+		return "", false, errInvalidStreamRes
+	}
+
+	b, err = s.next()
+	if err != nil {
+		return "", false, err
+	}
+	if b != ':' {
+		return "", false, errInvalidStreamRes
+	}
+
+	return key, true, nil
+}
+
+// canStream returns if the response of the handler can be streamed, the getters left to resolve are posted before
+// so only single reqs that are not saved on the response cache and whose result is not wrapped by a plugin are streamed
+func (c *RPCContext) canStream(rh *reqHandler) bool {
+	if !c.StreamResponses || rh.IsSlice || len(rh.CacheKeys) > 0 {
+		return false
+	}
+
+	rpcReqs, _ := customrpcmethods.SplitGetterReqs(rh.RPCReqs, rh.IDsHolder)
+	return len(rpcReqs) == 1 && !rh.CustomMethodHolder.WrapsResult(rpcReqs[0].ID, rh.ChangedMethods)
+}
+
+// streamRPCCall forwards the single req of the handler and streams the upstream result to the client
+// wrapped with the envelope of its custom method, the result is hashed while it is written to attest it
+// responses without a result are small so they are buffered and returned by the next stages
+func (c *RPCContext) streamRPCCall(w http.ResponseWriter, r *http.Request, rh *reqHandler) error {
+	id := rh.RPCReqs[0].ID
+	resp, body, err := c.forwardRPCReqs(w, r, rh, rh.RPCReqs)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, err := c.readResBody(w, rh, body)
+		if err != nil {
+			return err
+		}
+		return c.writeNotOKRes(w, rh, resp, respBody)
+	}
+
+	// the envelope is built once the upstream is known since its context is added to it
+	rh.addUpstreamContext()
+	prefix, suffix, block, gtError, err := rh.CustomMethodHolder.ResultEnvelope(id, rh.GetterRess, rh.ChangedMethods, rh.IDsHolder, rh.CustomMethodsMap)
+	if err != nil {
+		c.writeRPCError(w, rh, toRPCErr(err, customrpcmethods.ErrInternal))
+		return err
+	}
+
+	// the req is forwarded as a batch of one, so the response is usually a batch too
+	sc := newJSONScanner(body)
+	b, err := sc.next()
+	if err == nil && b == '[' {
+		b, err = sc.next()
+	}
+	if err != nil || b != '{' {
+		c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "invalid response format"))
+		return fmt.Errorf("failed to read response body: %w", errInvalidStreamRes)
+	}
+
+	res := &models.RPCResJSON{}
+	for first := true; ; first = false {
+		key, ok, err := sc.readKey(first)
+		if err != nil {
+			c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "invalid response format"))
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		// the result is replaced by the error of a failed getter as on the buffered responses
+		if key == "result" && gtError == nil {
+			return c.streamResult(w, rh, sc, id, prefix, suffix, block)
+		}
+		if key == "result" {
+			err = sc.copyValue(io.Discard)
+			if err != nil {
+				c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "invalid response format"))
+				return fmt.Errorf("failed to read response body: %w", err)
+			}
+			res.Error = gtError
+			continue
+		}
+
+		value := &limitedBuffer{maxSize: c.MaxResponseSize}
+		err = sc.copyValue(value)
+		if errors.Is(err, errBodyTooLarge) {
+			c.writeRPCError(w, rh, customrpcmethods.ErrResponseTooLarge)
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if err == nil {
+			err = setResField(res, key, value.Bytes())
+		}
+		if err != nil {
+			c.writeRPCError(w, rh, newRPCErr(customrpcmethods.ErrInternal, "invalid response format"))
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+	}
+
+	// responses without a result or with a failed getter are modified and returned as the buffered ones
+	rh.RPCRess = append([]*models.RPCResJSON{res}, rh.GetterRess...)
+
+	return nil
+}
+
+// setResField sets the field of the key of the response, unknown keys are ignored
+func setResField(res *models.RPCResJSON, key string, value []byte) error {
+	switch key {
+	case "jsonrpc":
+		return json.Unmarshal(value, &res.JSONRPC)
+	case "id":
+		res.ID = append(json.RawMessage(nil), value...)
+	case "error":
+		return json.Unmarshal(value, &res.Error)
+	}
+
+	return nil
+}
+
+// streamResult writes the response to the client with the result that is being read by the scanner
+// once the headers are written errors can't be returned to the client, so the body is left incomplete
+func (c *RPCContext) streamResult(w http.ResponseWriter, rh *reqHandler, sc *jsonScanner, id json.RawMessage, prefix, suffix []byte, block string) error {
+	for k, v := range rh.HTTPResponse.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	rh.Streamed = true
+
+	var out io.Writer = w
+	if rh.IsGzip {
+		gzw := gzip.NewWriter(w)
+		defer gzw.Close()
+		out = gzw
+	}
+
+	// the keys are written in a fixed order, jsonrpc, id, result and attestation
+	var hasher hash.Hash
+	resultOut := out
+	if c.UseAttestation {
+		hasher = sha256.New()
+		resultOut = io.MultiWriter(out, hasher)
+	}
+	if _, err := fmt.Fprintf(out, `{"jsonrpc":"2.0","id":%s,"result":`, id); err != nil {
+		return err
+	}
+
+	if _, err := resultOut.Write(prefix); err != nil {
+		return err
+	}
+	if err := sc.copyValue(resultOut); err != nil {
+		return fmt.Errorf("failed to stream response body: %w", err)
+	}
+	if _, err := resultOut.Write(suffix); err != nil {
+		return err
+	}
+
+	// the rest of the keys of the upstream response are already known
+	for {
+		_, ok, err := sc.readKey(false)
+		if err != nil {
+			return fmt.Errorf("failed to stream response body: %w", err)
+		}
+		if !ok {
+			break
+		}
+		if err := sc.copyValue(io.Discard); err != nil {
+			return fmt.Errorf("failed to stream response body: %w", err)
+		}
+	}
+
+	if !c.UseAttestation {
+		_, err := io.WriteString(out, "}")
+		return err
+	}
+
+	attested, err := c.attestDigest(rh, hasher.Sum(nil), id, block)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(attested)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, `,"attestation":%s}`, data)
+
+	return err
+}
+
+// attestDigest attests the digest of a streamed result with the attestation version of the context
+func (c *RPCContext) attestDigest(rh *reqHandler, digest []byte, id json.RawMessage, block string) (models.Attestation, error) {
+	if c.AttestationVersion != attestation.Version2 {
+		return attestation.AttestDigest(digest, c.Identity, c.SigningKey)
+	}
+
+	binding := rh.binding(&models.RPCResJSON{ID: id, Block: block})
+
+	return attestation.AttestDigestV2(digest, binding, c.Identity, c.SigningKey)
+}
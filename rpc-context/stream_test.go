@@ -0,0 +1,201 @@
+package rpccontext
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	customrpcmethods "github.com/stateless-solutions/compatibility-layer/custom-rpc-methods"
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+func TestJSONScannerCopyValue(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedValue string
+		expectedRest  string
+		expectErr     bool
+	}{
+		{
+			name:          "Object",
+			input:         ` {"a": [1, {"b": null}], "c": "d"},"id":1}`,
+			expectedValue: `{"a": [1, {"b": null}], "c": "d"}`,
+			expectedRest:  `,"id":1}`,
+		},
+		{
+			name:          "String With Delimiters",
+			input:         `"a\"}]\\",1]`,
+			expectedValue: `"a\"}]\\"`,
+			expectedRest:  `,1]`,
+		},
+		{
+			name:          "Number",
+			input:         `18446744073709551617}`,
+			expectedValue: `18446744073709551617`,
+			expectedRest:  `}`,
+		},
+		{
+			name:          "Literal Before Whitespace",
+			input:         "true\n}",
+			expectedValue: `true`,
+			expectedRest:  "\n}",
+		},
+		{
+			name:          "Value Longer Than The Buffer",
+			input:         `["` + strings.Repeat("a", 100) + `",{"b":"` + strings.Repeat("}", 100) + `"}]]`,
+			expectedValue: `["` + strings.Repeat("a", 100) + `",{"b":"` + strings.Repeat("}", 100) + `"}]`,
+			expectedRest:  `]`,
+		},
+		{
+			name:      "Missing Value",
+			input:     `,"id":1}`,
+			expectErr: true,
+		},
+		{
+			name:      "Truncated Value",
+			input:     `{"a":[1,2`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the smallest buffer makes the values span multiple chunks
+			sc := &jsonScanner{r: bufio.NewReaderSize(strings.NewReader(tt.input), 16)}
+
+			var value bytes.Buffer
+			err := sc.copyValue(&value)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Test case %s: Expected error %v, got %v", tt.name, tt.expectErr, err)
+			}
+			if tt.expectErr {
+				return
+			}
+
+			if value.String() != tt.expectedValue {
+				t.Errorf("Test case %s: Expected value %s, got %s", tt.name, tt.expectedValue, value.String())
+			}
+
+			var rest bytes.Buffer
+			rest.ReadFrom(sc.r)
+			if rest.String() != tt.expectedRest {
+				t.Errorf("Test case %s: Expected rest %s, got %s", tt.name, tt.expectedRest, rest.String())
+			}
+		})
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	_, err := readBody(strings.NewReader("12345"), 4)
+	if err != errBodyTooLarge {
+		t.Errorf("Expected error %v, got %v", errBodyTooLarge, err)
+	}
+
+	body, err := readBody(strings.NewReader("1234"), 4)
+	if err != nil || string(body) != "1234" {
+		t.Errorf("Expected body 1234, got %s %v", body, err)
+	}
+
+	body, err = readBody(strings.NewReader("12345"), 0)
+	if err != nil || string(body) != "12345" {
+		t.Errorf("Expected body without limit 12345, got %s %v", body, err)
+	}
+}
+
+func TestStreamRPCCall(t *testing.T) {
+	tests := []struct {
+		name           string
+		reqBody        string
+		getterBody     string
+		useAttestation bool
+		defaultMode    bool
+		expectedPrefix string
+		expectedBody   string
+	}{
+		{
+			name:         "Key Order",
+			reqBody:      `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`,
+			getterBody:   `{"number":"0x21"}`,
+			expectedBody: `{"jsonrpc":"2.0","id":1,"result":{"data":"0x1","blockNumber":"0x21"}}`,
+		},
+		{
+			name:           "Key Order With Attestation",
+			reqBody:        `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`,
+			getterBody:     `{"number":"0x21"}`,
+			useAttestation: true,
+			expectedPrefix: `{"jsonrpc":"2.0","id":1,"result":{"data":"0x1","blockNumber":"0x21"},"attestation":{`,
+		},
+		{
+			// the getter req is posted before the custom method that is streamed
+			name:         "Default Mode",
+			reqBody:      `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`,
+			getterBody:   `{"number":"0x21"}`,
+			defaultMode:  true,
+			expectedBody: `{"jsonrpc":"2.0","id":1,"result":{"data":"0x1","blockNumber":"0x21"}}`,
+		},
+		{
+			name:         "Getter Error",
+			reqBody:      `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`,
+			expectedBody: `{"jsonrpc":"2.0","error":{"code":-32000,"message":"header not found"},"id":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var reqs []*models.RPCReq
+				json.NewDecoder(r.Body).Decode(&reqs)
+				if len(reqs) != 1 {
+					t.Errorf("Test case %s: Expected reqs to be posted one by one, got %d", tt.name, len(reqs))
+				}
+
+				w.WriteHeader(http.StatusOK)
+				if reqs[0].Method != "eth_getBlockByNumber" {
+					w.Write([]byte(fmt.Sprintf(`[{"jsonrpc":"2.0","result":"0x1","id":%s}]`, reqs[0].ID)))
+					return
+				}
+				if tt.getterBody == "" {
+					w.Write([]byte(fmt.Sprintf(`[{"jsonrpc":"2.0","error":{"code":-32000,"message":"header not found"},"id":%s}]`, reqs[0].ID)))
+					return
+				}
+				w.Write([]byte(fmt.Sprintf(`[{"jsonrpc":"2.0","result":%s,"id":%s}]`, tt.getterBody, reqs[0].ID)))
+			}))
+			defer mockServer.Close()
+
+			req, err := http.NewRequest("POST", "/", bytes.NewBufferString(tt.reqBody))
+			if err != nil {
+				t.Fatalf("Test case %s: Error creating mock request: %v", tt.name, err)
+			}
+			rec := httptest.NewRecorder()
+
+			context := &RPCContext{
+				DefaultChainURL:    mockServer.URL,
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+				PinnedMode:         !tt.defaultMode,
+				StreamResponses:    true,
+				Logger:             slog.Default(),
+			}
+			if tt.useAttestation {
+				context.EnableAttestation("test-data/.mock_key.pem", "", "mock_identity")
+			}
+			http.HandlerFunc(context.Handler).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Test case %s: Expected status code %d, got %d", tt.name, http.StatusOK, rec.Code)
+			}
+			if tt.expectedPrefix != "" && !strings.HasPrefix(rec.Body.String(), tt.expectedPrefix) {
+				t.Errorf("Test case %s: Expected body starting with %s, got %s", tt.name, tt.expectedPrefix, rec.Body)
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedBody, rec.Body)
+			}
+		})
+	}
+}
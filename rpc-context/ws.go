@@ -0,0 +1,402 @@
+package rpccontext
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/stateless-solutions/compatibility-layer/attestation"
+	customrpcmethods "github.com/stateless-solutions/compatibility-layer/custom-rpc-methods"
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+// wsSession holds the state of a proxied websocket connection
+type wsSession struct {
+	ch            *customrpcmethods.CustomMethodHolder
+	chain         string // chain identifier bound to the attestations
+	nonce         string // nonce of the client bound to the attestations, sent when the connection was opened
+	clientConn    *websocket.Conn
+	chainConn     *websocket.Conn
+	clientMu      sync.Mutex
+	mu            sync.Mutex
+	pending       map[string]*wsPending                 // IDs of the reqs sent to the chain to the frame they belong to
+	lastID        uint64                                // last ID sent to the chain
+	subscriptions map[string]customrpcmethods.ChainType // subscriptions made with custom methods to their chain type
+}
+
+// wsPending is a frame sent to the chain, its reqs are sent with IDs that are unique on the session
+// since clients can reuse the IDs of frames that are still pending
+type wsPending struct {
+	rh  *reqHandler
+	ids map[string]json.RawMessage // IDs sent to the chain to the IDs of the reqs
+}
+
+func (s *wsSession) writeClient(data []byte) error {
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+
+	return s.clientConn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (s *wsSession) writeClientJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return s.writeClient(data)
+}
+
+// addPending returns the reqs of the frame with the IDs that are sent to the chain, the IDs of the responses are restored by popPending
+func (s *wsSession) addPending(rh *reqHandler) []*models.RPCReq {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := &wsPending{rh: rh, ids: make(map[string]json.RawMessage, len(rh.RPCReqs))}
+	reqs := make([]*models.RPCReq, len(rh.RPCReqs))
+	for i, req := range rh.RPCReqs {
+		s.lastID++
+		sent := *req
+		sent.ID = json.RawMessage(strconv.FormatUint(s.lastID, 10))
+		p.ids[string(sent.ID)] = req.ID
+		s.pending[string(sent.ID)] = p
+		reqs[i] = &sent
+	}
+
+	return reqs
+}
+
+// popPending returns the req handler of the frame of the responses, the IDs of the responses are restored to the ones of the reqs
+func (s *wsSession) popPending(ress []*models.RPCResJSON) *reqHandler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, res := range ress {
+		p, ok := s.pending[string(res.ID)]
+		if !ok {
+			continue
+		}
+		for id := range p.ids {
+			delete(s.pending, id)
+		}
+		for _, res := range ress {
+			id, ok := p.ids[string(res.ID)]
+			if ok {
+				res.ID = id
+			}
+		}
+		return p.rh
+	}
+
+	return nil
+}
+
+// updateSubscriptions adds the subscriptions made with custom methods and removes the ones that were unsubscribed
+func (s *wsSession) updateSubscriptions(ch *customrpcmethods.CustomMethodHolder, rh *reqHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reqs := make(map[string]*models.RPCReq, len(rh.RPCReqs))
+	for _, req := range rh.RPCReqs {
+		reqs[string(req.ID)] = req
+	}
+
+	for _, res := range rh.RPCRess {
+		req, ok := reqs[string(res.ID)]
+		if ok && isUnsubscribe(req.Method) && string(res.Result) == "true" {
+			var params []json.RawMessage
+			err := json.Unmarshal(req.Params, &params)
+			if err == nil && len(params) > 0 {
+				delete(s.subscriptions, string(params[0]))
+			}
+			continue
+		}
+
+		customMethod, ok := rh.ChangedMethods[string(res.ID)]
+		if !ok || !ch.SubscriptionMethods[customMethod] || !res.HasResult() {
+			continue
+		}
+
+		s.subscriptions[string(res.Result)] = ch.CustomMethodToChainType[customMethod]
+	}
+}
+
+// isUnsubscribe returns true for the unsubscribe methods of the chains, e.g. eth_unsubscribe or accountUnsubscribe
+func isUnsubscribe(method string) bool {
+	return strings.HasSuffix(strings.ToLower(method), "unsubscribe")
+}
+
+func (s *wsSession) getSubscription(subscription json.RawMessage) (customrpcmethods.ChainType, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chainType, ok := s.subscriptions[string(subscription)]
+	return chainType, ok
+}
+
+// checkOrigin returns true if the origin of the websocket client is allowed, any origin is allowed
+// if no allowed origins are set since dapps connect from any origin, reqs without an origin are not made by browsers
+func (c *RPCContext) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(c.WSAllowedOrigins) == 0 || origin == "" {
+		return true
+	}
+
+	for _, allowed := range c.WSAllowedOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *RPCContext) getChainWSURL(r *http.Request, route *ChainRoute) (string, error) {
+	chainWSURL := route.ChainWSURL
+
+	headerChainWSURL := r.Header.Get("Stateless-Chain-WS-URL")
+	if headerChainWSURL != "" {
+		// Validate the URL
+		_, err := url.ParseRequestURI(headerChainWSURL)
+		if err != nil {
+			return "", errors.New("invalid chain WS URL")
+		}
+		chainWSURL = headerChainWSURL
+	}
+
+	if chainWSURL == "" {
+		return "", errors.New("chain WS URL is not set")
+	}
+
+	return chainWSURL, nil
+}
+
+// handleClientMessage modifies the frame sent by the client and forwards it to the chain
+func (c *RPCContext) handleClientMessage(s *wsSession, msg []byte) error {
+	rh := &reqHandler{
+		CustomMethodHolder: s.ch,
+		Chain:              s.chain,
+		Nonce:              s.nonce,
+	}
+
+	err := rh.parseBody(msg)
+	if err != nil {
+		return c.writeClientRPCError(s, rh, toRPCErr(err, customrpcmethods.ErrParseErr))
+	}
+	if len(rh.RPCReqs) == 0 {
+		return c.writeClientRPCError(s, rh, customrpcmethods.ErrInvalidRequest)
+	}
+
+	// frames don't use the response cache, so no context is needed to reach its store
+	err = c.modifyRPCReqs(context.Background(), rh)
+	if err != nil {
+		return c.writeClientRPCError(s, rh, toRPCErr(err, customrpcmethods.ErrInvalidRequest))
+	}
+
+	reqs := s.addPending(rh)
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal modified request: %w", err)
+	}
+
+	return s.chainConn.WriteMessage(websocket.TextMessage, body)
+}
+
+// handleChainMessage modifies the frame sent by the chain and forwards it to the client
+func (c *RPCContext) handleChainMessage(s *wsSession, msg []byte) error {
+	var notification models.RPCNotification
+	err := json.Unmarshal(msg, &notification)
+	if err == nil && notification.Method != "" {
+		return c.handleNotification(s, msg, &notification)
+	}
+
+	var ress []*models.RPCResJSON
+	if err := json.Unmarshal(msg, &ress); err != nil {
+		var res *models.RPCResJSON
+		if err := json.Unmarshal(msg, &res); err != nil {
+			return s.writeClient(msg) // unknown frames are forwarded as they are
+		}
+		ress = []*models.RPCResJSON{res}
+	}
+
+	rh := s.popPending(ress)
+	if rh == nil {
+		return s.writeClient(msg)
+	}
+
+	rh.RPCRess = ress
+	s.updateSubscriptions(s.ch, rh)
+
+	err = c.modifyRPCRess(context.Background(), rh)
+	if err != nil {
+		return c.writeClientRPCError(s, rh, toRPCErr(err, customrpcmethods.ErrInternal))
+	}
+
+	body, err := c.marshalBody(rh)
+	if err != nil {
+		return fmt.Errorf("failed to marshal modified response: %w", err)
+	}
+
+	return s.writeClient(body)
+}
+
+// writeClientRPCError writes the error as a JSON-RPC response for each of the reqs of the frame
+func (c *RPCContext) writeClientRPCError(s *wsSession, rh *reqHandler, rpcErr *models.RPCErr) error {
+	body, err := c.marshalRPCError(rh, rpcErr)
+	if err != nil {
+		return fmt.Errorf("failed to marshal error response: %w", err)
+	}
+
+	return s.writeClient(body)
+}
+
+func (c *RPCContext) handleNotification(s *wsSession, msg []byte, notification *models.RPCNotification) error {
+	chainType, ok := s.getSubscription(notification.Params.Subscription)
+	if !ok {
+		return s.writeClient(msg) // subscriptions made with regular methods are not modified
+	}
+
+	err := s.ch.ChangeSubscriptionNotification(chainType, notification)
+	if err != nil {
+		return err
+	}
+
+	if c.UseAttestation {
+		attested, err := c.attestNotification(s, notification)
+		if err != nil {
+			return err
+		}
+		return s.writeClientJSON(attested)
+	}
+
+	return s.writeClientJSON(notification)
+}
+
+// attestNotification attests the notification with the attestation version of the context
+// version 2 attestations of notifications are bound to the subscription id as the params
+func (c *RPCContext) attestNotification(s *wsSession, notification *models.RPCNotification) (*models.RPCNotificationAttested, error) {
+	if c.AttestationVersion != attestation.Version2 {
+		return attestation.AttestNotification(notification, c.Identity, c.SigningKey)
+	}
+
+	binding := attestation.Binding{
+		Chain:  s.chain,
+		Method: notification.Method,
+		Params: notification.Params.Subscription,
+		Block:  notification.Block,
+		Nonce:  s.nonce,
+	}
+
+	return attestation.AttestNotificationV2(notification, binding, c.Identity, c.SigningKey)
+}
+
+// WSHandler proxies a websocket connection to the chain websocket URL
+// applying the same custom methods and attestations as the http handler
+func (c *RPCContext) WSHandler(w http.ResponseWriter, r *http.Request) {
+	c.handleWS(w, r, c.defaultRoute())
+}
+
+// ChainWSHandler proxies a websocket connection to the websocket URL of the chain name of the path
+func (c *RPCContext) ChainWSHandler(w http.ResponseWriter, r *http.Request) {
+	route, ok := c.getChainRoute(w, r)
+	if !ok {
+		return
+	}
+
+	c.handleWS(w, r, route)
+}
+
+func (c *RPCContext) handleWS(w http.ResponseWriter, r *http.Request, route *ChainRoute) {
+	if !c.checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		c.Logger.Error("Create websocket handler failed", slog.String("error", "origin not allowed"), slog.String("origin", r.Header.Get("Origin")))
+		return
+	}
+
+	chainWSURL, err := c.getChainWSURL(r, route)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		c.Logger.Error("Create websocket handler failed", slog.String("error", err.Error()))
+		return
+	}
+
+	nonce, err := nonceOf(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		c.Logger.Error("Create websocket handler failed", slog.String("error", err.Error()))
+		return
+	}
+
+	chain := c.chainOf(r)
+	if r.Header.Get("Stateless-Chain-WS-URL") != "" {
+		chain = chainURLHost(chainWSURL)
+	}
+
+	chainConn, _, err := websocket.DefaultDialer.Dial(chainWSURL, nil)
+	if err != nil {
+		http.Error(w, "Failed to connect to chain", http.StatusBadGateway)
+		c.Logger.Error("Dial chain websocket failed", slog.String("error", err.Error()))
+		return
+	}
+	defer chainConn.Close()
+
+	upgrader := websocket.Upgrader{CheckOrigin: c.checkOrigin}
+	clientConn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		c.Logger.Error("Upgrade websocket failed", slog.String("error", err.Error()))
+		return
+	}
+	defer clientConn.Close()
+	if c.MaxRequestBodySize > 0 {
+		clientConn.SetReadLimit(c.MaxRequestBodySize) // frames over the limit close the connection
+	}
+
+	s := &wsSession{
+		ch:            route.CustomMethodHolder,
+		chain:         chain,
+		nonce:         nonce,
+		clientConn:    clientConn,
+		chainConn:     chainConn,
+		pending:       map[string]*wsPending{},
+		subscriptions: map[string]customrpcmethods.ChainType{},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, msg, err := chainConn.ReadMessage()
+			if err != nil {
+				clientConn.Close()
+				return
+			}
+			err = c.handleChainMessage(s, msg)
+			if err != nil {
+				c.Logger.Error("Handle chain websocket message failed", slog.String("error", err.Error()))
+			}
+		}
+	}()
+
+	for {
+		_, msg, err := clientConn.ReadMessage()
+		if err != nil {
+			chainConn.Close()
+			break
+		}
+		err = c.handleClientMessage(s, msg)
+		if err != nil {
+			c.Logger.Error("Handle client websocket message failed", slog.String("error", err.Error()))
+		}
+	}
+
+	<-done
+	c.Logger.Info("Websocket connection closed")
+}
@@ -0,0 +1,376 @@
+package rpccontext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	customrpcmethods "github.com/stateless-solutions/compatibility-layer/custom-rpc-methods"
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+func mockChainWSHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Error upgrading mock chain connection: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var reqs []*models.RPCReq
+			err := conn.ReadJSON(&reqs)
+			if err != nil {
+				return
+			}
+
+			var ress []string
+			var subscribed bool
+			for _, req := range reqs {
+				switch req.Method {
+				case "eth_subscribe":
+					ress = append(ress, fmt.Sprintf(`{"jsonrpc":"2.0","result":"0xcd0c3e8af590364c09d0fa6a1210faf5","id":%s}`, req.ID))
+					subscribed = true
+				case "eth_getBlockByNumber":
+					ress = append(ress, fmt.Sprintf(`{"jsonrpc":"2.0","result":{"number":"0x21"},"id":%s}`, req.ID))
+				default:
+					ress = append(ress, fmt.Sprintf(`{"jsonrpc":"2.0","result":"0x1","id":%s}`, req.ID))
+				}
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte("["+strings.Join(ress, ",")+"]"))
+
+			if subscribed {
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xcd0c3e8af590364c09d0fa6a1210faf5","result":{"number":"0x22","hash":"0x1"}}}`))
+			}
+		}
+	}
+}
+
+func TestWSHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		reqBody        string
+		expectedFrames []string
+	}{
+		{
+			name:    "Regular method",
+			reqBody: `{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`,
+			expectedFrames: []string{
+				`{"jsonrpc":"2.0","result":"0x1","id":1}`,
+			},
+		},
+		{
+			name:    "Custom method",
+			reqBody: `{"jsonrpc":"2.0","method":"eth_getBalanceAndBlockNumber","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`,
+			expectedFrames: []string{
+				`{"jsonrpc":"2.0","result":{"data":"0x1","blockNumber":"0x21"},"id":1}`,
+			},
+		},
+		{
+			name:    "Custom subscription",
+			reqBody: `{"jsonrpc":"2.0","method":"eth_subscribeAndBlockNumber","id":1,"params":["newHeads"]}`,
+			expectedFrames: []string{
+				`{"jsonrpc":"2.0","result":{"data":"0xcd0c3e8af590364c09d0fa6a1210faf5","blockNumber":"0x21"},"id":1}`,
+				`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xcd0c3e8af590364c09d0fa6a1210faf5","result":{"data":{"number":"0x22","hash":"0x1"},"blockNumber":"0x22"}}}`,
+			},
+		},
+		{
+			name:    "Regular subscription",
+			reqBody: `{"jsonrpc":"2.0","method":"eth_subscribe","id":1,"params":["newHeads"]}`,
+			expectedFrames: []string{
+				`{"jsonrpc":"2.0","result":"0xcd0c3e8af590364c09d0fa6a1210faf5","id":1}`,
+				`{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xcd0c3e8af590364c09d0fa6a1210faf5","result":{"number":"0x22","hash":"0x1"}}}`,
+			},
+		},
+		{
+			name:    "Invalid request",
+			reqBody: `{"jsonrpc": 1}`,
+			expectedFrames: []string{
+				`{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error"},"id":null}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockChain := httptest.NewServer(mockChainWSHandler(t))
+			defer mockChain.Close()
+
+			context := &RPCContext{
+				DefaultChainWSURL:  "ws" + strings.TrimPrefix(mockChain.URL, "http"),
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+				Logger:             slog.Default(),
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(context.WSHandler))
+			defer server.Close()
+
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+			if err != nil {
+				t.Fatalf("Error dialing websocket: %v", err)
+			}
+			defer conn.Close()
+
+			err = conn.WriteMessage(websocket.TextMessage, []byte(tt.reqBody))
+			if err != nil {
+				t.Fatalf("Error writing websocket message: %v", err)
+			}
+
+			for _, expectedFrame := range tt.expectedFrames {
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				_, msg, err := conn.ReadMessage()
+				if err != nil {
+					t.Fatalf("Error reading websocket message: %v", err)
+				}
+
+				if string(msg) != expectedFrame {
+					t.Errorf("Test case %s: Expected frame %s, got %s", tt.name, expectedFrame, msg)
+				}
+			}
+		})
+	}
+}
+
+func TestWSHandlerWithAttestation(t *testing.T) {
+	mockChain := httptest.NewServer(mockChainWSHandler(t))
+	defer mockChain.Close()
+
+	context := &RPCContext{
+		DefaultChainWSURL:  "ws" + strings.TrimPrefix(mockChain.URL, "http"),
+		CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+		Logger:             slog.Default(),
+	}
+	context.EnableAttestation("test-data/.mock_key.pem", "", "mock_identity")
+
+	server := httptest.NewServer(http.HandlerFunc(context.WSHandler))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Error dialing websocket: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"eth_subscribeAndBlockNumber","id":1,"params":["newHeads"]}`))
+	if err != nil {
+		t.Fatalf("Error writing websocket message: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var res models.RPCResJSONAttested
+	if err := conn.ReadJSON(&res); err != nil {
+		t.Fatalf("Error reading websocket message: %v", err)
+	}
+	if res.Attestation == nil || res.Attestation.Signature == "" {
+		t.Errorf("Expected attested response, got %+v", res)
+	}
+
+	var notification models.RPCNotificationAttested
+	if err := conn.ReadJSON(&notification); err != nil {
+		t.Fatalf("Error reading websocket message: %v", err)
+	}
+	if notification.Attestation == nil || notification.Attestation.Signature == "" {
+		t.Errorf("Expected attested notification, got %+v", notification)
+	}
+
+	expectedResult := `{"data":{"number":"0x22","hash":"0x1"},"blockNumber":"0x22"}`
+	if string(notification.Params.Result) != expectedResult {
+		t.Errorf("Expected notification result %s, got %s", expectedResult, notification.Params.Result)
+	}
+}
+
+func TestWSHandlerAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowedOrigins []string
+		origin         string
+		expectedStatus int
+	}{
+		{
+			name:           "Any origin",
+			origin:         "https://app.example.com",
+			expectedStatus: http.StatusSwitchingProtocols,
+		},
+		{
+			name:           "Allowed origin",
+			allowedOrigins: []string{"https://other.example.com", "https://app.example.com"},
+			origin:         "https://app.example.com",
+			expectedStatus: http.StatusSwitchingProtocols,
+		},
+		{
+			name:           "Not allowed origin",
+			allowedOrigins: []string{"https://app.example.com"},
+			origin:         "https://evil.example.com",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "No origin",
+			allowedOrigins: []string{"https://app.example.com"},
+			expectedStatus: http.StatusSwitchingProtocols,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockChain := httptest.NewServer(mockChainWSHandler(t))
+			defer mockChain.Close()
+
+			context := &RPCContext{
+				DefaultChainWSURL:  "ws" + strings.TrimPrefix(mockChain.URL, "http"),
+				CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+				WSAllowedOrigins:   tt.allowedOrigins,
+				Logger:             slog.Default(),
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(context.WSHandler))
+			defer server.Close()
+
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			conn, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+			if err == nil {
+				conn.Close()
+			}
+			if res == nil || res.StatusCode != tt.expectedStatus {
+				t.Errorf("Test case %s: Expected status %d, got %v (%v)", tt.name, tt.expectedStatus, res, err)
+			}
+		})
+	}
+}
+
+func TestWSSessionSubscriptions(t *testing.T) {
+	ch := customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json")
+	s := &wsSession{subscriptions: map[string]customrpcmethods.ChainType{}}
+
+	subscription := `"0xcd0c3e8af590364c09d0fa6a1210faf5"`
+	s.updateSubscriptions(ch, &reqHandler{
+		RPCReqs:        []*models.RPCReq{{Method: "eth_subscribe", ID: json.RawMessage("1"), Params: json.RawMessage(`["newHeads"]`)}},
+		RPCRess:        []*models.RPCResJSON{{ID: json.RawMessage("1"), Result: json.RawMessage(subscription)}},
+		ChangedMethods: map[string]string{"1": "eth_subscribeAndBlockNumber"},
+	})
+
+	tests := []struct {
+		name                 string
+		result               string
+		expectedSubscription bool
+	}{
+		{
+			name:                 "Failed unsubscribe",
+			result:               "false",
+			expectedSubscription: true,
+		},
+		{
+			name:                 "Unsubscribe",
+			result:               "true",
+			expectedSubscription: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.updateSubscriptions(ch, &reqHandler{
+				RPCReqs: []*models.RPCReq{{Method: "eth_unsubscribe", ID: json.RawMessage("2"), Params: json.RawMessage("[" + subscription + "]")}},
+				RPCRess: []*models.RPCResJSON{{ID: json.RawMessage("2"), Result: json.RawMessage(tt.result)}},
+			})
+
+			_, ok := s.getSubscription(json.RawMessage(subscription))
+			if ok != tt.expectedSubscription {
+				t.Errorf("Test case %s: Expected subscription %v, got %v", tt.name, tt.expectedSubscription, ok)
+			}
+		})
+	}
+}
+
+func TestWSSessionPending(t *testing.T) {
+	s := &wsSession{pending: map[string]*wsPending{}}
+
+	// both frames use the same ID while the first one is pending
+	first := &reqHandler{RPCReqs: []*models.RPCReq{{Method: "eth_getBalance", ID: json.RawMessage("1")}}}
+	second := &reqHandler{RPCReqs: []*models.RPCReq{{Method: "eth_blockNumber", ID: json.RawMessage("1")}}}
+	firstSent := s.addPending(first)
+	secondSent := s.addPending(second)
+
+	if string(firstSent[0].ID) == string(secondSent[0].ID) {
+		t.Fatalf("Expected different IDs sent to the chain, got %s", firstSent[0].ID)
+	}
+	if string(first.RPCReqs[0].ID) != "1" {
+		t.Errorf("Expected the ID of the req to be kept, got %s", first.RPCReqs[0].ID)
+	}
+
+	tests := []struct {
+		name       string
+		sentID     json.RawMessage
+		expectedRh *reqHandler
+	}{
+		{
+			name:       "Second frame",
+			sentID:     secondSent[0].ID,
+			expectedRh: second,
+		},
+		{
+			name:       "First frame",
+			sentID:     firstSent[0].ID,
+			expectedRh: first,
+		},
+		{
+			name:   "Popped frame",
+			sentID: firstSent[0].ID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &models.RPCResJSON{ID: tt.sentID, Result: json.RawMessage(`"0x1"`)}
+			rh := s.popPending([]*models.RPCResJSON{res})
+			if rh != tt.expectedRh {
+				t.Fatalf("Test case %s: Expected req handler %v, got %v", tt.name, tt.expectedRh, rh)
+			}
+			if rh != nil && string(res.ID) != "1" {
+				t.Errorf("Test case %s: Expected the ID of the response to be restored, got %s", tt.name, res.ID)
+			}
+		})
+	}
+}
+
+func TestWSHandlerReadLimit(t *testing.T) {
+	mockChain := httptest.NewServer(mockChainWSHandler(t))
+	defer mockChain.Close()
+
+	context := &RPCContext{
+		DefaultChainWSURL:  "ws" + strings.TrimPrefix(mockChain.URL, "http"),
+		CustomMethodHolder: customrpcmethods.NewCustomMethodHolder(false, "../supported-chains/ethereum.json"),
+		MaxRequestBodySize: 64,
+		Logger:             slog.Default(),
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(context.WSHandler))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Error dialing websocket: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","method":"eth_getBalance","id":1,"params":["0x20f33ce90a13a4b5e7697e3544c3083b8f8a51d4","latest"]}`))
+	if err != nil {
+		t.Fatalf("Error writing websocket message: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseMessageTooBig {
+		t.Errorf("Expected the connection to be closed with code %d, got %v", websocket.CloseMessageTooBig, err)
+	}
+}
@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/stateless-solutions/compatibility-layer"
+
+// Tracer returns the tracer of the compatibility layer
+// it uses the global tracer provider so spans are not recorded until Setup is called
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// NewOTLPExporter returns an OTLP http exporter configured by the standard OTEL_EXPORTER_OTLP_* env vars
+func NewOTLPExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	return otlptracehttp.New(ctx)
+}
+
+// Setup registers a global tracer provider that sends the spans to the exporter and the W3C trace context propagator
+// the returned provider must be shut down to flush the remaining spans
+func Setup(exporter sdktrace.SpanExporter, serviceName string) *sdktrace.TracerProvider {
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return tp
+}
+
+// EndSpan records the error on the span if there is one and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
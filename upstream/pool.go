@@ -0,0 +1,512 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/metrics"
+	"github.com/stateless-solutions/compatibility-layer/models"
+	"github.com/stateless-solutions/compatibility-layer/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Policy string
+
+const (
+	PolicyRoundRobin    Policy = "round-robin"
+	PolicyLowestLatency Policy = "lowest-latency"
+	PolicyHighestBlock  Policy = "highest-block"
+
+	latencyWeight = 0.3 // weight of the last latency on the moving average
+
+	DefaultTimeout            = 30 * time.Second
+	defaultHealthCheckTimeout = 5 * time.Second
+)
+
+var (
+	validPolicies = map[Policy]bool{
+		PolicyRoundRobin:    true,
+		PolicyLowestLatency: true,
+		PolicyHighestBlock:  true,
+	}
+
+	errNoUpstreams = errors.New("no upstreams configured")
+)
+
+// HealthChecker builds the rpc req used to check the health of the upstreams and extracts their height from the response
+type HealthChecker interface {
+	GetHealthCheckReq() *models.RPCReq
+	ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error)
+}
+
+// ContextChecker is implemented by the health checkers of the chain types with node data added to the results
+// the data is fetched once per upstream with the health checks instead of on every request
+type ContextChecker interface {
+	// BuildContextReq returns the req of the node data, nil if the chain type has none
+	BuildContextReq(id string) *models.RPCReq
+}
+
+// Upstream is a single node of the pool
+type Upstream struct {
+	URL     string
+	mu      sync.RWMutex
+	healthy bool
+	latency time.Duration // exponential moving average of the request latencies
+	height  uint64
+	context *models.RPCResJSON
+}
+
+func (u *Upstream) IsHealthy() bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return u.healthy
+}
+
+func (u *Upstream) Latency() time.Duration {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return u.latency
+}
+
+func (u *Upstream) Height() uint64 {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return u.height
+}
+
+// Context returns the response of the context req of the upstream, nil if it is not known yet
+func (u *Upstream) Context() *models.RPCResJSON {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return u.context
+}
+
+func (u *Upstream) setContext(context *models.RPCResJSON) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.context = context
+}
+
+func (u *Upstream) markSuccess(latency time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.healthy = true
+	if u.latency == 0 {
+		u.latency = latency
+	} else {
+		u.latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(u.latency))
+	}
+}
+
+func (u *Upstream) markFailure() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.healthy = false
+}
+
+func (u *Upstream) setHeight(height uint64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.height = height
+}
+
+// Pool holds the upstreams of a chain and picks the one to use on each request based on the policy
+type Pool struct {
+	upstreams     []*Upstream
+	policy        Policy
+	healthChecker HealthChecker
+	client        *http.Client
+	next          uint64
+	logger        *slog.Logger
+	// timeout is the max duration of each attempt of a request including the read of its body, 0 disables it
+	timeout            time.Duration
+	healthCheckTimeout time.Duration
+	contextReq         *models.RPCReq // set when the health checks are started
+}
+
+// NewPool returns a pool of the urls, the health checker is optional and without it no active health checks are done
+func NewPool(urls []string, policy Policy, healthChecker HealthChecker, logger *slog.Logger) (*Pool, error) {
+	if len(urls) == 0 {
+		return nil, errNoUpstreams
+	}
+	if policy == "" {
+		policy = PolicyRoundRobin
+	}
+	if !validPolicies[policy] {
+		return nil, fmt.Errorf("invalid upstream policy: %s", policy)
+	}
+	if policy == PolicyHighestBlock && healthChecker == nil {
+		return nil, fmt.Errorf("upstream policy %s needs health checks", policy)
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	p := &Pool{
+		policy:        policy,
+		healthChecker: healthChecker,
+		client:        &http.Client{},
+		logger:        logger,
+
+		timeout:            DefaultTimeout,
+		healthCheckTimeout: defaultHealthCheckTimeout,
+	}
+	for _, url := range urls {
+		p.upstreams = append(p.upstreams, &Upstream{
+			URL:     url,
+			healthy: true, // upstreams are assumed healthy until a request or health check fails
+		})
+	}
+
+	return p, nil
+}
+
+// SetTransport sets the transport of the requests to the upstreams, e.g. to translate them to another API
+// it must be set before the pool is used
+func (p *Pool) SetTransport(transport http.RoundTripper) {
+	p.client.Transport = transport
+}
+
+// SetTimeout sets the max duration of each attempt of a request to an upstream, including the read of its body
+// a hung upstream is failed over after the timeout, 0 disables it. It must be set before the pool is used
+func (p *Pool) SetTimeout(timeout time.Duration) {
+	p.timeout = timeout
+}
+
+// Transport returns the transport of the requests to the upstreams, nil is the default transport
+func (p *Pool) Transport() http.RoundTripper {
+	return p.client.Transport
+}
+
+// ContextReq returns the context req of the upstreams, nil if the pool doesn't fetch their context with the health checks
+func (p *Pool) ContextReq() *models.RPCReq {
+	return p.contextReq
+}
+
+func (p *Pool) Upstreams() []*Upstream {
+	return p.upstreams
+}
+
+// Select returns the upstreams in the order they should be tried
+// healthy upstreams are ordered by the policy and unhealthy ones are kept at the end as last resort
+func (p *Pool) Select() []*Upstream {
+	var healthy, unhealthy []*Upstream
+	for _, u := range p.upstreams {
+		if u.IsHealthy() {
+			healthy = append(healthy, u)
+		} else {
+			unhealthy = append(unhealthy, u)
+		}
+	}
+
+	if len(healthy) > 0 {
+		start := int(atomic.AddUint64(&p.next, 1)-1) % len(healthy)
+		rotated := make([]*Upstream, 0, len(healthy))
+		rotated = append(rotated, healthy[start:]...)
+		healthy = append(rotated, healthy[:start]...) // rotation also breaks ties on the other policies
+	}
+
+	switch p.policy {
+	case PolicyLowestLatency:
+		sort.SliceStable(healthy, func(i, j int) bool {
+			return healthy[i].Latency() < healthy[j].Latency()
+		})
+	case PolicyHighestBlock:
+		sort.SliceStable(healthy, func(i, j int) bool {
+			return healthy[i].Height() > healthy[j].Height()
+		})
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+// Do sends the request built by newReq to the upstreams failing over to the next one on transport errors and 5xx responses
+// the preferred upstream is tried first, if all upstreams fail the last 5xx response is returned
+func (p *Pool) Do(preferred *Upstream, newReq func(url string) (*http.Request, error)) (*http.Response, *Upstream, error) {
+	upstreams := p.Select()
+	if preferred != nil {
+		ordered := []*Upstream{preferred}
+		for _, u := range upstreams {
+			if u != preferred {
+				ordered = append(ordered, u)
+			}
+		}
+		upstreams = ordered
+	}
+
+	var lastResp *http.Response
+	var lastUpstream *Upstream
+	var lastErr error
+	for _, u := range upstreams {
+		req, err := newReq(u.URL)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		start := time.Now()
+		resp, err := p.doAttempt(req)
+		if err != nil {
+			metrics.ObserveUpstreamResponse(u.URL, "error", time.Since(start))
+			u.markFailure()
+			p.logger.Warn("Upstream request failed", slog.String("upstream", u.URL), slog.String("error", err.Error()))
+			lastErr = err
+			continue
+		}
+
+		metrics.ObserveUpstreamResponse(u.URL, strconv.Itoa(resp.StatusCode), time.Since(start))
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			u.markFailure()
+			p.logger.Warn("Upstream request failed", slog.String("upstream", u.URL), slog.String("status", resp.Status))
+			if lastResp != nil {
+				lastResp.Body.Close()
+			}
+			lastResp = resp
+			lastUpstream = u
+			continue
+		}
+
+		if lastResp != nil {
+			lastResp.Body.Close()
+		}
+		u.markSuccess(time.Since(start))
+
+		return resp, u, nil
+	}
+
+	if lastResp != nil {
+		return lastResp, lastUpstream, nil
+	}
+
+	return nil, nil, fmt.Errorf("all upstreams failed: %w", lastErr)
+}
+
+// cancelOnCloseBody cancels the context of the attempt of the response when its body is closed
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// doAttempt sends the request with the timeout of the pool, the deadline also applies to the read of the body
+// so the context of the attempt is only released when the body is closed
+func (p *Pool) doAttempt(req *http.Request) (*http.Response, error) {
+	if p.timeout <= 0 {
+		return p.doTraced(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), p.timeout)
+	resp, err := p.doTraced(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+// doTraced sends the request on its own client span, each failover attempt is a different span
+// the W3C trace context of the span is injected on the request headers so the upstream can continue the trace
+func (p *Pool) doTraced(req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.Tracer().Start(req.Context(), "upstream",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("server.address", req.URL.Host)), // only the host to not leak keys of the path
+	)
+
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		tracing.EndSpan(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	var spanErr error
+	if resp.StatusCode >= http.StatusInternalServerError {
+		spanErr = fmt.Errorf("upstream response was %s", resp.Status)
+	}
+	tracing.EndSpan(span, spanErr)
+
+	return resp, nil
+}
+
+// Call sends the rpc req to the upstreams of the pool and returns its response
+func (p *Pool) Call(ctx context.Context, rpcReq *models.RPCReq) (*models.RPCResJSON, error) {
+	body, err := json.Marshal(rpcReq)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, _, err := p.Do(nil, func(url string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream response was %s", resp.Status)
+	}
+
+	var res models.RPCResJSON
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// post sends the rpc req to the upstream without failing over and returns its response
+func (p *Pool) post(ctx context.Context, u *Upstream, rpcReq *models.RPCReq) (*models.RPCResJSON, error) {
+	body, err := json.Marshal(rpcReq)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", u.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream response was %s", resp.Status)
+	}
+
+	var res models.RPCResJSON
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return &res, nil
+}
+
+func (p *Pool) checkHealth(ctx context.Context, u *Upstream) error {
+	// each probe has its own timeout so a hung upstream doesn't block the next health checks
+	ctx, cancel := context.WithTimeout(ctx, p.healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	res, err := p.post(ctx, u, p.healthChecker.GetHealthCheckReq())
+	if err != nil {
+		return err
+	}
+
+	height, err := p.healthChecker.ExtractHeightFromHealthCheck(res)
+	if err != nil {
+		return err
+	}
+
+	u.markSuccess(time.Since(start))
+	u.setHeight(height)
+
+	// the context is fetched again after the upstream fails a health check, e.g. if it was restarted on a new version
+	if p.contextReq != nil && u.Context() == nil {
+		contextRes, err := p.post(ctx, u, p.contextReq)
+		if err != nil {
+			p.logger.Warn("Upstream context request failed", slog.String("upstream", u.URL), slog.String("error", err.Error()))
+			return nil
+		}
+		u.setContext(contextRes)
+	}
+
+	return nil
+}
+
+// CheckHealth runs the health check on all upstreams of the pool
+func (p *Pool) CheckHealth(ctx context.Context) {
+	if p.healthChecker == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, u := range p.upstreams {
+		wg.Add(1)
+		go func(u *Upstream) {
+			defer wg.Done()
+			err := p.checkHealth(ctx, u)
+			if err != nil {
+				if u.IsHealthy() {
+					p.logger.Warn("Upstream health check failed", slog.String("upstream", u.URL), slog.String("error", err.Error()))
+				}
+				u.markFailure()
+				u.setContext(nil)
+			}
+		}(u)
+	}
+	wg.Wait()
+}
+
+// StartHealthChecks runs the health checks every interval until the context is done
+func (p *Pool) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	if p.healthChecker == nil || interval <= 0 {
+		return
+	}
+
+	contextChecker, ok := p.healthChecker.(ContextChecker)
+	if ok {
+		p.contextReq = contextChecker.BuildContextReq("1")
+	}
+
+	p.CheckHealth(ctx)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.CheckHealth(ctx)
+			}
+		}
+	}()
+}
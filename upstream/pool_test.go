@@ -0,0 +1,343 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stateless-solutions/compatibility-layer/models"
+)
+
+type mockHealthChecker struct{}
+
+func (mockHealthChecker) GetHealthCheckReq() *models.RPCReq {
+	return &models.RPCReq{JSONRPC: "2.0", Method: "getHeight"}
+}
+
+func (mockHealthChecker) ExtractHeightFromHealthCheck(res *models.RPCResJSON) (uint64, error) {
+	height, err := strconv.ParseUint(string(res.Result), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected height %s", res.Result)
+	}
+	return height, nil
+}
+
+func newMockUpstream(status int, body string, delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+func newPostReq(url string) (*http.Request, error) {
+	return http.NewRequest("POST", url, bytes.NewBufferString(`{}`))
+}
+
+func TestPoolDo(t *testing.T) {
+	ok := newMockUpstream(http.StatusOK, "ok", 0)
+	defer ok.Close()
+	unavailable := newMockUpstream(http.StatusServiceUnavailable, "unavailable", 0)
+	defer unavailable.Close()
+	badRequest := newMockUpstream(http.StatusBadRequest, "bad request", 0)
+	defer badRequest.Close()
+	closed := newMockUpstream(http.StatusOK, "closed", 0)
+	closed.Close()
+
+	tests := []struct {
+		name             string
+		urls             []string
+		expectedBody     string
+		expectedErr      bool
+		expectedHealthy  []bool
+		expectedUpstream int
+	}{
+		{
+			name:             "Single upstream",
+			urls:             []string{ok.URL},
+			expectedBody:     "ok",
+			expectedHealthy:  []bool{true},
+			expectedUpstream: 0,
+		},
+		{
+			name:             "Failover on 5xx",
+			urls:             []string{unavailable.URL, ok.URL},
+			expectedBody:     "ok",
+			expectedHealthy:  []bool{false, true},
+			expectedUpstream: 1,
+		},
+		{
+			name:             "Failover on transport error",
+			urls:             []string{closed.URL, ok.URL},
+			expectedBody:     "ok",
+			expectedHealthy:  []bool{false, true},
+			expectedUpstream: 1,
+		},
+		{
+			name:             "No failover on 4xx",
+			urls:             []string{badRequest.URL, ok.URL},
+			expectedBody:     "bad request",
+			expectedHealthy:  []bool{true, true},
+			expectedUpstream: 0,
+		},
+		{
+			name:             "All upstreams 5xx",
+			urls:             []string{unavailable.URL, closed.URL},
+			expectedBody:     "unavailable",
+			expectedHealthy:  []bool{false, false},
+			expectedUpstream: 0,
+		},
+		{
+			name:            "All upstreams transport error",
+			urls:            []string{closed.URL},
+			expectedErr:     true,
+			expectedHealthy: []bool{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := NewPool(tt.urls, PolicyRoundRobin, nil, nil)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+			pool.next = 0
+
+			resp, u, err := pool.Do(nil, newPostReq)
+			if tt.expectedErr {
+				if err == nil {
+					t.Fatalf("Test case %s: Expected error, got nil", tt.name)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+				}
+				defer resp.Body.Close()
+
+				body, _ := io.ReadAll(resp.Body)
+				if string(body) != tt.expectedBody {
+					t.Errorf("Test case %s: Expected body %s, got %s", tt.name, tt.expectedBody, body)
+				}
+				if u != pool.Upstreams()[tt.expectedUpstream] {
+					t.Errorf("Test case %s: Expected upstream %s, got %s", tt.name, tt.urls[tt.expectedUpstream], u.URL)
+				}
+			}
+
+			for i, healthy := range tt.expectedHealthy {
+				if pool.Upstreams()[i].IsHealthy() != healthy {
+					t.Errorf("Test case %s: Expected upstream %d healthy %t, got %t", tt.name, i, healthy, !healthy)
+				}
+			}
+		})
+	}
+}
+
+func TestPoolDoPreferred(t *testing.T) {
+	first := newMockUpstream(http.StatusOK, "first", 0)
+	defer first.Close()
+	second := newMockUpstream(http.StatusOK, "second", 0)
+	defer second.Close()
+
+	pool, err := NewPool([]string{first.URL, second.URL}, PolicyRoundRobin, nil, nil)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, u, err := pool.Do(pool.Upstreams()[1], newPostReq)
+		if err != nil {
+			t.Fatalf("Error not expected, got %v", err)
+		}
+		if u != pool.Upstreams()[1] {
+			t.Errorf("Expected preferred upstream %s, got %s", second.URL, u.URL)
+		}
+	}
+}
+
+func TestPoolSelect(t *testing.T) {
+	low := newMockUpstream(http.StatusOK, `{"jsonrpc":"2.0","result":10,"id":1}`, 0)
+	defer low.Close()
+	high := newMockUpstream(http.StatusOK, `{"jsonrpc":"2.0","result":20,"id":1}`, 50*time.Millisecond)
+	defer high.Close()
+	failing := newMockUpstream(http.StatusOK, `{"jsonrpc":"2.0","error":{"code":-32000,"message":"node is behind"},"id":1}`, 0)
+	defer failing.Close()
+
+	tests := []struct {
+		name        string
+		policy      Policy
+		expectedURL []string
+	}{
+		{
+			name:        "Highest block",
+			policy:      PolicyHighestBlock,
+			expectedURL: []string{high.URL, low.URL, failing.URL},
+		},
+		{
+			name:        "Lowest latency",
+			policy:      PolicyLowestLatency,
+			expectedURL: []string{low.URL, high.URL, failing.URL},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := NewPool([]string{failing.URL, low.URL, high.URL}, tt.policy, mockHealthChecker{}, nil)
+			if err != nil {
+				t.Fatalf("Test case %s: Error not expected, got %v", tt.name, err)
+			}
+
+			pool.CheckHealth(context.Background())
+
+			for i := 0; i < 3; i++ {
+				selected := pool.Select()
+				for j, u := range selected {
+					if u.URL != tt.expectedURL[j] {
+						t.Errorf("Test case %s: Expected upstream %s on position %d, got %s", tt.name, tt.expectedURL[j], j, u.URL)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPoolSelectRoundRobin(t *testing.T) {
+	urls := []string{"http://first", "http://second", "http://third"}
+	pool, err := NewPool(urls, PolicyRoundRobin, nil, nil)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+
+	for i := 0; i < 6; i++ {
+		selected := pool.Select()
+		if selected[0].URL != urls[i%len(urls)] {
+			t.Errorf("Expected upstream %s first, got %s", urls[i%len(urls)], selected[0].URL)
+		}
+	}
+}
+
+func TestNewPoolErrors(t *testing.T) {
+	_, err := NewPool(nil, PolicyRoundRobin, nil, nil)
+	if err != errNoUpstreams {
+		t.Errorf("Expected error %v, got %v", errNoUpstreams, err)
+	}
+
+	_, err = NewPool([]string{"http://first"}, Policy("random"), nil, nil)
+	if err == nil {
+		t.Errorf("Expected invalid policy error, got nil")
+	}
+
+	_, err = NewPool([]string{"http://first"}, PolicyHighestBlock, nil, nil)
+	if err == nil {
+		t.Errorf("Expected missing health checker error, got nil")
+	}
+}
+
+func TestPoolTimeouts(t *testing.T) {
+	hung := newMockUpstream(http.StatusOK, `{"jsonrpc":"2.0","result":10,"id":1}`, 300*time.Millisecond)
+	defer hung.Close()
+	ok := newMockUpstream(http.StatusOK, `{"jsonrpc":"2.0","result":20,"id":1}`, 0)
+	defer ok.Close()
+
+	pool, err := NewPool([]string{hung.URL, ok.URL}, PolicyRoundRobin, mockHealthChecker{}, nil)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	pool.SetTimeout(50 * time.Millisecond)
+	pool.healthCheckTimeout = 50 * time.Millisecond
+	pool.next = 0
+
+	start := time.Now()
+	resp, u, err := pool.Do(nil, newPostReq)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Error not expected reading the body after the timeout of the hung attempt, got %v", err)
+	}
+	if u != pool.Upstreams()[1] || string(body) != `{"jsonrpc":"2.0","result":20,"id":1}` {
+		t.Errorf("Expected the hung upstream to be failed over, got %s with %s", u.URL, body)
+	}
+	if pool.Upstreams()[0].IsHealthy() {
+		t.Errorf("Expected the hung upstream to be unhealthy")
+	}
+
+	pool.Upstreams()[0].markSuccess(0)
+	pool.CheckHealth(context.Background())
+	if time.Since(start) > 250*time.Millisecond {
+		t.Errorf("Expected the hung upstream to time out, took %s", time.Since(start))
+	}
+	if pool.Upstreams()[0].IsHealthy() || !pool.Upstreams()[1].IsHealthy() {
+		t.Errorf("Expected only the hung upstream to fail its health check")
+	}
+}
+
+type mockContextChecker struct {
+	mockHealthChecker
+}
+
+func (mockContextChecker) BuildContextReq(id string) *models.RPCReq {
+	return &models.RPCReq{JSONRPC: "2.0", Method: "getVersion", ID: json.RawMessage(id)}
+}
+
+func TestPoolContext(t *testing.T) {
+	var failing atomic.Bool
+	var contextReqs atomic.Int32
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.RPCReq
+		json.NewDecoder(r.Body).Decode(&req)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if req.Method == "getVersion" {
+			contextReqs.Add(1)
+			w.Write([]byte(`{"jsonrpc":"2.0","result":{"solana-core":"2.1.0"},"id":1}`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","result":10,"id":1}`))
+	}))
+	defer mock.Close()
+
+	pool, err := NewPool([]string{mock.URL}, PolicyRoundRobin, mockContextChecker{}, nil)
+	if err != nil {
+		t.Fatalf("Error not expected, got %v", err)
+	}
+	if pool.ContextReq() != nil {
+		t.Errorf("Expected no context req before the health checks are started")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.StartHealthChecks(ctx, time.Hour)
+	pool.CheckHealth(ctx)
+
+	u := pool.Upstreams()[0]
+	if pool.ContextReq() == nil || u.Context() == nil || string(u.Context().Result) != `{"solana-core":"2.1.0"}` {
+		t.Fatalf("Expected the context of the upstream, got %v", u.Context())
+	}
+	if contextReqs.Load() != 1 {
+		t.Errorf("Expected the context to be fetched once, got %d", contextReqs.Load())
+	}
+
+	failing.Store(true)
+	pool.CheckHealth(ctx)
+	if u.Context() != nil {
+		t.Errorf("Expected the context to be cleared after a failed health check")
+	}
+
+	failing.Store(false)
+	pool.CheckHealth(ctx)
+	if u.Context() == nil || contextReqs.Load() != 2 {
+		t.Errorf("Expected the context to be fetched again after the upstream recovers, got %d fetches", contextReqs.Load())
+	}
+}